@@ -0,0 +1,274 @@
+package dialects
+
+import (
+	"regexp/syntax"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+)
+
+// GrammarWarning provides an advisory finding about the grammar of a dialect, such as a pattern that can make parsing
+// backtrack far more than the input needs, which doesn't stop the dialect from compiling
+type GrammarWarning struct {
+	PartName string
+	Message  string
+}
+
+// String returns the message for the part, as in "dialects warning: part (root) ..."
+func (w GrammarWarning) String() string {
+	return "dialects warning: part (" + w.PartName + ") " + w.Message
+}
+
+// AnalyzeDialect checks the grammar of the dialect for patterns that tend to make parses slow or surprising, returning
+// a warning for each found, in order of part name: a repetition whose element can start with the text that the
+// constituents after it start with, so the boundary between them is ambiguous, a repetition of a part that already
+// repeats itself, and alternatives of a part that can start with the same character, so a later one is only tried
+// after an earlier one fails on the same input; the checks compare the first characters that parts can match, so
+// they can flag a grammar that's fine, and they can't see into Match parts or backreferences
+func AnalyzeDialect(d *Dialect) []GrammarWarning {
+	a := &analysis{dialect: d, nullable: nullableParts(d), firsts: make(map[string]firstSet), visiting: make(map[string]int)}
+	var warnings []GrammarWarning
+	for _, name := range sortedPartNames(d) {
+		constituents := d.PartDefinitions[name].Constituents
+		for i, constituentSeq := range constituents {
+			for j, constituentID := range constituentSeq {
+				reference := parseConstituent(constituentID)
+				if !repeats(reference) {
+					continue
+				}
+				if a.repeatsItself(reference.name, make(map[string]bool)) {
+					warnings = append(warnings, GrammarWarning{PartName: name, Message: "repeats part (" + reference.name + ") in constituent " + strconv.Quote(constituentID) + ", which already repeats its own matches"})
+				}
+				following := a.sequenceFirst(constituentSeq[j+1:])
+				if r, ok := a.partFirst(reference.name).overlap(following); ok {
+					warnings = append(warnings, GrammarWarning{PartName: name, Message: "repeats part (" + reference.name + ") in constituent " + strconv.Quote(constituentID) + " of alternative " + strconv.Itoa(i+1) + ", which can match the start of what follows it, such as " + strconv.QuoteRune(r)})
+				}
+			}
+		}
+		for i := range constituents {
+			for j := i + 1; j < len(constituents); j++ {
+				if r, ok := a.sequenceFirst(constituents[i]).overlap(a.sequenceFirst(constituents[j])); ok {
+					warnings = append(warnings, GrammarWarning{PartName: name, Message: "has alternatives " + strconv.Itoa(i+1) + " and " + strconv.Itoa(j+1) + " that can both start with " + strconv.QuoteRune(r)})
+				}
+			}
+		}
+	}
+	return warnings
+}
+
+// repeats reports whether the constituent can match its part more than once
+func repeats(reference constituent) bool {
+	switch reference.modifier {
+	case "*", "+":
+		return true
+	case "{}":
+		return reference.max != 1
+	}
+	return false
+}
+
+// firstSet provides the characters that a part can start with as pairs of the first and last runes of each range,
+// like the Rune field of a syntax.Regexp character class, where unknown marks parts whose start can't be worked out
+type firstSet struct {
+	ranges  []rune
+	unknown bool
+}
+
+// union returns the characters of both sets
+func (set firstSet) union(other firstSet) firstSet {
+	return firstSet{ranges: append(append([]rune{}, set.ranges...), other.ranges...), unknown: set.unknown || other.unknown}
+}
+
+// overlap returns the lowest character both sets can start with, with ok reporting whether there is one, which
+// there never is when either set is unknown
+func (set firstSet) overlap(other firstSet) (r rune, ok bool) {
+	if set.unknown || other.unknown {
+		return 0, false
+	}
+	lowest := rune(-1)
+	for i := 0; i < len(set.ranges); i += 2 {
+		for j := 0; j < len(other.ranges); j += 2 {
+			lo, hi := set.ranges[i], set.ranges[i+1]
+			if other.ranges[j] > lo {
+				lo = other.ranges[j]
+			}
+			if other.ranges[j+1] < hi {
+				hi = other.ranges[j+1]
+			}
+			if lo <= hi && (lowest < 0 || lo < lowest) {
+				lowest = lo
+			}
+		}
+	}
+	return lowest, lowest >= 0
+}
+
+// analysis provides the first sets of the parts of a dialect, worked out as they're needed
+type analysis struct {
+	dialect  *Dialect
+	nullable map[string]bool
+	firsts   map[string]firstSet
+	// visiting holds the depth of each part whose first set is being worked out, so recursive parts don't recurse
+	// forever, and reached holds the least depth of those the part being worked out recursed into
+	visiting map[string]int
+	reached  int
+}
+
+// partFirst returns the characters the part can start with, where a part still being worked out adds nothing to a
+// part it recursed into, as the characters it adds come from its other constituents, which are worked out anyway; the
+// first set of a part that recursed into another part still being worked out is missing those, so it isn't kept
+// until that part is worked out, leaving only the part the recursion started from with the whole set
+func (a *analysis) partFirst(name string) firstSet {
+	if set, ok := a.firsts[name]; ok {
+		return set
+	}
+	if depth, ok := a.visiting[name]; ok {
+		if depth < a.reached {
+			a.reached = depth
+		}
+		return firstSet{}
+	}
+	depth, outer := len(a.visiting)+1, a.reached
+	a.visiting[name], a.reached = depth, depth
+	partDefinition := a.dialect.PartDefinitions[name]
+	var set firstSet
+	switch {
+	case partDefinition.Literal != "":
+		set = textFirst(partDefinition.Literal, caseInsensitive(partDefinition, a.dialect))
+	case partDefinition.Keyword != "":
+		set = textFirst(partDefinition.Keyword, caseInsensitive(partDefinition, a.dialect) || (a.dialect.CaseInsensitiveKeywords && !partDefinition.CaseSensitive))
+	case partDefinition.Regex != "" && !a.dialect.UnanchoredRegexes:
+		set.unknown = true
+		flags := syntax.Perl
+		if caseInsensitive(partDefinition, a.dialect) {
+			flags |= syntax.FoldCase
+		}
+		if parsedRegex, err := syntax.Parse(partDefinition.Regex, flags); err == nil {
+			set.ranges, _ = regexFirst(parsedRegex)
+			set.unknown = false
+		}
+	case partDefinition.expression != nil:
+		set = a.partFirst(partDefinition.expression.operand)
+	case len(partDefinition.Constituents) > 0:
+		for _, constituentSeq := range partDefinition.Constituents {
+			set = set.union(a.sequenceFirst(constituentSeq))
+		}
+	case partDefinition.EOF:
+	default:
+		set.unknown = true
+	}
+	delete(a.visiting, name)
+	if a.reached >= depth {
+		a.firsts[name] = set
+	}
+	if outer < a.reached {
+		a.reached = outer
+	}
+	return set
+}
+
+// sequenceFirst returns the characters the constituent sequence can start with, which are those of its constituents
+// up to the first that can't match without consuming input
+func (a *analysis) sequenceFirst(constituentSeq []string) firstSet {
+	var set firstSet
+	for _, constituentID := range constituentSeq {
+		reference := parseConstituent(constituentID)
+		switch reference.modifier {
+		case "!", "&", "^":
+			// consumes nothing
+			continue
+		case "=":
+			set.unknown = true
+		default:
+			set = set.union(a.partFirst(reference.name))
+		}
+		if !constituentNullable(reference, a.nullable) {
+			break
+		}
+	}
+	return set
+}
+
+// repeatsItself reports whether the part's match is itself a repetition, either a regex repeated as a whole or a
+// composite part whose only constituent is a repetition, which seen guards against recursing forever
+func (a *analysis) repeatsItself(name string, seen map[string]bool) bool {
+	if seen[name] {
+		return false
+	}
+	seen[name] = true
+	partDefinition := a.dialect.PartDefinitions[name]
+	if partDefinition.Regex != "" {
+		parsedRegex, err := syntax.Parse(partDefinition.Regex, syntax.Perl)
+		for err == nil && parsedRegex.Op == syntax.OpCapture {
+			parsedRegex = parsedRegex.Sub[0]
+		}
+		return err == nil && (parsedRegex.Op == syntax.OpStar || parsedRegex.Op == syntax.OpPlus || (parsedRegex.Op == syntax.OpRepeat && parsedRegex.Max != 1))
+	}
+	if len(partDefinition.Constituents) != 1 || len(partDefinition.Constituents[0]) != 1 {
+		return false
+	}
+	reference := parseConstituent(partDefinition.Constituents[0][0])
+	return repeats(reference) || (reference.modifier == "" && a.repeatsItself(reference.name, seen))
+}
+
+// textFirst returns the first character of the literal or keyword text, in each case when ignoring case
+func textFirst(text string, ignoreCase bool) firstSet {
+	r, _ := utf8.DecodeRuneInString(text)
+	set := firstSet{ranges: []rune{r, r}}
+	if ignoreCase {
+		for folded := unicode.SimpleFold(r); folded != r; folded = unicode.SimpleFold(folded) {
+			set.ranges = append(set.ranges, folded, folded)
+		}
+	}
+	return set
+}
+
+// regexFirst returns the ranges of characters the parsed regex can start with, along with whether it can match
+// without consuming any input
+func regexFirst(re *syntax.Regexp) (ranges []rune, nullable bool) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		if len(re.Rune) == 0 {
+			return nil, true
+		}
+		ranges = []rune{re.Rune[0], re.Rune[0]}
+		if re.Flags&syntax.FoldCase != 0 {
+			ranges = textFirst(string(re.Rune[0]), true).ranges
+		}
+		return ranges, false
+	case syntax.OpCharClass:
+		return re.Rune, false
+	case syntax.OpAnyCharNotNL:
+		return []rune{0, '\n' - 1, '\n' + 1, unicode.MaxRune}, false
+	case syntax.OpAnyChar:
+		return []rune{0, unicode.MaxRune}, false
+	case syntax.OpCapture:
+		return regexFirst(re.Sub[0])
+	case syntax.OpStar, syntax.OpQuest:
+		ranges, _ = regexFirst(re.Sub[0])
+		return ranges, true
+	case syntax.OpPlus:
+		return regexFirst(re.Sub[0])
+	case syntax.OpRepeat:
+		ranges, nullable = regexFirst(re.Sub[0])
+		return ranges, nullable || re.Min == 0
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			subRanges, subNullable := regexFirst(sub)
+			ranges = append(ranges, subRanges...)
+			if !subNullable {
+				return ranges, false
+			}
+		}
+		return ranges, true
+	case syntax.OpAlternate:
+		for _, sub := range re.Sub {
+			subRanges, subNullable := regexFirst(sub)
+			ranges = append(ranges, subRanges...)
+			nullable = nullable || subNullable
+		}
+		return ranges, nullable
+	}
+	// empty matches and zero-width assertions consume nothing
+	return nil, true
+}
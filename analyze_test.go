@@ -0,0 +1,63 @@
+package dialects_test
+
+import (
+	"testing"
+
+	"github.com/AdamJonR/dialects"
+)
+
+func TestAnalyzeDialect(t *testing.T) {
+	g := newGrammar("root", map[string]dialects.PartDefinition{
+		"root":      {Constituents: [][]string{{"line*"}}},
+		"line":      {Constituents: [][]string{{"statement", "blank*", "newline"}}},
+		"statement": {Constituents: [][]string{{"name", "equals", "value"}, {"return", "value"}, {"number"}}},
+		"blank":     {Regex: `[ \t\n]`},
+		"newline":   {Literal: "\n"},
+		"name":      {Regex: `[a-z]+`},
+		"return":    {Keyword: "return"},
+		"equals":    {Literal: "="},
+		"value":     {Constituents: [][]string{{"number"}, {"name"}}},
+		"number":    {Regex: `[0-9]+`},
+		"list":      {Constituents: [][]string{{"number", "spaces*"}}},
+		"spaces":    {Regex: `[ ]+`},
+	})
+	warnings := dialects.AnalyzeDialect(g.NewDialect())
+	expected := []string{
+		`dialects warning: part (line) repeats part (blank) in constituent "blank*" of alternative 1, which can match the start of what follows it, such as '\n'`,
+		`dialects warning: part (list) repeats part (spaces) in constituent "spaces*", which already repeats its own matches`,
+		`dialects warning: part (statement) has alternatives 1 and 2 that can both start with 'r'`,
+	}
+	if len(warnings) != len(expected) {
+		t.Fatalf("expected %d warnings, got %v", len(expected), warnings)
+	}
+	for i, warning := range warnings {
+		if warning.String() != expected[i] {
+			t.Errorf("expected %q, got %q", expected[i], warning.String())
+		}
+	}
+	// the grammars used throughout the tests are clean
+	if warnings := dialects.AnalyzeDialect(wordList().NewDialect()); len(warnings) != 0 {
+		t.Errorf("expected no warnings, got %v", warnings)
+	}
+}
+
+func TestAnalyzeDialectRecursion(t *testing.T) {
+	// a and b start with each other, so each can start with whatever either of them can
+	g := newGrammar("a", map[string]dialects.PartDefinition{
+		"a": {Constituents: [][]string{{"b", "z"}, {"q"}}, AllowLeftRecursion: true},
+		"b": {Constituents: [][]string{{"a", "y"}, {"w"}}, AllowLeftRecursion: true},
+		"q": {Literal: "q"},
+		"w": {Literal: "w"},
+		"y": {Literal: "y"},
+		"z": {Literal: "z"},
+	})
+	expected := "dialects warning: part (a) has alternatives 1 and 2 that can both start with 'q'\n" +
+		"dialects warning: part (b) has alternatives 1 and 2 that can both start with 'w'\n"
+	var actual string
+	for _, warning := range dialects.AnalyzeDialect(g.NewDialect()) {
+		actual += warning.String() + "\n"
+	}
+	if actual != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, actual)
+	}
+}
@@ -0,0 +1,215 @@
+// Package builder provides typed constructors for the constituents of a dialect's parts, so a grammar's structure is
+// written as calls rather than constituent IDs with modifiers, and compiles down to the same PartDefinitions
+package builder
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/AdamJonR/dialects"
+)
+
+// Term provides a constituent of a sequence, such as a reference to a part, possibly with a modifier
+type Term struct {
+	id string
+	// modified reports whether the ID has a modifier, which another can't be added to
+	modified bool
+	// literals hold the texts of the literal parts the term refers to, which the Grammar defines
+	literals []string
+	err      string
+}
+
+// Ref returns the term referring to the part
+func Ref(name string) Term {
+	return Term{id: name}
+}
+
+// Lit returns the term referring to a Literal part matching the text, which the Grammar defines for it, named after
+// the text in single quotes, as in '='
+func Lit(text string) Term {
+	return Term{id: LiteralName(text), literals: []string{text}}
+}
+
+// LiteralName returns the name of the part the Grammar defines for the Literal text, escaping backslashes and percent
+// signs, which would otherwise be read as the separator of a repetition
+func LiteralName(text string) string {
+	return "'" + strings.ReplaceAll(strings.ReplaceAll(text, `\`, `\\`), "%", `\x25`) + "'"
+}
+
+// Opt returns the term matching the term or nothing, as in "ws?"
+func Opt(term Term) Term {
+	return term.modify("", "?")
+}
+
+// Many returns the term matching the term any number of times, as in "item*"
+func Many(term Term) Term {
+	return term.modify("", "*")
+}
+
+// Many1 returns the term matching the term at least once, as in "item+"
+func Many1(term Term) Term {
+	return term.modify("", "+")
+}
+
+// Repeat returns the term matching the term from min to max times, with no upper bound if max is negative, as in
+// "digit{2,4}"
+func Repeat(term Term, min int, max int) Term {
+	bounds := "{" + strconv.Itoa(min) + ","
+	if max >= 0 {
+		bounds += strconv.Itoa(max)
+	}
+	return term.modify("", bounds+"}")
+}
+
+// SepBy returns the term matching any number of the term separated by the separator, as in "item%comma*"
+func SepBy(term Term, separator Term) Term {
+	return term.separate(separator, "*")
+}
+
+// SepBy1 returns the term matching at least one of the term separated by the separator, as in "item%comma+"
+func SepBy1(term Term, separator Term) Term {
+	return term.separate(separator, "+")
+}
+
+// Not returns the term that only matches where the term doesn't, consuming nothing, as in "!keyword"
+func Not(term Term) Term {
+	return term.modify("!", "")
+}
+
+// And returns the term that only matches where the term does, consuming nothing, as in "&digit"
+func And(term Term) Term {
+	return term.modify("&", "")
+}
+
+// Backref returns the term matching the same text as the term's part earlier in the sequence, as in "=name"
+func Backref(term Term) Term {
+	return term.modify("=", "")
+}
+
+// Cut returns the term committing the part to its alternative, as in "^"
+func Cut() Term {
+	return Term{id: "^", modified: true}
+}
+
+// modify returns the term with the prefix and suffix added to its ID, noting an error if it already has a modifier
+func (term Term) modify(prefix string, suffix string) Term {
+	if term.modified && term.err == "" {
+		term.err = "adds " + strconv.Quote(prefix+suffix) + " to constituent " + strconv.Quote(term.id) + ", which already has a modifier"
+	}
+	term.id, term.modified = prefix+term.id+suffix, true
+	return term
+}
+
+// separate returns the term repeated with the separator, noting an error if either already has a modifier
+func (term Term) separate(separator Term, modifier string) Term {
+	if separator.modified && term.err == "" {
+		term.err = "separates constituent " + strconv.Quote(term.id) + " by " + strconv.Quote(separator.id) + ", which has a modifier"
+	}
+	if separator.err != "" && term.err == "" {
+		term.err = separator.err
+	}
+	literals := append(append([]string{}, term.literals...), separator.literals...)
+	term = term.modify("", "%"+separator.id+modifier)
+	term.literals = literals
+	return term
+}
+
+// Rule provides the alternatives of a part, each a sequence of terms
+type Rule struct {
+	alternatives [][]Term
+}
+
+// Seq returns the rule matching the terms in order
+func Seq(terms ...Term) Rule {
+	return Rule{alternatives: [][]Term{terms}}
+}
+
+// Choice returns the rule trying the alternatives of the rules in order
+func Choice(rules ...Rule) Rule {
+	var choice Rule
+	for _, rule := range rules {
+		choice.alternatives = append(choice.alternatives, rule.alternatives...)
+	}
+	return choice
+}
+
+// Grammar builds the part definitions of a dialect, alongside any it already has, defining the Literal parts its
+// rules refer to and checking the whole grammar once it's built
+type Grammar struct {
+	dialect  *dialects.Dialect
+	literals map[string]string
+	errs     []error
+}
+
+// New returns the Grammar adding parts to the dialect, whose settings, such as its RootName, are kept as they are
+func New(dialect *dialects.Dialect) *Grammar {
+	if dialect.PartDefinitions == nil {
+		dialect.PartDefinitions = make(map[string]dialects.PartDefinition)
+	}
+	return &Grammar{dialect: dialect, literals: make(map[string]string)}
+}
+
+// Part defines the part with the rule's alternatives as its Constituents
+func (g *Grammar) Part(name string, rule Rule) {
+	g.Define(name, dialects.PartDefinition{Constituents: g.constituents(name, rule)})
+}
+
+// Define defines the part, as in the dialect's PartDefinitions, for parts such as regexes or parts with handlers,
+// whose Constituents can come from Constituents
+func (g *Grammar) Define(name string, partDefinition dialects.PartDefinition) {
+	if _, ok := g.dialect.PartDefinitions[name]; ok {
+		g.errs = append(g.errs, &dialects.DialectError{PartName: name, Message: "is defined more than once"})
+		return
+	}
+	g.dialect.PartDefinitions[name] = partDefinition
+}
+
+// Constituents returns the rule's alternatives as the Constituents of a PartDefinition, noting the Literal parts it
+// refers to so the Grammar defines them
+func (g *Grammar) Constituents(rule Rule) [][]string {
+	return g.constituents("", rule)
+}
+
+// constituents returns the rule's alternatives as the Constituents of the part, noting their literals and errors
+func (g *Grammar) constituents(name string, rule Rule) [][]string {
+	constituents := make([][]string, len(rule.alternatives))
+	for i, terms := range rule.alternatives {
+		constituents[i] = make([]string, len(terms))
+		for j, term := range terms {
+			if term.err != "" {
+				g.errs = append(g.errs, &dialects.DialectError{PartName: name, Message: term.err})
+			}
+			for _, text := range term.literals {
+				g.literals[LiteralName(text)] = text
+			}
+			constituents[i][j] = term.id
+		}
+	}
+	return constituents
+}
+
+// Dialect returns the dialect with the parts defined and a Literal part for each text of a Lit, returning the
+// problems found building it or by ValidateDialect, such as references to undefined parts, joined into one error
+func (g *Grammar) Dialect() (*dialects.Dialect, error) {
+	errs := g.errs
+	names := make([]string, 0, len(g.literals))
+	for name := range g.literals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		text := g.literals[name]
+		if partDefinition, ok := g.dialect.PartDefinitions[name]; !ok {
+			g.dialect.PartDefinitions[name] = dialects.PartDefinition{Literal: text}
+		} else if partDefinition.Literal != text {
+			errs = append(errs, &dialects.DialectError{PartName: name, Message: "is already defined, so it can't be the Literal part for " + strconv.Quote(text)})
+		}
+	}
+	errs = append(errs, dialects.ValidateDialect(g.dialect)...)
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return g.dialect, nil
+}
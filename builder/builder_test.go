@@ -0,0 +1,90 @@
+package builder_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/AdamJonR/dialects"
+	"github.com/AdamJonR/dialects/builder"
+)
+
+// built provides the dialect built by a Grammar, whose output lists the keys of its pairs
+type built struct {
+	dialect *dialects.Dialect
+}
+
+func (b built) NewDialect() *dialects.Dialect {
+	return b.dialect
+}
+
+func (built) NewModel() interface{} {
+	return &[]string{}
+}
+
+func (built) GenerateOutput(model interface{}) (string, error) {
+	return strings.Join(*model.(*[]string), ","), nil
+}
+
+// pairs returns a grammar of comma-separated key=value pairs
+func pairs() *builder.Grammar {
+	g := builder.New(&dialects.Dialect{Title: "pairs", RootName: "pairs"})
+	g.Part("pairs", builder.Seq(builder.SepBy1(builder.Ref("pair"), builder.Lit(","))))
+	g.Define("pair", dialects.PartDefinition{
+		Constituents: g.Constituents(builder.Seq(builder.Ref("key"), builder.Opt(builder.Ref("ws")), builder.Lit("="), builder.Opt(builder.Ref("ws")), builder.Ref("value"))),
+		Handler: func(part *dialects.Part, model interface{}) bool {
+			keys := model.(*[]string)
+			*keys = append(*keys, part.Constituents[0].Value)
+			return true
+		},
+	})
+	g.Define("key", dialects.PartDefinition{Regex: `[a-z]+`})
+	g.Part("value", builder.Choice(builder.Seq(builder.Ref("number")), builder.Seq(builder.Lit("%"), builder.Repeat(builder.Ref("number"), 1, -1))))
+	g.Define("number", dialects.PartDefinition{Regex: `[0-9]`})
+	g.Define("ws", dialects.PartDefinition{Regex: `[ ]+`, Ignore: true})
+	return g
+}
+
+func TestGrammar(t *testing.T) {
+	d, err := pairs().Dialect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the terms compile down to the constituent IDs a grammar would otherwise write
+	expected := map[string]string{
+		"pairs": "pair%','+",
+		"pair":  "key, ws?, '=', ws?, value",
+		"value": `number | '\x25', number{1,}`,
+	}
+	for name, constituents := range expected {
+		var alternatives []string
+		for _, constituentSeq := range d.PartDefinitions[name].Constituents {
+			alternatives = append(alternatives, strings.Join(constituentSeq, ", "))
+		}
+		if strings.Join(alternatives, " | ") != constituents {
+			t.Errorf("expected part %s to have the constituents %s, got %s", name, constituents, strings.Join(alternatives, " | "))
+		}
+	}
+	if d.PartDefinitions[builder.LiteralName("%")].Literal != "%" {
+		t.Errorf("expected a Literal part for the percent sign, got %+v", d.PartDefinitions[builder.LiteralName("%")])
+	}
+	output, err, _ := dialects.Parse(built{d}, "a = 1,b=%42")
+	if err != nil || output != "a,b" {
+		t.Errorf("expected both pairs to parse, got %q and %v", output, err)
+	}
+}
+
+func TestGrammarErrors(t *testing.T) {
+	g := pairs()
+	g.Part("extra", builder.Seq(builder.Opt(builder.Many(builder.Ref("pair"))), builder.Ref("nothing")))
+	g.Define("key", dialects.PartDefinition{Regex: `[A-Z]+`})
+	_, err := g.Dialect()
+	for _, message := range []string{
+		`part (extra) adds "?" to constituent "pair*", which already has a modifier`,
+		"part (key) is defined more than once",
+		`part (extra) references undefined part (nothing) in constituent "nothing"`,
+	} {
+		if err == nil || !strings.Contains(err.Error(), "dialects error: "+message) {
+			t.Errorf("expected the error %q, got %v", message, err)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package dialects
+
+import (
+	"context"
+	"errors"
+	"regexp"
+)
+
+// CompiledDialect provides a dialect whose grammar has been checked and whose regexes have been compiled once, so it
+// can be used for repeated parses, including concurrent parses from multiple goroutines
+type CompiledDialect struct {
+	dialectable     Dialectable
+	dialect         *Dialect
+	compiledRegexes map[string]*regexp.Regexp
+	continuation    *regexp.Regexp
+	skip            *regexp.Regexp
+	// recovers reports whether any part has RecoverAt
+	recovers bool
+}
+
+// Compile creates the dialect, validates its grammar, and compiles the regexes of all its parts, returning an error
+// rather than panicking if the grammar has problems
+func Compile(dialectable Dialectable) (*CompiledDialect, error) {
+	dialect := dialectable.NewDialect()
+	if errs := ValidateDialect(dialect); len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	compiled := &CompiledDialect{dialectable: dialectable, dialect: dialect, compiledRegexes: make(map[string]*regexp.Regexp)}
+	for _, name := range sortedPartNames(dialect) {
+		partDefinition := dialect.PartDefinitions[name]
+		if len(partDefinition.RecoverAt) > 0 {
+			compiled.recovers = true
+		}
+		if partDefinition.Regex == "" {
+			continue
+		}
+		compiledRegex, err := regexp.Compile(anchorRegex(partDefinition, dialect))
+		if err != nil {
+			return nil, &DialectError{PartName: name, Message: "has an invalid regex", Err: err}
+		}
+		compiled.compiledRegexes[name] = compiledRegex
+	}
+	continuation, err := regexp.Compile(`\A(?:` + keywordContinuation(dialect) + `)`)
+	if err != nil {
+		return nil, &DialectError{Message: "KeywordContinuation is an invalid regex", Err: err}
+	}
+	compiled.continuation = continuation
+	if dialect.SkipPattern != "" {
+		if compiled.skip, err = regexp.Compile(`\A(?:` + dialect.SkipPattern + `)`); err != nil {
+			return nil, &DialectError{Message: "SkipPattern is an invalid regex", Err: err}
+		}
+	}
+	return compiled, nil
+}
+
+// Dialect returns the dialect that was compiled
+func (compiled *CompiledDialect) Dialect() *Dialect {
+	return compiled.dialect
+}
+
+// Parse parses the input with the compiled dialect, using a fresh model
+func (compiled *CompiledDialect) Parse(input string) (string, error, string) {
+	return compiled.ParseWithOptions(input, Options{})
+}
+
+// ParseWithOptions parses the input with the compiled dialect, using a fresh model and the options for this parse
+func (compiled *CompiledDialect) ParseWithOptions(input string, options Options) (string, error, string) {
+	return compiled.ParseContext(context.Background(), input, options)
+}
+
+// ParseContext parses the input like ParseWithOptions, stopping with a ParseError wrapping the context's error once
+// the context is done
+func (compiled *CompiledDialect) ParseContext(ctx context.Context, input string, options Options) (string, error, string) {
+	output, _, err, log := compiled.parse(ctx, input, options, false).values()
+	return output, err, log
+}
+
+// ParsePrefix parses the input like ParseWithOptions, but lets the root part match a prefix of the input, returning
+// the number of bytes of the input it consumed, so a fragment embedded in a larger text can be parsed from an offset
+// and the caller can carry on scanning after it; any text after the root part that the dialect would skip, such as
+// whitespace, is left to the caller, and consumed is zero if the parse fails
+func (compiled *CompiledDialect) ParsePrefix(input string, options Options) (output string, consumed int, err error, log string) {
+	return compiled.parse(context.Background(), input, options, true).values()
+}
+
+// parse parses the input like ParseContext, letting the root part match a prefix of the input if prefix is set,
+// returning the Result of the parse
+func (compiled *CompiledDialect) parse(ctx context.Context, input string, options Options, prefix bool) Result {
+	parser, root, err := compiled.run(ctx, input, nil, 0, options, func(parser *Parser) {
+		parser.prefix = prefix
+	})
+	if parser == nil {
+		return Result{Status: StatusFailedSyntax, Err: err}
+	}
+	if err != nil {
+		if *parser.status == StatusSucceeded {
+			*parser.status = StatusFailedSyntax
+		}
+		return newResult(err, 0, *parser, options)
+	}
+	parser.output, err = compiled.generateOutput(parser.model, root, input, options)
+	if err != nil {
+		*parser.status = StatusFailedGenerate
+	}
+	return newResult(err, root.EndPos, *parser, options)
+}
+
+// run preprocesses the input and parses it with a parser set up by the function, recording the coverage, stats,
+// partial result, and diagnostics of the parse, and returns the parser, or nil if the input couldn't be preprocessed,
+// along with the tree and the error, whose positions are moved back to the input, or into the host input if the
+// input is the text at the offset of one
+func (compiled *CompiledDialect) run(ctx context.Context, input string, host *preprocessing, offset int, options Options, setup func(parser *Parser)) (*Parser, *Part, error) {
+	input, mapping, err := compiled.preprocess(input)
+	if host != nil {
+		mapping = mapping.within(host, offset)
+	}
+	if err != nil {
+		// the error is positioned in the input as it was before preprocessing, so only the host's offset is mapped
+		return nil, nil, mapping.mapError(err, compiled.dialect.OmitSnippets)
+	}
+	parser := newParser(ctx, compiled, input, options)
+	if setup != nil {
+		setup(&parser)
+	}
+	// WHEEEEEEEE!!!! (enjoy the ride as you descend into the rabbit hole)
+	root, err := parseRoot(parser)
+	options.Coverage.add(err, parser.coverage)
+	options.Stats.add(parser.stats)
+	recordPartial(err, parser, mapping, options)
+	err = mapping.mapError(err, compiled.dialect.OmitSnippets)
+	recordDiagnostics(err, parser, mapping, options)
+	// a parse that recovered from syntax errors keeps the tree along with them
+	mapping.mapTree(root, options.RunePositions)
+	return &parser, root, err
+}
+
+// newResult returns the Result of the parser's parse, which failed with the error, if any, after consuming the bytes
+func newResult(err error, consumed int, parser Parser, options Options) Result {
+	result := Result{Status: *parser.status, Output: parser.output, Err: err, Consumed: consumed, Log: parser.log.String(), TraceErr: parser.log.err()}
+	if options.Diagnostics != nil {
+		result.Diagnostics = *options.Diagnostics
+	}
+	return result
+}
+
+// generateOutput returns the output for the model, using the tree, whose positions are in the original input, if the
+// dialect is a SourceMapper or TreeAware, in which case the generator can slice the original input, and returning a
+// PanicError if the generator panics, unless the options don't recover
+func (compiled *CompiledDialect) generateOutput(model interface{}, root *Part, original string, options Options) (output string, err error) {
+	switch generator := compiled.dialectable.(type) {
+	case SourceMapper:
+		if !options.NoRecover {
+			defer recoverOutput("GenerateMappedOutput", &err)
+		}
+		builder := newOutputBuilder()
+		err = generator.GenerateMappedOutput(model, root, original, builder)
+		if options.SourceMap != nil {
+			options.SourceMap.Mappings = builder.mappings
+		}
+		return builder.String(), err
+	case TreeAware:
+		if !options.NoRecover {
+			defer recoverOutput("GenerateOutputWithTree", &err)
+		}
+		return generator.GenerateOutputWithTree(model, root, original)
+	}
+	if !options.NoRecover {
+		defer recoverOutput("GenerateOutput", &err)
+	}
+	return compiled.dialectable.GenerateOutput(model)
+}
+
+// ParseToTree parses the input with the compiled dialect, returning the root Part of the parse tree
+func (compiled *CompiledDialect) ParseToTree(input string) (*Part, error, string) {
+	return compiled.ParseToTreeWithOptions(input, Options{})
+}
+
+// ParseToTreeWithOptions parses the input with the compiled dialect and the options for this parse, returning the root
+// Part of the parse tree
+func (compiled *CompiledDialect) ParseToTreeWithOptions(input string, options Options) (*Part, error, string) {
+	return compiled.ParseToTreeContext(context.Background(), input, options)
+}
+
+// ParseToTreeContext parses the input like ParseToTreeWithOptions, stopping with a ParseError wrapping the context's
+// error once the context is done
+func (compiled *CompiledDialect) ParseToTreeContext(ctx context.Context, input string, options Options) (*Part, error, string) {
+	return compiled.parseTree(ctx, compiled.dialect.RootName, input, options)
+}
+
+// ParsePart parses the input with the part of the compiled dialect as the root part, running the handlers of the parts
+// found against a fresh model, and returning the Part found, or a DialectError if the dialect doesn't define the part;
+// the part must consume the whole input unless the dialect sets AllowTrailing
+func (compiled *CompiledDialect) ParsePart(partName string, input string, options Options) (*Part, error, string) {
+	if _, ok := compiled.dialect.PartDefinitions[partName]; !ok {
+		return nil, &DialectError{PartName: partName, Message: "is not defined"}, ""
+	}
+	return compiled.parseTree(context.Background(), partName, input, options)
+}
+
+// parseTree parses the input like ParseToTreeContext, with the named part as the root part, returning the tree, error,
+// and log the way ParseToTree returns them
+func (compiled *CompiledDialect) parseTree(ctx context.Context, rootName string, input string, options Options) (*Part, error, string) {
+	return compiled.parseTreeResult(ctx, rootName, input, options).tree()
+}
+
+// parseTreeResult parses the input with the named part as the root part, returning the Result of the parse with its
+// Root
+func (compiled *CompiledDialect) parseTreeResult(ctx context.Context, rootName string, input string, options Options) Result {
+	parser, root, err := compiled.run(ctx, input, nil, 0, options, func(parser *Parser) {
+		parser.rootName = rootName
+	})
+	if parser == nil {
+		return Result{Status: StatusFailedSyntax, Err: err}
+	}
+	consumed := 0
+	if err != nil {
+		if *parser.status == StatusSucceeded {
+			*parser.status = StatusFailedSyntax
+		}
+	} else {
+		consumed = root.EndPos
+	}
+	result := newResult(err, consumed, *parser, options)
+	result.Root = root
+	return result
+}
+
+// keywordContinuation returns the regex matching a character that continues a word for the dialect
+func keywordContinuation(dialect *Dialect) string {
+	if dialect.KeywordContinuation == "" {
+		return DefaultKeywordContinuation
+	}
+	return dialect.KeywordContinuation
+}
@@ -0,0 +1,136 @@
+package dialects
+
+import "reflect"
+
+// MergeOption configures how MergeDialects compares the parts the dialects both define
+type MergeOption func(merger *merger)
+
+// merger provides the settings MergeDialects compares the parts the dialects both define with
+type merger struct {
+	sharedFunctions bool
+}
+
+// WithSharedFunctions makes MergeDialects treat the functions of parts defined by both dialects as the same if they
+// share their code, for callers who know their functions don't capture differing values, as closures made by the
+// same factory share their code whatever they capture
+func WithSharedFunctions() MergeOption {
+	return func(merger *merger) {
+		merger.sharedFunctions = true
+	}
+}
+
+// MergeDialects copies the part definitions of the source dialect into the target dialect, naming each after the
+// prefix and a dot, as in "query.expression", or as it is if the prefix is empty, and rewriting the references of the
+// source's parts to each other to match, so the target's constituents can refer to the source's parts by their
+// prefixed names and the source's grammar is defined in one place. A part the target already defines the same way is
+// left as it is, so fragments sharing parts can be merged under the same prefix. A part defined differently, or one
+// with functions such as handlers unless WithSharedFunctions is given, makes MergeDialects return a DialectError
+// naming both dialects without changing the target. Only the part definitions are copied, not settings such as
+// SkipPattern, and the handlers of the copied parts run against the target's model, so they must accept its type.
+func MergeDialects(target *Dialect, prefix string, source *Dialect, options ...MergeOption) error {
+	settings := &merger{}
+	for _, option := range options {
+		option(settings)
+	}
+	rename := func(name string) string {
+		if _, ok := source.PartDefinitions[name]; !ok || prefix == "" {
+			return name
+		}
+		return prefix + "." + name
+	}
+	merged := make(map[string]PartDefinition, len(source.PartDefinitions))
+	for _, name := range sortedPartNames(source) {
+		partDefinition := renameParts(source.PartDefinitions[name], rename)
+		partDefinition.origin = source.Title
+		existing, ok := target.PartDefinitions[rename(name)]
+		if !ok {
+			merged[rename(name)] = partDefinition
+			continue
+		}
+		if !samePartDefinition(existing, partDefinition, settings.sharedFunctions) {
+			origin := existing.origin
+			if origin == "" {
+				origin = target.Title
+			}
+			return &DialectError{PartName: rename(name), Message: "from dialect " + source.Title + " differs from the part of the same name from dialect " + origin}
+		}
+	}
+	if target.PartDefinitions == nil {
+		target.PartDefinitions = make(map[string]PartDefinition, len(merged))
+	}
+	for name, partDefinition := range merged {
+		target.PartDefinitions[name] = partDefinition
+	}
+	return nil
+}
+
+// samePartDefinition reports whether the part definitions define a part the same way, whichever dialects they came
+// from, where functions are never the same unless shared functions are allowed, in which case they're the same if
+// they share their code, as closures can't be told apart otherwise
+func samePartDefinition(a PartDefinition, b PartDefinition, sharedFunctions bool) bool {
+	functions := [][2]interface{}{
+		{a.Handler, b.Handler},
+		{a.ValidateMatch, b.ValidateMatch},
+		{a.FormatMatch, b.FormatMatch},
+		{a.HandlerE, b.HandlerE},
+		{a.ValidateMatchCtx, b.ValidateMatchCtx},
+		{a.ValidateMatchSeverity, b.ValidateMatchSeverity},
+		{a.Match, b.Match},
+	}
+	for _, pair := range functions {
+		if !sharedFunctions && (!reflect.ValueOf(pair[0]).IsNil() || !reflect.ValueOf(pair[1]).IsNil()) {
+			return false
+		}
+		if reflect.ValueOf(pair[0]).Pointer() != reflect.ValueOf(pair[1]).Pointer() {
+			return false
+		}
+	}
+	// compare the rest of the fields, leaving out the functions already compared
+	a.Handler, a.ValidateMatch, a.FormatMatch, a.HandlerE, a.ValidateMatchCtx, a.ValidateMatchSeverity, a.Match = nil, nil, nil, nil, nil, nil, nil
+	b.Handler, b.ValidateMatch, b.FormatMatch, b.HandlerE, b.ValidateMatchCtx, b.ValidateMatchSeverity, b.Match = nil, nil, nil, nil, nil, nil, nil
+	a.origin, b.origin = "", ""
+	return reflect.DeepEqual(a, b)
+}
+
+// renameParts returns a copy of the part definition with the names of the parts it refers to renamed
+func renameParts(partDefinition PartDefinition, rename func(string) string) PartDefinition {
+	if partDefinition.Constituents != nil {
+		constituents := make([][]string, len(partDefinition.Constituents))
+		for i, constituentSeq := range partDefinition.Constituents {
+			constituents[i] = make([]string, len(constituentSeq))
+			for j, constituentID := range constituentSeq {
+				constituents[i][j] = renameConstituent(constituentID, rename)
+			}
+		}
+		partDefinition.Constituents = constituents
+	}
+	if partDefinition.RecoverAt != nil {
+		recoverAt := make([]string, len(partDefinition.RecoverAt))
+		for i, name := range partDefinition.RecoverAt {
+			recoverAt[i] = rename(name)
+		}
+		partDefinition.RecoverAt = recoverAt
+	}
+	if partDefinition.expression != nil {
+		partDefinition.expression = &expression{operand: rename(partDefinition.expression.operand), levels: partDefinition.expression.levels}
+	}
+	return partDefinition
+}
+
+// renameConstituent returns the constituent ID with the names of its part and separator renamed, keeping its modifier
+func renameConstituent(constituentID string, rename func(string) string) string {
+	reference := parseConstituent(constituentID)
+	switch {
+	case reference.name == "":
+		return constituentID
+	case reference.modifier == "!" || reference.modifier == "&" || reference.modifier == "=":
+		return reference.modifier + rename(reference.name)
+	case reference.separator != "":
+		separator := "%"
+		if reference.trailing {
+			separator = "%%"
+		}
+		return rename(reference.name) + separator + rename(reference.separator) + reference.modifier
+	}
+	return rename(reference.name) + constituentID[len(reference.name):]
+}
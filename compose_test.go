@@ -0,0 +1,104 @@
+package dialects_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/AdamJonR/dialects"
+)
+
+// pipeline returns a grammar of queries joined by pipes, whose queries are merged in from the word list
+func pipeline(t *testing.T) grammar {
+	g := newGrammar("pipeline", map[string]dialects.PartDefinition{
+		"pipeline": {Constituents: [][]string{{"query.root%pipe+"}}},
+		"pipe":     {Literal: "| "},
+	})
+	if err := dialects.MergeDialects(&g.dialect, "query", wordList().NewDialect()); err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestMergeDialects(t *testing.T) {
+	g := pipeline(t)
+	// the references between the merged parts are renamed along with them
+	item := g.dialect.PartDefinitions["query.item"]
+	if strings.Join(item.Constituents[0], ", ") != "query.word, query.ws?" {
+		t.Errorf("expected the merged references to be prefixed, got %v", item.Constituents)
+	}
+	if errs := dialects.ValidateDialect(&g.dialect); len(errs) > 0 {
+		t.Fatalf("expected the merged dialect to be valid, got %v", errs)
+	}
+	// the merged handlers run against the model of the dialect they're merged into
+	output, err, _ := dialects.Parse(g, "ab cd | ef")
+	if err != nil || output != "query.item,query.item,query.item" {
+		t.Errorf("expected the items of both queries, got %q and %v", output, err)
+	}
+}
+
+// fragment returns a dialect generated from a schema, defining an identifier matching the regex
+func fragment(title string, identifier string) *dialects.Dialect {
+	return &dialects.Dialect{Title: title, RootName: "field", PartDefinitions: map[string]dialects.PartDefinition{
+		"field":   {Constituents: [][]string{{"!keyword", "id", "ws?"}}},
+		"id":      {Regex: identifier},
+		"keyword": {Keyword: "end"},
+		"ws":      {Regex: `[ ]+`, Ignore: true},
+	}}
+}
+
+func TestMergeDialectsCollision(t *testing.T) {
+	g := newGrammar("root", map[string]dialects.PartDefinition{
+		"root": {Constituents: [][]string{{"schema.field+"}}},
+	})
+	if err := dialects.MergeDialects(&g.dialect, "schema", fragment("users", `[a-z]+`)); err != nil {
+		t.Fatal(err)
+	}
+	// parts defined the same way by both fragments can be merged again
+	if err := dialects.MergeDialects(&g.dialect, "schema", fragment("accounts", `[a-z]+`)); err != nil {
+		t.Fatalf("expected the identical fragment to merge, got %v", err)
+	}
+	before := len(g.dialect.PartDefinitions)
+	var dialectError *dialects.DialectError
+	err := dialects.MergeDialects(&g.dialect, "schema", fragment("orders", `[0-9]+`))
+	if !errors.As(err, &dialectError) || err.Error() != "dialects error: part (schema.id) from dialect orders differs from the part of the same name from dialect users" {
+		t.Fatalf("expected a DialectError naming both dialects, got %v", err)
+	}
+	if len(g.dialect.PartDefinitions) != before || g.dialect.PartDefinitions["schema.id"].Regex != `[a-z]+` {
+		t.Errorf("expected the failed merge to leave the dialect unchanged, got %v", g.dialect.PartDefinitions)
+	}
+	output, err, _ := dialects.Parse(g, "ab cd")
+	if err != nil || output != "" {
+		t.Errorf("expected the merged parts to parse, got %q and %v", output, err)
+	}
+}
+
+// tagged returns a dialect whose identifiers are recorded with the tag, from a handler made by the same factory for
+// every tag
+func tagged(title string, tag string) *dialects.Dialect {
+	d := fragment(title, `[a-z]+`)
+	d.PartDefinitions["id"] = dialects.PartDefinition{Regex: `[a-z]+`, Handler: func(part *dialects.Part, model interface{}) bool {
+		*model.(*[]string) = append(*model.(*[]string), tag+":"+part.Value)
+		return true
+	}}
+	return d
+}
+
+func TestMergeDialectsClosures(t *testing.T) {
+	g := newGrammar("root", map[string]dialects.PartDefinition{
+		"root": {Constituents: [][]string{{"schema.field+"}}},
+	})
+	if err := dialects.MergeDialects(&g.dialect, "schema", tagged("a", "a")); err != nil {
+		t.Fatal(err)
+	}
+	// the handlers share their code but capture different tags, so they can't be told apart
+	var dialectError *dialects.DialectError
+	err := dialects.MergeDialects(&g.dialect, "schema", tagged("b", "b"))
+	if !errors.As(err, &dialectError) || dialectError.PartName != "schema.id" {
+		t.Fatalf("expected a DialectError for the part with a handler, got %v", err)
+	}
+	// callers who know their functions match can opt in to comparing them by their code
+	if err := dialects.MergeDialects(&g.dialect, "schema", tagged("b", "b"), dialects.WithSharedFunctions()); err != nil {
+		t.Errorf("expected the handlers sharing their code to merge, got %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package dialects
+
+import (
+	"strconv"
+	"strings"
+)
+
+// constituent provides the part name and modifier of a constituent ID used in a constituent sequence, along with the
+// bounds of a bounded repetition, whose max is -1 when it has no upper bound, and the separator of a separated list
+type constituent struct {
+	name      string
+	modifier  string
+	min       int
+	max       int
+	separator string
+	// trailing allows a separated list to end with a separator
+	trailing bool
+}
+
+// parseConstituent splits a constituent ID such as "statement+", "!keyword", "&digit", "=name", "flag{1,8}", or
+// "item%comma*" into its part name and modifier
+func parseConstituent(constituentID string) constituent {
+	if constituentID == "" {
+		return constituent{}
+	}
+	// a cut is a constituent of its own, naming no part
+	if constituentID == "^" {
+		return constituent{modifier: constituentID}
+	}
+	// lookaheads and backreferences are marked before the part name, as in PEG grammars
+	if firstChar := constituentID[:1]; firstChar == "!" || firstChar == "&" || firstChar == "=" {
+		return constituent{name: constituentID[1:], modifier: firstChar}
+	}
+	switch lastChar := constituentID[len(constituentID)-1:]; lastChar {
+	case "+", "*":
+		return parseSeparator(constituent{name: constituentID[:len(constituentID)-1], modifier: lastChar})
+	case "?":
+		return constituent{name: constituentID[:len(constituentID)-1], modifier: lastChar}
+	case "}":
+		if reference, ok := parseBounds(constituentID); ok {
+			return reference
+		}
+	}
+	return constituent{name: constituentID}
+}
+
+// parseBounds splits a bounded repetition such as "coordinate{3}", "flag{1,8}", or "digit{2,}" into its part name and
+// bounds, with ok reporting whether the bounds are well formed
+func parseBounds(constituentID string) (reference constituent, ok bool) {
+	open := strings.LastIndexByte(constituentID, '{')
+	if open < 1 {
+		return constituent{}, false
+	}
+	minBound, maxBound, ranged := strings.Cut(constituentID[open+1:len(constituentID)-1], ",")
+	min, err := strconv.Atoi(minBound)
+	if err != nil || min < 0 {
+		return constituent{}, false
+	}
+	max := min
+	switch {
+	case ranged && maxBound == "":
+		max = -1
+	case ranged:
+		if max, err = strconv.Atoi(maxBound); err != nil || max < 0 {
+			return constituent{}, false
+		}
+	}
+	return constituent{name: constituentID[:open], modifier: "{}", min: min, max: max}, true
+}
+
+// parseSeparator splits the separator from the name of a repetition such as "item%comma*", where a doubled %, as in
+// "item%%comma*", allows a trailing separator
+func parseSeparator(reference constituent) constituent {
+	name, separator, separated := strings.Cut(reference.name, "%")
+	if !separated || strings.Trim(separator, "%") == "" {
+		return reference
+	}
+	reference.name = name
+	reference.separator, reference.trailing = strings.CutPrefix(separator, "%")
+	return reference
+}
+
+// Constituent provides the part Name and Modifier of a constituent ID, for tools that work with a grammar's structure,
+// where the Modifier is one of "?", "*", "+", "!", "&", "=", "^" for a cut (naming no part), "{}" for a bounded
+// repetition from Min to Max times (with no upper bound when Max is -1), or empty, and a separated list has the
+// Separator part, with TrailingSeparator set when it can end with one
+type Constituent struct {
+	Name              string
+	Modifier          string
+	Min               int
+	Max               int
+	Separator         string
+	TrailingSeparator bool
+}
+
+// ParseConstituent splits a constituent ID such as "statement+", "!keyword", "flag{1,8}", or "item%comma*" into its
+// part name and modifier
+func ParseConstituent(constituentID string) Constituent {
+	reference := parseConstituent(constituentID)
+	return Constituent{
+		Name:              reference.name,
+		Modifier:          reference.modifier,
+		Min:               reference.min,
+		Max:               reference.max,
+		Separator:         reference.separator,
+		TrailingSeparator: reference.trailing,
+	}
+}
+
+// Repeats reports whether the constituent can match its part more than once
+func (c Constituent) Repeats() bool {
+	return repeats(constituent{modifier: c.Modifier, max: c.Max})
+}
+
+// Optional reports whether the constituent can match without its part
+func (c Constituent) Optional() bool {
+	return c.Modifier == "?" || c.Modifier == "*" || (c.Modifier == "{}" && c.Min == 0)
+}
+
+// Consumes reports whether the constituent matches the text of its part, keeping it in the tree, which lookaheads and
+// cuts never do
+func (c Constituent) Consumes() bool {
+	return c.Modifier != "!" && c.Modifier != "&" && c.Modifier != "^"
+}
@@ -0,0 +1,130 @@
+package dialects
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Coverage provides the parts, and the alternatives of their constituent sequences, matched by the parses given it
+// through Options.Coverage, which may run concurrently, where the zero value has recorded nothing
+type Coverage struct {
+	mutex        sync.Mutex
+	parts        map[string]int
+	alternatives map[string]map[int]int
+}
+
+// CoverageReport provides the parts of a grammar that the parses recorded by a Coverage never matched, in order of
+// name, along with the alternatives never matched of the parts that were
+type CoverageReport struct {
+	UncoveredParts        []string
+	UncoveredAlternatives []UncoveredAlternative
+}
+
+// UncoveredAlternative provides a constituent sequence never matched, where Alternative numbers the sequences of the
+// part from 1
+type UncoveredAlternative struct {
+	PartName    string
+	Alternative int
+	Sequence    []string
+}
+
+// coverMark provides a part matched by a parse, along with the alternative of its constituent sequences matched, or
+// -1 for the part itself, and the position it ended at
+type coverMark struct {
+	partName    string
+	alternative int
+	end         int
+}
+
+// coverPart records the part as matched if it was found outside a lookahead, returning the parts found
+func coverPart(partName string, parts []*Part, parser Parser) []*Part {
+	if parts != nil && parser.coverage != nil && !parser.lookahead {
+		parser.coverage.items = append(parser.coverage.items, coverMark{partName: partName, alternative: -1, end: *parser.currentPosPointer})
+	}
+	return parts
+}
+
+// coverAlternative records the alternative of the part as matched if it was found outside a lookahead
+func coverAlternative(partName string, alternative int, parser Parser) {
+	if parser.coverage == nil || parser.lookahead {
+		return
+	}
+	parser.coverage.items = append(parser.coverage.items, coverMark{partName: partName, alternative: alternative, end: *parser.currentPosPointer})
+}
+
+// add adds the parts and alternatives a parse matched to the coverage, if there is one, which are those of the
+// attempt that reached farthest if the parse failed, as backtracking discards the rest
+func (coverage *Coverage) add(err error, covered *trail[coverMark]) {
+	if coverage == nil || covered == nil {
+		return
+	}
+	marks := covered.items
+	if err != nil {
+		marks = covered.farthestItems()
+	}
+	coverage.mutex.Lock()
+	if coverage.parts == nil {
+		coverage.parts = make(map[string]int)
+		coverage.alternatives = make(map[string]map[int]int)
+	}
+	for _, mark := range marks {
+		if mark.alternative < 0 {
+			coverage.parts[mark.partName]++
+			continue
+		}
+		if coverage.alternatives[mark.partName] == nil {
+			coverage.alternatives[mark.partName] = make(map[int]int)
+		}
+		coverage.alternatives[mark.partName][mark.alternative]++
+	}
+	coverage.mutex.Unlock()
+}
+
+// Report returns the parts of the dialect, and the alternatives of the parts matched, that no recorded parse matched
+func (coverage *Coverage) Report(dialectable Dialectable) CoverageReport {
+	dialect := dialectable.NewDialect()
+	var report CoverageReport
+	coverage.mutex.Lock()
+	for _, name := range sortedPartNames(dialect) {
+		if coverage.parts[name] == 0 {
+			report.UncoveredParts = append(report.UncoveredParts, name)
+			continue
+		}
+		for i, constituentSeq := range dialect.PartDefinitions[name].Constituents {
+			if coverage.alternatives[name][i] == 0 {
+				report.UncoveredAlternatives = append(report.UncoveredAlternatives, UncoveredAlternative{PartName: name, Alternative: i + 1, Sequence: constituentSeq})
+			}
+		}
+	}
+	coverage.mutex.Unlock()
+	return report
+}
+
+// CoverExamples returns the report of the parts and alternatives that none of the dialect's Examples match, which is
+// every part if the dialect doesn't compile
+func CoverExamples(dialectable Dialectable) CoverageReport {
+	coverage := &Coverage{}
+	dialect := dialectable.NewDialect()
+	if compiled, err := Compile(dialectable); err == nil {
+		for _, input := range dialect.Examples {
+			compiled.ParseWithOptions(input, Options{NoTrace: true, Coverage: coverage})
+		}
+	}
+	return coverage.Report(dialectable)
+}
+
+// String returns the report as text, with a line for each uncovered part and alternative
+func (report CoverageReport) String() string {
+	if len(report.UncoveredParts) == 0 && len(report.UncoveredAlternatives) == 0 {
+		return "every part and alternative is covered\n"
+	}
+	var b strings.Builder
+	for _, name := range report.UncoveredParts {
+		b.WriteString("part " + name + " is never matched\n")
+	}
+	for _, uncovered := range report.UncoveredAlternatives {
+		b.WriteString("part " + uncovered.PartName + ": alternative " + strconv.Itoa(uncovered.Alternative) + " (" + strings.Join(uncovered.Sequence, ", ") + ") is never matched\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,65 @@
+package dialects_test
+
+import (
+	"testing"
+
+	"github.com/AdamJonR/dialects"
+)
+
+func TestCoverage(t *testing.T) {
+	g := alternatives()
+	coverage := &dialects.Coverage{}
+	// coverage adds up across parses
+	for _, input := range []string{"ab 1", "cd"} {
+		if _, err, _ := dialects.ParseWithOptions(g, input, dialects.Options{Coverage: coverage}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	report := coverage.Report(g)
+	if len(report.UncoveredAlternatives) != 2 || report.UncoveredAlternatives[0].PartName != "item" || report.UncoveredAlternatives[0].Alternative != 2 {
+		t.Errorf("expected item's string and symbol alternatives to be uncovered, got %+v", report.UncoveredAlternatives)
+	}
+	expected := "part string is never matched\npart symbol is never matched\n" +
+		"part item: alternative 2 (string, ws?) is never matched\npart item: alternative 3 (symbol, ws?) is never matched\n"
+	if report.String() != expected {
+		t.Errorf("expected report %q, got %q", expected, report.String())
+	}
+}
+
+func TestCoverageLookahead(t *testing.T) {
+	g := newGrammar("root", map[string]dialects.PartDefinition{
+		"root":   {Constituents: [][]string{{"&letter", "name"}}},
+		"letter": {Regex: `[a-z]`},
+		"name":   {Regex: `[a-z0-9]+`},
+	})
+	g.dialect.Examples = map[string]string{"name": "a1"}
+	// the letter is only found by the lookahead, whose parts are discarded
+	if report := dialects.CoverExamples(g); len(report.UncoveredParts) != 1 || report.UncoveredParts[0] != "letter" {
+		t.Errorf("expected letter to be uncovered, got %+v", report)
+	}
+	g.dialect.Examples = nil
+	if report := dialects.CoverExamples(g).String(); report != "part letter is never matched\npart name is never matched\npart root is never matched\n" {
+		t.Errorf("expected every part to be uncovered without examples, got %q", report)
+	}
+}
+
+func TestCoverageBacktracking(t *testing.T) {
+	g := newGrammar("root", map[string]dialects.PartDefinition{
+		"root": {Constituents: [][]string{{"head", "bang"}, {"word", "dot"}}},
+		"head": {Constituents: [][]string{{"word"}}},
+		"word": {Regex: `[a-z]+`},
+		"bang": {Literal: "!"},
+		"dot":  {Literal: "."},
+	})
+	// the head matches before its alternative is abandoned, which discards it along with its coverage
+	for _, memoize := range []bool{false, true} {
+		coverage := &dialects.Coverage{}
+		if _, err, _ := dialects.ParseWithOptions(g, "ab.", dialects.Options{Coverage: coverage, Memoize: memoize}); err != nil {
+			t.Fatal(err)
+		}
+		expected := "part bang is never matched\npart head is never matched\npart root: alternative 1 (head, bang) is never matched\n"
+		if report := coverage.Report(g).String(); report != expected {
+			t.Errorf("memoize %v: expected report %q, got %q", memoize, expected, report)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package dialects
+
+import (
+	"strconv"
+	"unicode/utf8"
+)
+
+// DebugKind identifies the decision point of a parse that a DebugStep stops at
+type DebugKind int
+
+const (
+	// DebugEnter reports that a part is about to be attempted at the current position
+	DebugEnter DebugKind = iota
+	// DebugSequence reports that a constituent sequence of a part is about to be tried
+	DebugSequence
+	// DebugMatch reports that a regex, literal, keyword, or Match part matched its Value at the current position,
+	// which it's about to consume
+	DebugMatch
+	// DebugFound reports that the part was found, with the parser positioned after it
+	DebugFound
+	// DebugBacktrack reports that the part wasn't found, with the parser back where the part was attempted
+	DebugBacktrack
+)
+
+// String returns the name of the kind of step
+func (kind DebugKind) String() string {
+	switch kind {
+	case DebugEnter:
+		return "enter"
+	case DebugSequence:
+		return "sequence"
+	case DebugMatch:
+		return "match"
+	case DebugFound:
+		return "found"
+	case DebugBacktrack:
+		return "backtrack"
+	}
+	return "DebugKind(" + strconv.Itoa(int(kind)) + ")"
+}
+
+// DebugStep provides the decision point of a parse passed to Options.Debug
+type DebugStep struct {
+	Kind DebugKind
+	// PartName holds the part attempted, found, or matched, or whose constituent sequence is tried
+	PartName string
+	// Sequence holds the constituent sequence tried for DebugSequence
+	Sequence []string
+	// Value holds the text matched for DebugMatch
+	Value string
+	// StartPos holds the offset where the part was attempted, or where the sequence or match starts
+	StartPos int
+}
+
+// ParserState provides a view of the parser at a DebugStep, which is only valid during the call to Options.Debug
+type ParserState struct {
+	// Pos, Line, and Column hold the current offset and its 1-based line and byte column
+	Pos    int
+	Line   int
+	Column int
+	// Depth holds the number of parts being found, which is the length of Parts
+	Depth int
+	// Parts holds the names of the parts being found, from the root to the innermost, which is the step's part for
+	// every kind of step but DebugSequence
+	Parts []string
+	// Lookahead reports whether the step is within a lookahead, whose parts are discarded
+	Lookahead bool
+	input     string
+}
+
+// Preview returns up to the number of runes of the input after the current position, for showing the text the parser
+// is about to see
+func (state ParserState) Preview(runes int) string {
+	end := state.Pos
+	for i := 0; i < runes && end < len(state.input); i++ {
+		_, width := utf8.DecodeRuneInString(state.input[end:])
+		end += width
+	}
+	return state.input[state.Pos:end]
+}
+
+// Preceding returns up to the number of runes of the input before the current position, for showing the text the
+// parser has just consumed
+func (state ParserState) Preceding(runes int) string {
+	start := state.Pos
+	for i := 0; i < runes && start > 0; i++ {
+		_, width := utf8.DecodeLastRuneInString(state.input[:start])
+		start -= width
+	}
+	return state.input[start:state.Pos]
+}
+
+// debugStep passes the step to the debugger, if any, along with the state of the parser, where the part stack is the
+// path of the parent followed by the parent and the step's part, returning false after aborting the parse if the
+// debugger stops it
+func debugStep(step DebugStep, parser Parser, parent *Part) bool {
+	if parser.debug == nil {
+		return true
+	}
+	var parts []string
+	if parent != nil {
+		parts = append(append(parts, parent.Path...), parent.Name)
+	}
+	parts = append(parts, step.PartName)
+	state := ParserState{
+		Pos:       *parser.currentPosPointer,
+		Line:      currentLine(parser),
+		Column:    parser.cursor.column,
+		Depth:     len(parts),
+		Parts:     parts,
+		Lookahead: parser.lookahead,
+		input:     parser.input,
+	}
+	if parser.debug(step, state) {
+		return true
+	}
+	abortParse(newParseError(parser, state.Pos, state.Line, step.PartName, "parse stopped by debugger"), parser)
+	return false
+}
@@ -0,0 +1,52 @@
+package dialects_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/AdamJonR/dialects"
+)
+
+func TestDebug(t *testing.T) {
+	var steps []string
+	debug := func(step dialects.DebugStep, state dialects.ParserState) bool {
+		steps = append(steps, step.Kind.String()+" "+strings.Join(state.Parts, "/")+" "+state.Preceding(1)+"|"+state.Preview(2))
+		return true
+	}
+	output, err, _ := dialects.ParseWithOptions(wordList(), "ab cd", dialects.Options{Debug: debug})
+	if err != nil || output != "item,item" {
+		t.Fatalf("expected the parse to succeed, got %q and %v", output, err)
+	}
+	expected := []string{
+		"enter root |ab",
+		"sequence root |ab",
+		"enter root/item |ab",
+		"sequence root/item |ab",
+		"enter root/item/word |ab",
+		"match root/item/word |ab",
+		"found root/item/word b| c",
+		"enter root/item/ws b| c",
+		"match root/item/ws b| c",
+		"found root/item/ws  |cd",
+		"found root/item  |cd",
+	}
+	if len(steps) < len(expected) || strings.Join(steps[:len(expected)], "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("expected the steps to start with\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(steps, "\n"))
+	}
+	// the last attempt to find an item fails at the end of the input
+	if last := steps[len(steps)-2:]; last[0] != "backtrack root/item d|" || last[1] != "found root d|" {
+		t.Errorf("expected the steps to end with a backtrack and the root, got %q", last)
+	}
+}
+
+func TestDebugStop(t *testing.T) {
+	debug := func(step dialects.DebugStep, state dialects.ParserState) bool {
+		return step.Kind != dialects.DebugMatch || step.Value != "cd"
+	}
+	_, err, _ := dialects.ParseWithOptions(wordList(), "ab cd", dialects.Options{Debug: debug})
+	var parseError *dialects.ParseError
+	if !errors.As(err, &parseError) || parseError.Message != "parse stopped by debugger" || parseError.Offset != 3 || parseError.PartName != "word" {
+		t.Errorf("expected the parse to stop at the second word, got %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package dialects
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+)
+
+// Severity provides how serious a Diagnostic is
+type Severity int
+
+const (
+	// SeverityError marks a diagnostic for an error that failed the parse, or that the parse recovered from
+	SeverityError Severity = iota
+	// SeverityWarning marks a diagnostic for a suspicious but legal part, which doesn't fail the parse
+	SeverityWarning
+)
+
+// String returns the name of the severity
+func (severity Severity) String() string {
+	if severity == SeverityWarning {
+		return "warning"
+	}
+	return "error"
+}
+
+// Diagnostic provides a problem found in the input by a parse, where Offset, Line, and Column give where it starts
+// and Length the bytes of input it covers, which is zero for a syntax error at a point
+type Diagnostic struct {
+	Severity Severity
+	Message  string
+	Line     int
+	Column   int
+	Offset   int
+	Length   int
+	PartName string
+	// VisualColumn holds the column where the diagnostic starts as displayed with Options.TabWidth, for consumers
+	// that place it on screen, while Offset remains the authoritative position
+	VisualColumn int
+	// Suggestion holds the literal or keyword text that the word at a syntax error is probably a misspelling of, so
+	// tooling can offer to replace the Length of input with it
+	Suggestion string
+}
+
+// String returns the diagnostic as text, as in "line 2, column 5: error: expected value"
+func (diagnostic Diagnostic) String() string {
+	return "line " + strconv.Itoa(diagnostic.Line) + ", column " + strconv.Itoa(diagnostic.Column) + ": " + diagnostic.Severity.String() + ": " + diagnostic.Message
+}
+
+// Warning provides the error a HandlerE returns to flag a suspicious but legal part, which accepts the part rather
+// than rejecting it, and is reported as a warning Diagnostic through Options.Diagnostics
+type Warning struct {
+	Message string
+}
+
+// Error returns the message of the warning
+func (w *Warning) Error() string {
+	return w.Message
+}
+
+// warned reports whether the error returned by a HandlerE is a Warning, recording it for the part if so
+func warned(err error, part *Part, parser Parser) bool {
+	var warning *Warning
+	if !errors.As(err, &warning) {
+		return false
+	}
+	recordWarning(warning.Message, part.Name, part.StartPos, part.EndPos-part.StartPos, part.StartLine, parser)
+	return true
+}
+
+// recordWarning adds a warning for the part starting at the offset, unless within a lookahead
+func recordWarning(message string, partName string, offset int, length int, line int, parser Parser) {
+	if parser.warnings == nil || parser.lookahead {
+		return
+	}
+	parser.warnings.items = append(parser.warnings.items, Diagnostic{Severity: SeverityWarning, Message: message, Line: line, Column: column(parser.input, offset), VisualColumn: visualColumn(parser.input, offset, parser.tabWidth), Offset: offset, Length: length, PartName: partName})
+}
+
+// errorDiagnostic returns the diagnostic for an error the parse failed with or recovered from, with ok reporting
+// whether the error has a position
+func errorDiagnostic(err error) (diagnostic Diagnostic, ok bool) {
+	var parseError *ParseError
+	if !errors.As(err, &parseError) {
+		var limitError *LimitError
+		if !errors.As(err, &limitError) {
+			return Diagnostic{}, false
+		}
+		parseError = limitError.ParseError
+	}
+	return Diagnostic{Severity: SeverityError, Message: parseError.Message, Line: parseError.Line, Column: parseError.Column, VisualColumn: parseError.VisualColumn, Offset: parseError.Offset, Length: parseError.length, PartName: parseError.PartName, Suggestion: parseError.Suggestion}, true
+}
+
+// recordDiagnostics sets Options.Diagnostics to the warnings of the parse and the errors it failed with or recovered
+// from, in order of position, with positions in the original input
+func recordDiagnostics(err error, parser Parser, mapping *preprocessing, options Options) {
+	if options.Diagnostics == nil {
+		return
+	}
+	var diagnostics []Diagnostic
+	for _, warning := range parser.warnings.items {
+		if mapping != nil {
+			end, _, _, _, _ := mapping.position(warning.Offset + warning.Length)
+			warning.Offset, warning.Line, warning.Column, _, _ = mapping.position(warning.Offset)
+			warning.VisualColumn = visualColumn(mapping.original, warning.Offset, options.TabWidth)
+			warning.Length = end - warning.Offset
+		}
+		diagnostics = append(diagnostics, warning)
+	}
+	errs := []error{err}
+	var recovered *RecoveredError
+	if errors.As(err, &recovered) {
+		errs = recovered.Errors
+	}
+	for _, err := range errs {
+		if diagnostic, ok := errorDiagnostic(err); ok {
+			diagnostics = append(diagnostics, diagnostic)
+		}
+	}
+	sort.SliceStable(diagnostics, func(i, j int) bool { return diagnostics[i].Offset < diagnostics[j].Offset })
+	*options.Diagnostics = diagnostics
+}
@@ -0,0 +1,94 @@
+package dialects_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/AdamJonR/dialects"
+)
+
+func TestDiagnostics(t *testing.T) {
+	g := settings()
+	value := g.dialect.PartDefinitions["value"]
+	value.HandlerE = func(part *dialects.Part, model interface{}) error {
+		switch part.Value {
+		case "0":
+			return &dialects.Warning{Message: "zero disables the setting"}
+		case "999":
+			return errors.New("value too large")
+		}
+		return nil
+	}
+	g.dialect.PartDefinitions["value"] = value
+	var diagnostics []dialects.Diagnostic
+	root, err, _ := dialects.ParseToTreeWithOptions(g, "a = 0\nb = x\nc = 999\nd = 4\n", dialects.Options{Diagnostics: &diagnostics})
+	var recovered *dialects.RecoveredError
+	if !errors.As(err, &recovered) || len(recovered.Errors) != 2 || root == nil {
+		t.Fatalf("expected the tree along with 2 errors, got %v", err)
+	}
+	// the warning accepts its part, while the syntax error and the handler's error are recovered from
+	expected := []string{
+		"line 1, column 5: warning: zero disables the setting",
+		"line 2, column 5: error: expected value",
+		"line 3, column 5: error: value too large",
+	}
+	if len(diagnostics) != len(expected) {
+		t.Fatalf("expected %d diagnostics, got %v", len(expected), diagnostics)
+	}
+	for i, diagnostic := range diagnostics {
+		if diagnostic.String() != expected[i] {
+			t.Errorf("expected %q, got %q", expected[i], diagnostic.String())
+		}
+	}
+	if warning := diagnostics[0]; warning.Severity != dialects.SeverityWarning || warning.Offset != 4 || warning.Length != 1 || warning.PartName != "value" {
+		t.Errorf("expected the warning to cover the value at offset 4, got %+v", warning)
+	}
+	if handlerError := diagnostics[2]; handlerError.Severity != dialects.SeverityError || handlerError.Length != 3 {
+		t.Errorf("expected the handler's error to cover the value, got %+v", handlerError)
+	}
+	// a parse with only warnings succeeds
+	if _, err, _ := dialects.ParseWithOptions(g, "a = 0\n", dialects.Options{Diagnostics: &diagnostics}); err != nil || len(diagnostics) != 1 {
+		t.Errorf("expected the parse to succeed with a warning, got %v and %v", err, diagnostics)
+	}
+	// the error a parse fails with is a diagnostic too
+	if _, err, _ := dialects.ParseWithOptions(wordList(), "ab 12", dialects.Options{Diagnostics: &diagnostics}); err == nil || len(diagnostics) != 1 || diagnostics[0].Column != 4 {
+		t.Errorf("expected the syntax error as a diagnostic, got %v", diagnostics)
+	}
+}
+
+func TestValidateMatchSeverity(t *testing.T) {
+	g := settings()
+	value := g.dialect.PartDefinitions["value"]
+	value.Handler = func(part *dialects.Part, model interface{}) bool {
+		names := model.(*[]string)
+		*names = append(*names, part.Value)
+		return true
+	}
+	value.ValidateMatchSeverity = func(matches []string, pos int, line int, model interface{}) (dialects.Severity, string) {
+		switch {
+		case strings.HasPrefix(matches[0], "0") && matches[0] != "0":
+			return dialects.SeverityWarning, "leading zero"
+		case len(matches[0]) > 3:
+			return dialects.SeverityError, "value too large"
+		}
+		return dialects.SeverityWarning, ""
+	}
+	g.dialect.PartDefinitions["value"] = value
+	var diagnostics []dialects.Diagnostic
+	output, err, trace := dialects.ParseWithOptions(g, "a = 1\nb = 07\nc = 3\n", dialects.Options{Diagnostics: &diagnostics})
+	// the warning doesn't fail the parse, so the model gets every value
+	if err != nil || output != "1,07,3" {
+		t.Fatalf("expected the values in the model, got %q and %v", output, err)
+	}
+	if len(diagnostics) != 1 || diagnostics[0].String() != "line 2, column 5: warning: leading zero" || diagnostics[0].Length != 2 || diagnostics[0].PartName != "value" {
+		t.Fatalf("expected the warning on line 2, got %+v", diagnostics)
+	}
+	if !strings.Contains(trace, "warning for value starting on line 2: leading zero") {
+		t.Errorf("expected the warning in the trace, got %q", trace)
+	}
+	// an error rejects the match like ValidateMatch
+	if _, err, _ := dialects.ParseWithOptions(g, "a = 1234\n", dialects.Options{Diagnostics: &diagnostics}); err == nil || !strings.Contains(err.Error(), "invalid value: value too large") {
+		t.Errorf("expected the value to be rejected, got %v", err)
+	}
+}
@@ -3,10 +3,12 @@ package dialects
 
 import (
 	"bytes"
-	"errors"
+	"context"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 // PartDefinition provides the struct that's used to define the various parts of a grammar
@@ -18,6 +20,56 @@ type PartDefinition struct {
 	Regex         string
 	ValidateMatch func([]string) (bool, string)
 	FormatMatch   func([]string) string
+	// HandlerE is used in place of Handler when set, and a returned error both rejects the part and is reported by Parse,
+	// unless it's a Warning, which accepts the part
+	HandlerE func(*Part, interface{}) error
+	// Literal is used in place of a Regex to match exactly the text, with no regex semantics
+	Literal string
+	// Keyword is used in place of a Regex to match exactly the text when it isn't followed by a character that would
+	// continue it into a longer word, as set by Dialect.KeywordContinuation
+	Keyword string
+	// ValidateMatchCtx is used in place of ValidateMatch when set, and also receives the offset and line where the
+	// match starts along with the model
+	ValidateMatchCtx func(matches []string, pos int, line int, model interface{}) (bool, string)
+	// ValidateMatchSeverity is used in place of ValidateMatchCtx when set, returning a message and its severity,
+	// where a SeverityError rejects the match like ValidateMatchCtx, a SeverityWarning accepts it, reporting the
+	// warning through Options.Diagnostics and the trace, and an empty message accepts it
+	ValidateMatchSeverity func(matches []string, pos int, line int, model interface{}) (Severity, string)
+	// CaseInsensitive lets the part's Regex, Literal, or Keyword match in any case, keeping the case of the input in
+	// the part's Value
+	CaseInsensitive bool
+	// CaseSensitive keeps the part matching in its case when Dialect.CaseInsensitive is set
+	CaseSensitive bool
+	// EOF is used in place of a Regex to match only at the end of the input, consuming nothing, and the part found is
+	// always Ignored
+	EOF bool
+	// Match is used in place of a Regex to match text that a regex can't, such as balanced delimiters, returning the
+	// length of the input it consumes from pos, the part's Value, and whether it matched
+	Match func(input string, pos int) (length int, value string, ok bool)
+	// NoSkip turns off Dialect.SkipPattern within the part and its descendants, for parts like string literals whose
+	// text must be matched as written, though text is still skipped before the part itself
+	NoSkip bool
+	// AllowLeftRecursion lets the part refer to itself before consuming any input, as in expr -> expr minus number,
+	// finding it by repeatedly growing the shortest match at the position into a longer one
+	AllowLeftRecursion bool
+	// PrintHint gives the text Print writes for a part that a transform added to the tree rather than the parser
+	// finding it, where PrintHintContent stands for the part's own text, as in "{} " for canonical spacing after it,
+	// and a hint without it replaces the part's text, as in " = " for an operator
+	PrintHint string
+	// RecoverAt names the parts, such as a newline or semicolon, that the parser skips to when the part fails after
+	// matching some of its input, recording the syntax error and putting an ErrorPart holding the text skipped in
+	// its place, so a repetition of the part continues and every error in the input can be reported
+	RecoverAt []string
+	// Exported marks a part that's parsed on its own, as with ParsePart, so UnreachableParts doesn't report it, or the
+	// parts it refers to, when the root can't reach it
+	Exported bool
+	// expression is set by ExpressionPart to match operands joined by operators, nested by precedence
+	expression *expression
+	// origin holds the title of the dialect that MergeDialects copied the part from
+	origin string
+	// templateProblems hold the problems with the arguments of the template the part was instantiated from, which
+	// ValidateDialect reports
+	templateProblems []string
 }
 
 // Dialect defines the DSL Title, Description, Examples, grammar, and Model
@@ -29,8 +81,69 @@ type Dialect struct {
 	PartDefinitions map[string]PartDefinition
 	Model           interface{}
 	Version         float64
+	// UnanchoredRegexes restores the legacy behavior of letting part regexes match anywhere after the current position
+	UnanchoredRegexes bool
+	// OmitSnippets leaves the offending line of input out of parse errors, which helps with huge single-line inputs
+	OmitSnippets bool
+	// DeferHandlers queues Handler calls until the root part has matched, so alternatives that are abandoned while
+	// backtracking never reach the model (handlers can then only reject the whole parse, not individual parts)
+	DeferHandlers bool
+	// AllowTrailing lets the root part match a prefix of the input, ignoring any unconsumed text that follows
+	AllowTrailing bool
+	// KeywordContinuation holds the regex matching a character that continues a word, so keywords can't match the
+	// start of a longer word, using DefaultKeywordContinuation when empty
+	KeywordContinuation string
+	// CaseInsensitiveKeywords lets Keyword parts match their text in any case
+	CaseInsensitiveKeywords bool
+	// CaseInsensitive lets the Regex, Literal, and Keyword parts match in any case, unless a part sets CaseSensitive
+	CaseInsensitive bool
+	// SkipPattern holds a regex for text, such as whitespace and comments, to skip before each part other than the root
+	// and after the root part, so the grammar needn't define parts for it everywhere
+	SkipPattern string
+	// NormalizeLineEndings collapses each CRLF line ending of the input into a \n before it's parsed, and before any
+	// Preprocessor sees it, so regexes needn't allow for the \r, while positions are still reported in the input as
+	// it was given
+	NormalizeLineEndings bool
+	// AllowInvalidUTF8 lets the input hold invalid UTF-8 byte sequences, which otherwise fail the parse with an
+	// EncodingError before it starts, for dialects that parse text in legacy encodings byte by byte
+	AllowInvalidUTF8 bool
+	// LineComment holds the text that starts a comment running to the end of the line, such as "//", which is skipped
+	// along with the text matching SkipPattern
+	LineComment string
+	// BlockComment holds the texts that start and end a comment that can span lines, such as "/*" and "*/", which is
+	// skipped along with the text matching SkipPattern
+	BlockComment [2]string
+	// ExpectedOutputs maps the names of Examples to the output GenerateOutput should produce for them, which
+	// TestExamples checks
+	ExpectedOutputs map[string]string
+	// CounterExamples maps names to inputs the dialect should reject, which TestCounterExamples checks
+	CounterExamples map[string]string
+	// ExpectedErrors maps the names of CounterExamples to text the parse error should contain, such as "line 3" or
+	// "expected number", which TestCounterExamples checks
+	ExpectedErrors map[string]string
+	// VersionPart names the part whose Value holds the version of the dialect that the input declares it's written in,
+	// as in "2.1" for a "version 2.1" directive, which is checked against the Version as soon as the part is found,
+	// aborting the parse if it isn't compatible
+	VersionPart string
+	// CheckVersion is used in place of CompatibleVersion to check the version the input declares against the Version,
+	// returning an error if the parser can't parse it
+	CheckVersion func(declared string, version float64) error
 }
 
+// SkippedPartName provides the name of the Ignored parts holding the text skipped by Dialect.SkipPattern, which are
+// only kept with Options.KeepIgnored
+const SkippedPartName = "$skipped"
+
+// CommentPartName provides the name of the Ignored parts holding the comments skipped for Dialect.LineComment and
+// Dialect.BlockComment, which are only kept with Options.KeepIgnored
+const CommentPartName = "$comment"
+
+// PrintHintContent provides the placeholder in a PartDefinition's PrintHint for the text of the part itself
+const PrintHintContent = "{}"
+
+// DefaultKeywordContinuation provides the characters that continue a word when Dialect.KeywordContinuation is empty
+const DefaultKeywordContinuation = `[A-Za-z0-9_]`
+
 // Dialectable defines the interface all DSL grammars must fulfill
 type Dialectable interface {
 	NewDialect() *Dialect
@@ -38,6 +151,13 @@ type Dialectable interface {
 	GenerateOutput(model interface{}) (string, error)
 }
 
+// TreeAware defines the optional interface for grammars whose output depends on the parse tree as well as the model,
+// such as to preserve the formatting of untouched sections, which Parse prefers to GenerateOutput, passing the root
+// Part, whose positions are in the input as it was given, along with that input
+type TreeAware interface {
+	GenerateOutputWithTree(model interface{}, root *Part, input string) (string, error)
+}
+
 // Part provides a convenient storage container for the corresponding properties of parsed parts of an input string
 type Part struct {
 	Name         string
@@ -48,218 +168,1279 @@ type Part struct {
 	Parent       *Part
 	Value        string
 	Constituents []*Part
-}
-
-type Log struct {
-	buffer      *bytes.Buffer
-	indent      string
-	indentLevel int
-	currentLine int
+	// StartLine, StartCol, EndLine, and EndCol hold the 1-based line and rune column (counting a tab as one column)
+	// of StartPos and EndPos
+	StartLine int
+	StartCol  int
+	EndLine   int
+	EndCol    int
+	// StartRune and EndRune hold StartPos and EndPos counted in runes rather than bytes, and are only set when parsing
+	// with Options.RunePositions
+	StartRune int
+	EndRune   int
+	// Matches holds the match and submatches of a regex part whose regex has capture groups, and Groups holds the
+	// submatches of its named groups by name
+	Matches []string
+	Groups  map[string]string
+	// Found reports that the parser found the part in the input, as opposed to a part a transform added, and Source
+	// holds the text of the input it spans, which differs from its Value when FormatMatch changed that
+	Found  bool
+	Source string
 }
 
 // Parser provides a simple container for the primary parsing variables
 type Parser struct {
-	status            string
+	status            *Status
 	currentPosPointer *int
 	input             string
 	output            string
 	dialect           *Dialect
 	model             interface{}
 	compiledRegexes   map[string]*regexp.Regexp
+	continuation      *regexp.Regexp
+	cursor            *cursor
+	lines             *lineIndex
 	log               *Log
+	tracer            *tracer
+	failure           *failure
+	deferred          *[]deferredCall
+	depth             int
+	maxDepth          int
+	memo              map[memoKey]*memoEntry
+	seeds             map[memoKey]*memoEntry
+	paths             *[]string
+	keepIgnored       bool
+	runePositions     bool
+	tabWidth          int
+	stream            *stream
+	tokens            *trail[Token]
+	completed         *trail[*Part]
+	recovered         *trail[*ParseError]
+	warnings          *trail[Diagnostic]
+	ambiguity         bool
+	prefix            bool
+	rootName          string
+	coverage          *trail[coverMark]
+	stats             *Stats
+	ctx               context.Context
+	attempts          *int
+	maxAttempts       int
+	partAttempts      map[string]int
+	skip              *regexp.Regexp
+	debug             func(DebugStep, ParserState) bool
+	timed             bool
+	started           time.Time
+	// noSkip marks the copies of the parser within a NoSkip part, which don't skip text
+	noSkip bool
+	// lookahead marks the copies of the parser trying a lookahead, which queue handler calls to be discarded with the
+	// parts found and don't record failures
+	lookahead bool
+	// growing marks the copies of the parser growing a left-recursive part, which queue handler calls until the
+	// attempt that grows the part farthest is known, so the attempts that are thrown away never reach the model
+	growing bool
+	// calling holds the callback the parser is running, if any, and noRecover lets a panic in it crash the parse
+	calling   *callback
+	noRecover bool
+}
+
+// cursor provides the 1-based column of the parser's current position, along with the runes before it when tracking
+// rune positions, which backtracking restores along with the position
+type cursor struct {
+	column int
+	runes  int
+}
+
+// state provides a snapshot of the parser that can be restored when backtracking
+type state struct {
+	pos       int
+	column    int
+	runes     int
+	deferred  int
+	streamed  int
+	tokens    int
+	completed int
+	recovered int
+	warnings  int
+	covered   int
+}
+
+// deferredCall provides the Handler call for a part that's queued until the parse is committed
+type deferredCall struct {
+	partDefinition PartDefinition
+	part           *Part
+	line           int
+}
+
+// saveState returns a snapshot of the parser's position, column, queued handler calls, and trails
+func saveState(parser Parser) state {
+	return state{pos: *parser.currentPosPointer, column: parser.cursor.column, runes: parser.cursor.runes, deferred: len(*parser.deferred), streamed: streamLength(parser), tokens: parser.tokens.length(), completed: parser.completed.length(), recovered: parser.recovered.length(), warnings: parser.warnings.length(), covered: parser.coverage.length()}
+}
+
+// restoreState rewinds the parser to the snapshot, dropping any handler calls queued since it was taken
+func restoreState(snapshot state, parser Parser) {
+	*parser.currentPosPointer = snapshot.pos
+	parser.cursor.column = snapshot.column
+	parser.cursor.runes = snapshot.runes
+	*parser.deferred = (*parser.deferred)[:snapshot.deferred]
+	truncateStream(snapshot.streamed, parser)
+	parser.tokens.truncate(snapshot.tokens)
+	parser.completed.truncate(snapshot.completed)
+	parser.recovered.truncate(snapshot.recovered)
+	parser.warnings.truncate(snapshot.warnings)
+	parser.coverage.truncate(snapshot.covered)
 }
 
 // Parse provides the entry point for using the dialect library
 func Parse(dialectable Dialectable, input string) (string, error, string) {
-	parser := Parser{model: dialectable.NewModel(), dialect: dialectable.NewDialect(), compiledRegexes: make(map[string]*regexp.Regexp)}
+	compiled, err := Compile(dialectable)
+	if err != nil {
+		return "", err, ""
+	}
+	return compiled.Parse(input)
+}
+
+// ParseContext parses the input like Parse, stopping with a ParseError wrapping the context's error once the context
+// is done
+func ParseContext(ctx context.Context, dialectable Dialectable, input string) (string, error, string) {
+	compiled, err := Compile(dialectable)
+	if err != nil {
+		return "", err, ""
+	}
+	return compiled.ParseContext(ctx, input, Options{})
+}
+
+// ParseToTree parses the input like Parse, but returns the root Part of the parse tree instead of generating output
+func ParseToTree(dialectable Dialectable, input string) (*Part, error, string) {
+	compiled, err := Compile(dialectable)
+	if err != nil {
+		return nil, err, ""
+	}
+	return compiled.ParseToTree(input)
+}
+
+// ParsePrefix parses the input like Parse, but lets the root part match a prefix of the input, returning the number of
+// bytes of the input it consumed
+func ParsePrefix(dialectable Dialectable, input string) (output string, consumed int, err error, log string) {
+	compiled, err := Compile(dialectable)
+	if err != nil {
+		return "", 0, err, ""
+	}
+	return compiled.ParsePrefix(input, Options{})
+}
+
+// ParsePart parses the input like ParseToTree, but with the named part as the root part, returning a DialectError if
+// the dialect doesn't define the part
+func ParsePart(dialectable Dialectable, partName string, input string) (*Part, error, string) {
+	compiled, err := Compile(dialectable)
+	if err != nil {
+		return nil, err, ""
+	}
+	return compiled.ParsePart(partName, input, Options{})
+}
+
+// contextCheckInterval provides the number of attempts to find parts between checks of whether the parse was canceled
+const contextCheckInterval = 1024
+
+// newParser returns a Parser for the input that uses the compiled dialect, a fresh model, and the options, stopping
+// once the context is done
+func newParser(ctx context.Context, compiled *CompiledDialect, input string, options Options) Parser {
+	parser := Parser{model: compiled.dialectable.NewModel(), dialect: compiled.dialect, compiledRegexes: compiled.compiledRegexes, continuation: compiled.continuation, skip: compiled.skip, debug: options.Debug, maxDepth: options.maxDepth(), keepIgnored: options.KeepIgnored, runePositions: options.RunePositions, tabWidth: options.TabWidth, ambiguity: options.ReportAmbiguity}
 	currentPos := 0
 	parser.currentPosPointer = &currentPos
+	parser.status = new(Status)
+	parser.rootName = compiled.dialect.RootName
 	parser.input = input
-	parser.log = &Log{buffer: new(bytes.Buffer), indent: "| | | | ", indentLevel: 0, currentLine: 1}
-	// WHEEEEEEEE!!!! (enjoy the ride as you descend into the rabbit hole)
-	parts := findOne(parser.dialect.RootName, parser, nil)
+	parser.cursor = &cursor{column: 1}
+	parser.lines = &lineIndex{input: input}
+	parser.log = &Log{unit: options.TraceIndent, maxDepth: options.TraceMaxDepth, maxSize: options.maxTraceSize()}
+	if parser.log.unit == "" {
+		parser.log.unit = DefaultTraceIndent
+	}
+	parser.tracer = &tracer{filter: options.TraceFilter}
+	if options.Timing {
+		parser.timed, parser.started = true, time.Now()
+	}
+	switch {
+	case options.NoTrace:
+	case options.TraceWriter != nil:
+		parser.log.writer = options.TraceWriter
+	default:
+		parser.log.buffer = new(bytes.Buffer)
+		parser.log.writer = parser.log.buffer
+	}
+	switch {
+	case parser.log.writer == nil:
+	case options.StableTrace:
+		parser.log.write(stableHeader())
+		parser.tracer.consumers = append(parser.tracer.consumers, parser.log.stableEvent)
+	default:
+		parser.tracer.consumers = append(parser.tracer.consumers, parser.log.event)
+	}
+	if options.TraceFunc != nil {
+		parser.tracer.consumers = append(parser.tracer.consumers, options.TraceFunc)
+	}
+	parser.failure = &failure{}
+	parser.deferred = &[]deferredCall{}
+	parser.paths = &[]string{}
+	parser.calling, parser.noRecover = &callback{}, options.NoRecover
+	if len(options.OnPart) > 0 {
+		parser.stream = &stream{callbacks: options.OnPart}
+	}
+	parser.seeds = make(map[memoKey]*memoEntry)
+	if options.Memoize {
+		parser.memo = make(map[memoKey]*memoEntry)
+	}
+	if options.Coverage != nil {
+		parser.coverage = &trail[coverMark]{end: func(mark coverMark) int { return mark.end }}
+	}
+	if options.Stats != nil {
+		parser.stats = &Stats{}
+	}
+	if compiled.recovers {
+		parser.recovered = &trail[*ParseError]{end: func(parseError *ParseError) int { return parseError.Offset }}
+	}
+	if options.Diagnostics != nil {
+		parser.warnings = &trail[Diagnostic]{end: func(warning Diagnostic) int { return warning.Offset + warning.Length }}
+	}
+	if options.Partial != nil {
+		parser.completed = &trail[*Part]{end: func(part *Part) int { return part.EndPos }}
+	}
+	// contexts that can't be canceled needn't be checked
+	if ctx.Done() != nil {
+		parser.ctx = ctx
+		parser.attempts = new(int)
+	}
+	if options.MaxAttempts > 0 {
+		parser.maxAttempts = options.MaxAttempts
+		parser.partAttempts = make(map[string]int)
+		parser.attempts = new(int)
+	}
+	return parser
+}
+
+// parseRoot finds the root part of the dialect like findRoot, returning the tree along with a RecoveredError if the
+// parse recovered from syntax errors, or a ParseError if a callback panicked, unless the parse doesn't recover
+func parseRoot(parser Parser) (root *Part, err error) {
+	if !parser.noRecover {
+		defer recoverCallback(parser, &root, &err)
+	}
+	root, err = findRoot(parser)
+	parser.log.finish()
+	return root, recoveredError(err, parser)
+}
+
+// findRoot finds the root part of the dialect, returning an error if it can't be found
+func findRoot(parser Parser) (*Part, error) {
+	parts := findOne(parser.rootName, parser, nil)
+	// skip any text matching the SkipPattern and any comments after the root part, unless it's a NoSkip part or the
+	// text after it belongs to a prefix parse's caller
+	if len(parts) > 0 && skipping(parser) && !parser.dialect.PartDefinitions[parser.rootName].NoSkip && !parser.prefix {
+		parts[0].Constituents = append(parts[0].Constituents, skipText(parser, parts[0])...)
+	}
+	// an error that aborted the parse takes precedence over everything else
+	if parser.failure.abort != nil {
+		return nil, parser.failure.abort
+	}
+	// a semantic error from a handler explains the failure better than the grammar can
+	if parser.failure.semantic != nil && (len(parts) < 1 || (!allowsTrailing(parser) && *parser.currentPosPointer < len(parser.input))) {
+		failSemantically(parser)
+		return nil, parser.failure.semantic
+	}
 	if len(parts) < 1 {
-		return "", errors.New("dialects error: Parse() function of dialect unable to find root part (" + parser.dialect.RootName + ") of " + parser.dialect.Title), ""
+		// report the farthest failure, falling back to the root part if nothing was attempted
+		if len(parser.failure.partNames) < 1 {
+			return nil, newParseError(parser, 0, 1, parser.rootName, "unable to find root part ("+parser.rootName+") of "+parser.dialect.Title)
+		}
+		return nil, expectedError(parser)
 	}
-	output, err := dialectable.GenerateOutput(parser.model)
-	return output, err, parser.log.buffer.String() + "\n"
+	// fail if the root part didn't consume all of the input
+	if !allowsTrailing(parser) && *parser.currentPosPointer < len(parser.input) {
+		// a failure at or beyond the unconsumed input explains why the root part stopped there
+		if len(parser.failure.partNames) > 0 && parser.failure.offset >= *parser.currentPosPointer {
+			return nil, expectedError(parser)
+		}
+		return nil, newParseError(parser, *parser.currentPosPointer, currentLine(parser), parser.rootName, "unexpected input")
+	}
+	// now that the parse is committed, run any deferred handlers in the order their parts were found
+	for _, call := range *parser.deferred {
+		if call.partDefinition.HandlerE != nil {
+			enterCallback("HandlerE", call.part.Name, call.part.StartPos, call.line, parser)
+			err := call.partDefinition.HandlerE(call.part, parser.model)
+			leaveCallback(parser)
+			if err != nil && !warned(err, call.part, parser) {
+				parseError := newParseError(parser, call.part.StartPos, call.line, call.part.Name, err.Error())
+				parseError.Err = err
+				parseError.length = call.part.EndPos - call.part.StartPos
+				failSemantically(parser)
+				return nil, parseError
+			}
+			continue
+		}
+		enterCallback("Handler", call.part.Name, call.part.StartPos, call.line, parser)
+		ok := call.partDefinition.Handler(call.part, parser.model)
+		leaveCallback(parser)
+		if !ok {
+			parseError := newParseError(parser, call.part.StartPos, call.line, call.part.Name, "handler rejected "+call.part.Name)
+			parseError.length = call.part.EndPos - call.part.StartPos
+			failSemantically(parser)
+			return nil, parseError
+		}
+	}
+	return parts[0], nil
+}
+
+// allowsTrailing reports whether the root part may leave unconsumed input after it
+func allowsTrailing(parser Parser) bool {
+	return parser.dialect.AllowTrailing || parser.prefix
 }
 
 // findOne returns an array of Parts, returning empty array if none found
-func findOne(partName string, parser Parser, path []string) (parts []*Part) {
-	// exit early if position pointer is already at the end of the string
-	if len(parser.input) == (*parser.currentPosPointer + 1) {
+func findOne(partName string, parser Parser, parent *Part) (parts []*Part) {
+	// exit early if position pointer is already beyond the end of the string or the parse was aborted
+	if *parser.currentPosPointer > len(parser.input) || parser.failure.abort != nil {
+		return nil
+	}
+	// count attempts when the parse can be canceled or has a budget, aborting once it's canceled or over budget
+	if parser.attempts != nil && !countAttempts(partName, parser) {
+		return nil
+	}
+	// track nesting in this copy of the parser, aborting once it's too deep
+	parser.depth++
+	if parser.maxDepth > 0 && parser.depth > parser.maxDepth {
+		abortParse(newParseError(parser, *parser.currentPosPointer, currentLine(parser), partName, "maximum nesting depth ("+strconv.Itoa(parser.maxDepth)+") exceeded"), parser)
 		return nil
 	}
+	if parser.stats == nil && parser.debug == nil {
+		return checkVersion(partName, attemptRecovering(partName, parser, parent), parser)
+	}
+	start := *parser.currentPosPointer
+	if !debugStep(DebugStep{Kind: DebugEnter, PartName: partName, StartPos: start}, parser, parent) {
+		return nil
+	}
+	// only read the clock when timing, as it's slow next to most attempts
+	var began time.Time
+	if parser.timed {
+		began = time.Now()
+	}
+	parts = checkVersion(partName, attemptRecovering(partName, parser, parent), parser)
+	if parser.stats != nil {
+		countAttempt(partName, parts, start, began, parser)
+	}
+	// let the debugger see the outcome, unless the parse has been aborted
+	if parser.failure.abort == nil {
+		kind := DebugFound
+		if parts == nil {
+			kind = DebugBacktrack
+		}
+		debugStep(DebugStep{Kind: kind, PartName: partName, StartPos: start}, parser, parent)
+	}
+	return parts
+}
+
+// attemptPart returns an array of the parts found at the current position, reusing earlier attempts where it can
+func attemptPart(partName string, parser Parser, parent *Part) (parts []*Part) {
+	// grow a left-recursive part from a seed rather than recursing forever
+	if parser.dialect.PartDefinitions[partName].AllowLeftRecursion {
+		return growPart(partName, parser, parent)
+	}
+	// reuse the result of an earlier attempt at this position when memoizing, though not within lookaheads, whose
+	// handler calls are discarded
+	if parser.memo != nil && !parser.lookahead {
+		if parts, ok := recallPart(partName, parser, parent); ok {
+			return parts
+		}
+		start := saveState(parser)
+		parts = findPart(partName, parser, parent)
+		memoizePart(partName, parts, start, parser)
+		return parts
+	}
+	return findPart(partName, parser, parent)
+}
+
+// countAttempts counts the attempt to find the part, returning false after aborting the parse if it's over its budget
+// of attempts or its context is done, which is only checked every so often, as checking on every attempt is slow
+func countAttempts(partName string, parser Parser) bool {
+	*parser.attempts++
+	if parser.maxAttempts > 0 {
+		parser.partAttempts[partName]++
+		if *parser.attempts > parser.maxAttempts {
+			abortParse(newLimitError(partName, parser), parser)
+			return false
+		}
+	}
+	if parser.ctx != nil && *parser.attempts%contextCheckInterval == 0 && parser.ctx.Err() != nil {
+		parseError := newParseError(parser, *parser.currentPosPointer, currentLine(parser), partName, "parse stopped: "+parser.ctx.Err().Error())
+		parseError.Err = parser.ctx.Err()
+		abortParse(parseError, parser)
+		return false
+	}
+	return true
+}
+
+// findPart returns an array containing the part matched at the current position, preceded by the text skipped before it
+// when keeping Ignored parts, returning empty array if not found
+func findPart(partName string, parser Parser, parent *Part) (parts []*Part) {
+	// skip text matching the SkipPattern and comments before every part but the root, unless within a NoSkip part
+	if !skipping(parser) || parser.noSkip || parent == nil {
+		parts = coverPart(partName, matchPart(partName, parser, parent), parser)
+		recordCompleted(parts, parser)
+		return parts
+	}
+	start := saveState(parser)
+	skipped := skipText(parser, parent)
+	parts = coverPart(partName, matchPart(partName, parser, parent), parser)
+	if len(parts) < 1 {
+		// give back the skipped text
+		restoreState(start, parser)
+		return nil
+	}
+	if skipped != nil {
+		parts = append(skipped, parts...)
+	}
+	recordCompleted(parts, parser)
+	return parts
+}
+
+// skipping reports whether the dialect has text to skip, matching its SkipPattern or comments
+func skipping(parser Parser) bool {
+	return parser.skip != nil || parser.dialect.LineComment != "" || parser.dialect.BlockComment[0] != ""
+}
+
+// skipText advances the parser past any text matching the SkipPattern and any comments at the current position,
+// returning them as Ignored parts when keeping Ignored parts
+func skipText(parser Parser, parent *Part) (skipped []*Part) {
+	for {
+		name, length := skippable(parser)
+		if length == 0 {
+			return skipped
+		}
+		match := parser.input[(*parser.currentPosPointer) : (*parser.currentPosPointer)+length]
+		recordToken(name, true, match, parser)
+		if !parser.keepIgnored {
+			advance(match, parser)
+			continue
+		}
+		part := &Part{
+			Name:      name,
+			Ignore:    true,
+			Parent:    parent,
+			Path:      childPath(parent, parser),
+			Value:     match,
+			Found:     true,
+			Source:    match,
+			StartPos:  *parser.currentPosPointer,
+			StartLine: currentLine(parser),
+			StartCol:  parser.cursor.column,
+			StartRune: parser.cursor.runes,
+		}
+		advance(match, parser)
+		part.EndPos = (*parser.currentPosPointer)
+		part.EndLine, part.EndCol = currentLine(parser), parser.cursor.column
+		part.EndRune = parser.cursor.runes
+		skipped = append(skipped, part)
+	}
+}
+
+// skippable returns the name of the part and the length of the text to skip at the current position, which is either
+// text matching the SkipPattern or a comment, aborting the parse at a block comment that's never ended
+func skippable(parser Parser) (name string, length int) {
+	rest := parser.input[(*parser.currentPosPointer):]
+	if parser.skip != nil {
+		if loc := parser.skip.FindStringIndex(rest); loc != nil && loc[1] > 0 {
+			return SkippedPartName, loc[1]
+		}
+	}
+	if lineComment := parser.dialect.LineComment; lineComment != "" && strings.HasPrefix(rest, lineComment) {
+		// the line ending, whether LF or CRLF, is left for the SkipPattern
+		if end := strings.IndexByte(rest, '\n'); end >= 0 {
+			return CommentPartName, len(strings.TrimSuffix(rest[:end], "\r"))
+		}
+		return CommentPartName, len(rest)
+	}
+	if blockComment := parser.dialect.BlockComment; blockComment[0] != "" && strings.HasPrefix(rest, blockComment[0]) {
+		end := strings.Index(rest[len(blockComment[0]):], blockComment[1])
+		if end < 0 {
+			abortParse(newParseError(parser, *parser.currentPosPointer, currentLine(parser), CommentPartName, "unterminated comment starting on line "+strconv.Itoa(currentLine(parser))), parser)
+			return "", 0
+		}
+		return CommentPartName, len(blockComment[0]) + end + len(blockComment[1])
+	}
+	return "", 0
+}
+
+// matchPart returns an array containing the part matched at the current position, returning empty array if not found
+func matchPart(partName string, parser Parser, parent *Part) (parts []*Part) {
 	partDefinition := parser.dialect.PartDefinitions[partName]
+	// stop skipping text within a NoSkip part
+	if partDefinition.NoSkip {
+		parser.noSkip = true
+	}
 	part := &Part{
 		Name:   partName,
 		Ignore: partDefinition.Ignore,
+		Parent: parent,
+		Found:  true,
 	}
+	// set path to the names of the ancestor parts, including Ignored ones
+	part.Path = childPath(parent, parser)
 	// save current position and pointer reference
 	currentPosPointer := parser.currentPosPointer
 	// set part start to current position
 	part.StartPos = *currentPosPointer
+	part.StartLine, part.StartCol = currentLine(parser), parser.cursor.column
+	part.StartRune = parser.cursor.runes
+	// save current state in case a Handler rejects the part
+	start := saveState(parser)
 	// handle Consituents
 	if len(partDefinition.Constituents) > 0 {
+		// snapshot failures so described parts can summarize their constituents' failures
+		mark := markFailure(parser)
 		// find Constituents
-		part.Constituents = findConstituents(partDefinition.Constituents, parser, path)
-		// handle no Constituents
-		if len(part.Constituents) < 1 {
+		constituents, found := findConstituents(partDefinition.Constituents, parser, part)
+		// handle no constituent sequence found
+		if !found {
+			if partDefinition.Description != "" {
+				summarizeFailure(partName, mark, parser)
+			}
 			// return early with nil
 			return nil
 		}
-		// otherwise call Handler if present
-		if partDefinition.Handler != nil {
-			if ok := partDefinition.Handler(part, parser.model); !ok {
-				// if something went wrong, call handler
-				return nil
-			}
-		}
+		part.Constituents = constituents
 		// set end position of part to current position
 		part.EndPos = *currentPosPointer
+		part.EndLine, part.EndCol = currentLine(parser), parser.cursor.column
+		part.EndRune = parser.cursor.runes
+		// set value to the text the part spans, including any Ignored constituents (slicing rather than copying)
+		part.Value = parser.input[part.StartPos:part.EndPos]
+		part.Source = part.Value
+		// otherwise call Handler if present
+		if !callHandler(partDefinition, part, parser, start) {
+			return nil
+		}
+		// queue the part for its callback
+		streamParts([]*Part{part}, parser)
 		// return part slice
 		return []*Part{part}
 	}
+	// otherwise handle an expression
+	if partDefinition.expression != nil {
+		mark := markFailure(parser)
+		parts := findExpression(partDefinition, part, parser, start)
+		if parts == nil && partDefinition.Description != "" {
+			summarizeFailure(partName, mark, parser)
+		}
+		return parts
+	}
 	// otherwise handle regex
 	if partDefinition.Regex != "" {
-		compiledRegex, saved := parser.compiledRegexes[partName]
-		// compile Regex if not already done
-		if !saved {
-			compiledRegex = regexp.MustCompile(partDefinition.Regex)
-			// save for future use
-			parser.compiledRegexes[partName] = compiledRegex
-		}
-		// find part by Regex
-		matches := compiledRegex.FindStringSubmatch(parser.input[(*currentPosPointer):])
+		// use the Regex compiled along with the dialect
+		compiledRegex := parser.compiledRegexes[partName]
+		// find part by Regex, getting the match's indices so no strings are built for matches that are only skipped
+		// over, and relying on the anchor to stop a failed match at the current position rather than scanning the tail
+		loc := compiledRegex.FindStringSubmatchIndex(parser.input[(*currentPosPointer):])
 		// return nil if no matches
-		if len(matches) < 1 {
+		if loc == nil {
+			recordFailure(partName, parser)
 			return nil
 		}
+		match := parser.input[(*currentPosPointer)+loc[0] : (*currentPosPointer)+loc[1]]
+		// only build the submatches when a callback or capture group needs them
+		var matches []string
+		if partDefinition.ValidateMatch != nil || partDefinition.ValidateMatchCtx != nil || partDefinition.ValidateMatchSeverity != nil || partDefinition.FormatMatch != nil || len(loc) > 2 {
+			matches = submatches(parser.input[(*currentPosPointer):], loc)
+		}
 		// check for validator
-		if partDefinition.ValidateMatch != nil {
-			// call validator if present
-			isValid, errMsg := partDefinition.ValidateMatch(matches)
-			// check if invalid
-			if !isValid {
-				// log error
-				if errMsg != "" {
-					// log custom error message
-					parser.log.buffer.WriteString(parser.log.indent[:parser.log.indentLevel] + "invalid " + partName + " starting on line " + strconv.Itoa(parser.log.currentLine) + ": " + errMsg + "\n")
-				} else {
-					// log generic err message
-					parser.log.buffer.WriteString(parser.log.indent[:parser.log.indentLevel] + "invalid " + partName + " starting on line " + strconv.Itoa(parser.log.currentLine) + "\n")
-				}
-				// return nil
-				return nil
-			}
+		if !validateMatch(partName, partDefinition, matches, len(match), part, parser) {
+			return nil
+		}
+		// keep the submatches of capture groups, along with those of named groups by name
+		if len(loc) > 2 {
+			part.Matches = matches
+			part.Groups = namedGroups(compiledRegex, matches)
 		}
 		// optionally format match
 		if partDefinition.FormatMatch != nil {
+			enterCallback("FormatMatch", partName, part.StartPos, part.StartLine, parser)
 			part.Value = partDefinition.FormatMatch(matches)
+			leaveCallback(parser)
 		} else {
-			part.Value = matches[0]
-		}
-		// update current position to account for length of entire match
-		(*currentPosPointer) = (*currentPosPointer) + len(matches[0])
-		// update currentLine to account for \n's in the match
-		parser.log.currentLine = parser.log.currentLine + strings.Count(matches[0], "\n")
-		// update EndPos
-		part.EndPos = (*currentPosPointer)
-		// return part
-		return []*Part{part}
+			part.Value = match
+		}
+		// consume the match and call Handler if present
+		return consumeMatch(match, partDefinition, part, parser, start)
+	}
+	// otherwise handle literal
+	if partDefinition.Literal != "" {
+		// find part by comparing the text at the current position, with no regex semantics
+		match, ok := matchText(partDefinition.Literal, caseInsensitive(partDefinition, parser.dialect), parser)
+		if !ok {
+			recordFailure(partName, parser)
+			recordExpectedText(partDefinition.Literal, parser)
+			return nil
+		}
+		// keep the case of the input
+		part.Value = match
+		// consume the literal and call Handler if present
+		return consumeMatch(match, partDefinition, part, parser, start)
+	}
+	// otherwise handle keyword
+	if partDefinition.Keyword != "" {
+		match, ok := matchKeyword(partDefinition, parser)
+		if !ok {
+			recordFailure(partName, parser)
+			recordExpectedText(partDefinition.Keyword, parser)
+			return nil
+		}
+		// keep the case of the input
+		part.Value = match
+		// consume the keyword and call Handler if present
+		return consumeMatch(match, partDefinition, part, parser, start)
+	}
+	// otherwise handle match function
+	if partDefinition.Match != nil {
+		enterCallback("Match", partName, part.StartPos, part.StartLine, parser)
+		length, value, ok := partDefinition.Match(parser.input, *currentPosPointer)
+		leaveCallback(parser)
+		if !ok {
+			recordFailure(partName, parser)
+			return nil
+		}
+		if length < 0 || length > len(parser.input)-(*currentPosPointer) {
+			abortParse(newParseError(parser, *currentPosPointer, currentLine(parser), partName, "match function of "+partName+" returned an invalid length ("+strconv.Itoa(length)+")"), parser)
+			return nil
+		}
+		// treat the value like a regex match without subexpressions
+		matches := []string{value}
+		if !validateMatch(partName, partDefinition, matches, length, part, parser) {
+			return nil
+		}
+		if partDefinition.FormatMatch != nil {
+			enterCallback("FormatMatch", partName, part.StartPos, part.StartLine, parser)
+			part.Value = partDefinition.FormatMatch(matches)
+			leaveCallback(parser)
+		} else {
+			part.Value = value
+		}
+		// consume the match and call Handler if present
+		return consumeMatch(parser.input[(*currentPosPointer):(*currentPosPointer)+length], partDefinition, part, parser, start)
+	}
+	// otherwise handle end of input
+	if partDefinition.EOF {
+		if *currentPosPointer < len(parser.input) {
+			recordFailureMessage(partName, "unexpected trailing input "+trailingText(parser.input, *currentPosPointer), parser)
+			return nil
+		}
+		part.Ignore = true
+		// consume nothing and call Handler if present
+		return consumeMatch("", partDefinition, part, parser, start)
 	}
 	// handle invalid case where definition has neither parts nor Regex
 	return nil
 }
 
-func findMany(partName string, parser Parser, path []string) (manyParts []*Part) {
-	findMore := true
+// validateMatch returns whether the part's validator, if any, accepts the match, preferring the validator with
+// severity and then the one with context, and records the failure if not, or the warning covering the length of input
+// matched if the validator warned
+func validateMatch(partName string, partDefinition PartDefinition, matches []string, length int, part *Part, parser Parser) bool {
+	if partDefinition.ValidateMatch == nil && partDefinition.ValidateMatchCtx == nil && partDefinition.ValidateMatchSeverity == nil {
+		return true
+	}
+	var isValid bool
+	var errMsg string
+	switch {
+	case partDefinition.ValidateMatchSeverity != nil:
+		enterCallback("ValidateMatchSeverity", partName, part.StartPos, part.StartLine, parser)
+		severity, message := partDefinition.ValidateMatchSeverity(matches, part.StartPos, part.StartLine, parser.model)
+		leaveCallback(parser)
+		// a warning accepts the match
+		if message != "" && severity == SeverityWarning {
+			if tracing(partName, parser) {
+				trace(TraceEvent{Kind: TraceWarning, PartName: partName, Message: message, StartPos: part.StartPos}, parser)
+			}
+			recordWarning(message, partName, part.StartPos, length, part.StartLine, parser)
+		}
+		isValid, errMsg = message == "" || severity == SeverityWarning, message
+	case partDefinition.ValidateMatchCtx != nil:
+		enterCallback("ValidateMatchCtx", partName, part.StartPos, part.StartLine, parser)
+		isValid, errMsg = partDefinition.ValidateMatchCtx(matches, part.StartPos, part.StartLine, parser.model)
+		leaveCallback(parser)
+	default:
+		enterCallback("ValidateMatch", partName, part.StartPos, part.StartLine, parser)
+		isValid, errMsg = partDefinition.ValidateMatch(matches)
+		leaveCallback(parser)
+	}
+	if !isValid {
+		// trace error
+		if tracing(partName, parser) {
+			trace(TraceEvent{Kind: TraceInvalid, PartName: partName, Message: errMsg, StartPos: part.StartPos}, parser)
+		}
+		recordInvalid(partName, errMsg, parser)
+	}
+	return isValid
+}
+
+// matchKeyword returns the text of the keyword at the current position, with ok reporting whether it's there and not
+// followed by a character that would continue it into a longer word
+func matchKeyword(partDefinition PartDefinition, parser Parser) (match string, ok bool) {
+	ignoreCase := caseInsensitive(partDefinition, parser.dialect) || (parser.dialect.CaseInsensitiveKeywords && !partDefinition.CaseSensitive)
+	match, ok = matchText(partDefinition.Keyword, ignoreCase, parser)
+	if !ok || parser.continuation.MatchString(parser.input[(*parser.currentPosPointer)+len(match):]) {
+		return "", false
+	}
+	return match, true
+}
+
+// matchText returns the input at the current position when it's the text, ignoring case if asked, with ok reporting
+// whether it is
+func matchText(text string, ignoreCase bool, parser Parser) (match string, ok bool) {
+	rest := parser.input[(*parser.currentPosPointer):]
+	if len(rest) < len(text) {
+		return "", false
+	}
+	match = rest[:len(text)]
+	if match != text && !(ignoreCase && strings.EqualFold(match, text)) {
+		return "", false
+	}
+	return match, true
+}
+
+// caseInsensitive reports whether the part matches in any case, either by itself or by default for the dialect
+func caseInsensitive(partDefinition PartDefinition, dialect *Dialect) bool {
+	return partDefinition.CaseInsensitive || (dialect.CaseInsensitive && !partDefinition.CaseSensitive)
+}
+
+// consumeMatch advances the parser past the text matched by the leaf part, setting the part's end, and
+// returns the part unless its Handler rejects it
+func consumeMatch(match string, partDefinition PartDefinition, part *Part, parser Parser, start state) []*Part {
+	if !debugStep(DebugStep{Kind: DebugMatch, PartName: part.Name, Value: match, StartPos: part.StartPos}, parser, part.Parent) {
+		return nil
+	}
+	recordToken(part.Name, part.Ignore, match, parser)
+	part.Source = match
+	advance(match, parser)
+	// update EndPos
+	part.EndPos = (*parser.currentPosPointer)
+	part.EndLine, part.EndCol = currentLine(parser), parser.cursor.column
+	part.EndRune = parser.cursor.runes
+	// call Handler if present
+	if !callHandler(partDefinition, part, parser, start) {
+		return nil
+	}
+	// queue the part for its callback
+	streamParts([]*Part{part}, parser)
+	// return part
+	return []*Part{part}
+}
+
+// advance moves the parser's position, column, and rune count past the matched text, where the line follows from the
+// position
+func advance(match string, parser Parser) {
+	// update current position to account for length of entire match
+	(*parser.currentPosPointer) = (*parser.currentPosPointer) + len(match)
+	// update the column to count the runes after the last \n, or all of them if there isn't one
+	if lastNewline := strings.LastIndexByte(match, '\n'); lastNewline >= 0 {
+		parser.cursor.column = utf8.RuneCountInString(match[lastNewline+1:]) + 1
+	} else {
+		parser.cursor.column = parser.cursor.column + utf8.RuneCountInString(match)
+	}
+	// a match ending within a CRLF line ending ends in the column of its \r, as one ending before a lone \n would,
+	// which only a match including the \r moves into, as an empty match there doesn't move at all
+	if strings.HasSuffix(match, "\r") && splitsCRLF(parser.input, *parser.currentPosPointer) {
+		parser.cursor.column--
+	}
+	// update the rune count to include the runes of the match when tracking rune positions
+	if parser.runePositions {
+		parser.cursor.runes = parser.cursor.runes + utf8.RuneCountInString(match)
+	}
+}
+
+// childPath returns the Path for a child of the parent, sharing storage with the paths of other parts where possible so
+// deeply nested parts don't each copy all of their ancestors' names
+func childPath(parent *Part, parser Parser) []string {
+	if parent == nil {
+		return nil
+	}
+	paths := *parser.paths
+	depth := len(parent.Path)
+	// names written to the shared paths are never overwritten, so the parent's path can be extended in place if it
+	// lives there and the next name is either already the parent's name or not yet written
+	if depth == 0 || (len(paths) >= depth && &paths[0] == &parent.Path[0]) {
+		switch {
+		case len(paths) > depth && paths[depth] == parent.Name:
+			return paths[: depth+1 : depth+1]
+		case len(paths) == depth:
+			paths = append(paths, parent.Name)
+			*parser.paths = paths
+			return paths[: depth+1 : depth+1]
+		}
+	}
+	// otherwise start new shared paths from a copy of the parent's path
+	paths = make([]string, depth+1, 2*(depth+1))
+	copy(paths, parent.Path)
+	paths[depth] = parent.Name
+	*parser.paths = paths
+	return paths[: depth+1 : depth+1]
+}
+
+// callHandler calls the part's Handler if present, restoring the state to the start of the part if the Handler
+// rejects it, or queues the call if the dialect defers handlers
+func callHandler(partDefinition PartDefinition, part *Part, parser Parser, start state) (ok bool) {
+	if partDefinition.HandlerE == nil && partDefinition.Handler == nil {
+		return true
+	}
+	// queue the call until the parse is committed, until a lookahead discards it, or until the growth of a
+	// left-recursive part settles
+	if parser.dialect.DeferHandlers || parser.lookahead || parser.growing {
+		*parser.deferred = append(*parser.deferred, deferredCall{partDefinition: partDefinition, part: part, line: parser.lines.line(start.pos)})
+		return true
+	}
+	if !runHandler(partDefinition, part, parser.lines.line(start.pos), parser) {
+		// if something went wrong, give back the input the part consumed
+		restoreState(start, parser)
+		return false
+	}
+	return true
+}
+
+// runHandler calls the part's HandlerE or Handler for the part found on the line, reporting whether it accepted the
+// part, and recording the error of a HandlerE that rejects it
+func runHandler(partDefinition PartDefinition, part *Part, line int, parser Parser) (ok bool) {
+	if partDefinition.HandlerE != nil {
+		enterCallback("HandlerE", part.Name, part.StartPos, line, parser)
+		err := partDefinition.HandlerE(part, parser.model)
+		leaveCallback(parser)
+		if err != nil && !warned(err, part, parser) {
+			// record the semantic error so backtracking doesn't mask it
+			recordSemanticError(err, part, parser, line)
+			return false
+		}
+		return true
+	}
+	enterCallback("Handler", part.Name, part.StartPos, line, parser)
+	ok = partDefinition.Handler(part, parser.model)
+	leaveCallback(parser)
+	return ok
+}
+
+// submatches returns the text of the match and its subexpressions from their indices, like FindStringSubmatch
+func submatches(input string, loc []int) []string {
+	matches := make([]string, len(loc)/2)
+	for i := range matches {
+		if loc[2*i] >= 0 {
+			matches[i] = input[loc[2*i]:loc[2*i+1]]
+		}
+	}
+	return matches
+}
+
+// namedGroups returns the submatches of the regex's named groups by name, or nil if it has none
+func namedGroups(compiledRegex *regexp.Regexp, matches []string) map[string]string {
+	var groups map[string]string
+	for i, name := range compiledRegex.SubexpNames() {
+		if name != "" {
+			if groups == nil {
+				groups = make(map[string]string)
+			}
+			groups[name] = matches[i]
+		}
+	}
+	return groups
+}
+
+// anchorRegex returns the part's regex wrapped so it only matches at the start of the text, unless the dialect opts
+// out, and flagged to match in any case if the part does
+func anchorRegex(partDefinition PartDefinition, dialect *Dialect) string {
+	regex := partDefinition.Regex
+	if caseInsensitive(partDefinition, dialect) {
+		regex = `(?i)` + regex
+	}
+	if dialect.UnanchoredRegexes {
+		return regex
+	}
+	return `\A(?:` + regex + `)`
+}
+
+// findBackreference returns a part with the name matching the text of the latest earlier part of the sequence with that
+// name, Ignored or not, so closing delimiters can repeat their opening ones, skipping text first like findPart and
+// calling the part's Handler like a leaf part
+func findBackreference(partName string, siblings []*Part, parser Parser, parent *Part) []*Part {
+	var opening *Part
+	for i := len(siblings) - 1; i >= 0 && opening == nil; i-- {
+		if siblings[i].Name == partName {
+			opening = siblings[i]
+		}
+	}
+	if opening == nil {
+		recordFailure(partName, parser)
+		return nil
+	}
+	start := saveState(parser)
+	var skipped []*Part
+	if skipping(parser) && !parser.noSkip {
+		skipped = skipText(parser, parent)
+	}
+	// compare the text of the input rather than Values, which FormatMatch may have changed
+	text := parser.input[opening.StartPos:opening.EndPos]
+	partDefinition := parser.dialect.PartDefinitions[partName]
+	match, ok := matchText(text, caseInsensitive(partDefinition, parser.dialect), parser)
+	if !ok {
+		rest := parser.input[*parser.currentPosPointer:]
+		found := "'" + foundText(rest, text) + "'"
+		switch {
+		case rest == "":
+			found = "the end of the input"
+		case found == "''":
+			found = "the end of the line"
+		}
+		recordFailureMessage(partName, "expected '"+text+"' to match opening '"+text+"' on line "+strconv.Itoa(opening.StartLine)+", found "+found, parser)
+		restoreState(start, parser)
+		return nil
+	}
+	part := &Part{
+		Name:      partName,
+		Ignore:    opening.Ignore,
+		Parent:    parent,
+		Path:      childPath(parent, parser),
+		Value:     match,
+		Found:     true,
+		StartPos:  *parser.currentPosPointer,
+		StartLine: currentLine(parser),
+		StartCol:  parser.cursor.column,
+		StartRune: parser.cursor.runes,
+	}
+	// consume the match and call Handler if present, giving back the skipped text if it rejects the part
+	parts := coverPart(partName, consumeMatch(match, partDefinition, part, parser, saveState(parser)), parser)
+	if parts == nil {
+		restoreState(start, parser)
+		return nil
+	}
+	parts = append(skipped, parts...)
+	recordCompleted(parts, parser)
+	return parts
+}
+
+// foundText returns the text at the start of the rest of the input in place of the expected text, as many runes long
+// as it but stopping at the end of the line
+func foundText(rest string, expected string) string {
+	length := utf8.RuneCountInString(expected)
+	for i, r := range rest {
+		if length == 0 || r == '\n' || r == '\r' {
+			return rest[:i]
+		}
+		length--
+	}
+	return rest
+}
+
+// lookahead reports whether the part matches at the current position without consuming anything, discarding the parts
+// found so they never reach Handlers, the tree, or OnPart callbacks
+func lookahead(partName string, parser Parser, parent *Part) bool {
+	start := saveState(parser)
+	parser.lookahead = true
+	// hold back any parts streamed until they're discarded
+	beginChoice(parser)
+	found := len(findOne(partName, parser, parent)) > 0
+	restoreState(start, parser)
+	endChoice(parser)
+	return found
+}
+
+// findMany returns the parts found by repeatedly finding the part until it no longer matches or stops advancing, with
+// found reporting whether any were, since parts passed to stream callbacks aren't returned
+func findMany(partName string, parser Parser, parent *Part) (manyParts []*Part, found bool) {
+	manyParts, count := findRepeated(partName, -1, parser, parent)
+	return manyParts, count > 0
+}
+
+// findRepeated returns the parts found by repeatedly finding the part like findMany, stopping once it has been found
+// max times unless max is -1, along with the number of times it was found
+func findRepeated(partName string, max int, parser Parser, parent *Part) (manyParts []*Part, count int) {
+	findMore := max != 0
 
 	for findMore {
-		parts := findOne(partName, parser, path)
+		// store state so matches that consume nothing can be detected and discarded
+		start := saveState(parser)
+		// each repetition is a choice point, as the repetition just ends if it fails
+		beginChoice(parser)
+		parts := findOne(partName, parser, parent)
 		if len(parts) > 0 {
+			// a match that didn't advance would match forever, so keep it only if it's the first and then stop
+			if *parser.currentPosPointer == start.pos {
+				if count == 0 {
+					manyParts = append(manyParts, parts...)
+					count++
+				} else {
+					restoreState(start, parser)
+				}
+				endChoice(parser)
+				break
+			}
 			manyParts = append(manyParts, parts...)
+			count++
+			endChoice(parser)
+			manyParts = dropStreamed(manyParts, parser)
+			// stop at the upper bound, leaving any further matches for what follows
+			findMore = count != max
 			continue
 		}
+		endChoice(parser)
 
 		findMore = false
 	}
 
-	return manyParts
+	return manyParts, count
 }
 
-func findConstituents(Constituents [][]string, parser Parser, path []string) (parts []*Part) {
-	// store temporary position in case sequence isn't found
-	tempPos := *parser.currentPosPointer
-	// store tempory current line
-	tempCurrentLine := parser.log.currentLine
+// findSeparated returns the parts found by repeatedly finding the part of the separated list with its separator between
+// each, leaving out the separators unless keeping Ignored parts, with found reporting whether any items were
+func findSeparated(list constituent, parser Parser, parent *Part) (manyParts []*Part, found bool) {
+	// the first item is a choice point like each later one, as an empty list just ends
+	beginChoice(parser)
+	manyParts = findOne(list.name, parser, parent)
+	endChoice(parser)
+	if len(manyParts) < 1 {
+		return nil, false
+	}
+	for {
+		// store state so a separator without an item after it can be given back
+		start := saveState(parser)
+		beginChoice(parser)
+		separatorParts := findOne(list.separator, parser, parent)
+		if len(separatorParts) < 1 {
+			endChoice(parser)
+			break
+		}
+		parts := findOne(list.name, parser, parent)
+		if len(parts) < 1 || *parser.currentPosPointer == start.pos {
+			// keep a trailing separator only if the list allows one, and then stop
+			if len(parts) > 0 || !list.trailing {
+				restoreState(start, parser)
+			} else if parser.keepIgnored {
+				manyParts = append(manyParts, separatorParts...)
+			}
+			endChoice(parser)
+			break
+		}
+		if parser.keepIgnored {
+			manyParts = append(manyParts, separatorParts...)
+		}
+		manyParts = append(manyParts, parts...)
+		endChoice(parser)
+		manyParts = dropStreamed(manyParts, parser)
+	}
+	return manyParts, true
+}
+
+// findConstituents returns the parts of the first constituent sequence found, with found reporting whether any sequence matched
+func findConstituents(Constituents [][]string, parser Parser, parent *Part) (parts []*Part, found bool) {
+	// store temporary state in case sequence isn't found
+	tempState := saveState(parser)
 	// cycle through constituent sequences
-	for _, Constituentseq := range Constituents {
+	for i, Constituentseq := range Constituents {
+		// every alternative but the last is a choice point, as the next alternative is tried if it fails
+		if i < len(Constituents)-1 {
+			beginChoice(parser)
+		}
 		// test each possible set of Constituents
-		parts := findConstituentseq(Constituentseq, parser, path)
-		// if parts found, return result
-		if len(parts) > 0 {
-			return parts
+		parts, found, cut := findConstituentseq(Constituentseq, parser, parent)
+		// if sequence found, return result (which may be empty if every part was optional or Ignored)
+		if found {
+			if i < len(Constituents)-1 {
+				endChoice(parser)
+			}
+			coverAlternative(parent.Name, i, parser)
+			// check the later alternatives for the same match when diagnosing ambiguity, though not within the
+			// attempts of another check or a lookahead
+			if parser.ambiguity && !parser.lookahead && tracing(parent.Name, parser) {
+				reportAmbiguity(Constituents, i, tempState, parser, parent)
+			}
+			return parts, true
+		}
+		// otherwise, reset state and try next sequence (unless the parse was aborted), ending the choice point only
+		// after the parts of the failed sequence are dropped
+		restoreState(tempState, parser)
+		countBacktrack(parent.Name, parser)
+		if i < len(Constituents)-1 {
+			endChoice(parser)
+		}
+		// a failure past a cut fails the part, so the error from within this alternative stands
+		if parser.failure.abort != nil || cut {
+			break
 		}
-		// otherwise, reset position and try next sequence
-		*parser.currentPosPointer = tempPos
-		parser.log.currentLine = tempCurrentLine
 	}
 	// no constituent set found, so return empty slice
-	return nil
+	return nil, false
+}
+
+// reportAmbiguity traces an event for each alternative after the matched one that also matches from the start state to
+// the current position, trying them as lookaheads so their handler calls and parts are discarded, without tracing them
+func reportAmbiguity(Constituents [][]string, matched int, start state, parser Parser, parent *Part) {
+	end := saveState(parser)
+	// rewind to the start without dropping the handler calls and parts queued by the match
+	start.deferred, start.streamed = end.deferred, end.streamed
+	parser.lookahead = true
+	consumers, abort := parser.tracer.consumers, parser.failure.abort
+	var ambiguous []int
+	parser.tracer.consumers = nil
+	beginChoice(parser)
+	for i := matched + 1; i < len(Constituents) && parser.failure.abort == nil; i++ {
+		restoreState(start, parser)
+		if _, found, _ := findConstituentseq(Constituents[i], parser, parent); found && *parser.currentPosPointer == end.pos {
+			ambiguous = append(ambiguous, i)
+		}
+	}
+	restoreState(start, parser)
+	endChoice(parser)
+	// an attempt that aborts, such as by nesting too deeply, only ends the check
+	parser.tracer.consumers, parser.failure.abort = consumers, abort
+	for _, i := range ambiguous {
+		message := "alternatives " + strconv.Itoa(matched+1) + " and " + strconv.Itoa(i+1) + " both match"
+		trace(TraceEvent{Kind: TraceAmbiguity, PartName: parent.Name, Sequence: Constituents[i], Message: message, StartPos: start.pos, EndPos: end.pos}, parser)
+	}
+	restoreState(end, parser)
 }
 
-func findConstituentseq(Constituentseq []string, parser Parser, path []string) (parts []*Part) {
-	// ensure log indent is large enough
-	if parser.log.indentLevel > len(parser.log.indent) {
-		parser.log.indent = parser.log.indent + parser.log.indent
+// findConstituentseq returns the non-Ignored parts of the constituent sequence, with found reporting whether the whole
+// sequence matched and cut whether it got past a cut, so the part's later alternatives mustn't be tried
+func findConstituentseq(Constituentseq []string, parser Parser, parent *Part) (parts []*Part, found bool, cut bool) {
+	start := *parser.currentPosPointer
+	// trace sequence parsing, only counting the depth of traced sequences so filtered ones leave no gaps
+	if !debugStep(DebugStep{Kind: DebugSequence, PartName: parent.Name, Sequence: Constituentseq, StartPos: start}, parser, parent.Parent) {
+		return nil, false, false
+	}
+	traced := tracing(parent.Name, parser)
+	if traced {
+		trace(TraceEvent{Kind: TraceSequence, PartName: parent.Name, Sequence: Constituentseq, StartPos: start}, parser)
+		parser.tracer.depth++
 	}
-	// log sequence parsing
-	parser.log.buffer.WriteString(parser.log.indent[:parser.log.indentLevel] + strings.Join(Constituentseq, ", ") + "\n")
-	// update indentLevel
-	parser.log.indentLevel = parser.log.indentLevel + 2
 	var Constituents []*Part
+	// a backreference can repeat an Ignored part, so a sequence with one keeps every part it matched to look it up in
+	var matched []*Part
+	backreferences := false
 	for _, constituentID := range Constituentseq {
-		lastChar := constituentID[len(constituentID)-1:]
+		backreferences = backreferences || strings.HasPrefix(constituentID, "=")
+	}
+	for _, constituentID := range Constituentseq {
+		constituent := parseConstituent(constituentID)
 		// find modifiers
-		switch lastChar {
+		switch constituent.modifier {
+		case "^":
+			// a cut consumes nothing, committing the part to this alternative
+			cut = true
+			continue
 		case "+":
-			parts = findMany(constituentID[:len(constituentID)-1], parser, path)
+			var found bool
+			if constituent.separator != "" {
+				parts, found = findSeparated(constituent, parser, parent)
+			} else {
+				parts, found = findMany(constituent.name, parser, parent)
+			}
 			// if one or more required part not found, we're done
-			if len(parts) < 1 {
-				// adjust indent back to current level
-				parser.log.indentLevel = parser.log.indentLevel - 2
-				// log missing part of sequence
-				parser.log.buffer.WriteString(parser.log.indent[:parser.log.indentLevel] + "missing " + constituentID[:len(constituentID)] + " on line " + strconv.Itoa(parser.log.currentLine) + "\n")
+			if !found {
+				// trace missing part of sequence
+				if traced {
+					parser.tracer.depth--
+					trace(TraceEvent{Kind: TraceMiss, PartName: parent.Name, Sequence: Constituentseq, Constituent: constituentID, StartPos: start}, parser)
+				}
 				// return empty slice pointer
-				return parts
+				return nil, false, cut
 			}
 		case "*":
-			parts = findMany(constituentID[:len(constituentID)-1], parser, path)
+			if constituent.separator != "" {
+				parts, _ = findSeparated(constituent, parser, parent)
+			} else {
+				parts, _ = findMany(constituent.name, parser, parent)
+			}
+		case "{}":
+			// a bounded repetition is a choice point, as it gives back its parts if too few are found
+			repetitionStart := saveState(parser)
+			beginChoice(parser)
+			var count int
+			parts, count = findRepeated(constituent.name, constituent.max, parser, parent)
+			// if too few were found, give back what they consumed and we're done
+			if count < constituent.min {
+				restoreState(repetitionStart, parser)
+				endChoice(parser)
+				// trace the shortfall of the sequence
+				if traced {
+					parser.tracer.depth--
+					message := "expected at least " + strconv.Itoa(constituent.min) + " " + constituent.name + ", found " + strconv.Itoa(count)
+					trace(TraceEvent{Kind: TraceMiss, PartName: parent.Name, Sequence: Constituentseq, Constituent: constituentID, Message: message, StartPos: start}, parser)
+				}
+				// return empty slice pointer
+				return nil, false, cut
+			}
+			endChoice(parser)
 		case "?":
-			parts = findOne(constituentID[:len(constituentID)-1], parser, path)
+			// an optional part is a choice point, as the sequence carries on without it if it fails
+			beginChoice(parser)
+			parts = findOne(constituent.name, parser, parent)
+			endChoice(parser)
+		case "=":
+			parts = findBackreference(constituent.name, matched, parser, parent)
+			// if the text of the earlier part isn't repeated, we're done
+			if len(parts) < 1 {
+				// trace missing part of sequence
+				if traced {
+					parser.tracer.depth--
+					trace(TraceEvent{Kind: TraceMiss, PartName: parent.Name, Sequence: Constituentseq, Constituent: constituentID, StartPos: start}, parser)
+				}
+				// return empty slice pointer
+				return nil, false, cut
+			}
+		case "!":
+			// a negative lookahead consumes nothing, failing the sequence if the part matches here
+			if lookahead(constituent.name, parser, parent) {
+				// trace the part of the sequence that shouldn't be there
+				if traced {
+					parser.tracer.depth--
+					trace(TraceEvent{Kind: TraceMiss, PartName: parent.Name, Sequence: Constituentseq, Constituent: constituentID, StartPos: start}, parser)
+				}
+				// return empty slice pointer
+				return nil, false, cut
+			}
+			parts = nil
+		case "&":
+			// a positive lookahead consumes nothing, failing the sequence unless the part matches here
+			if !lookahead(constituent.name, parser, parent) {
+				// trace missing part of sequence
+				if traced {
+					parser.tracer.depth--
+					trace(TraceEvent{Kind: TraceMiss, PartName: parent.Name, Sequence: Constituentseq, Constituent: constituentID, StartPos: start}, parser)
+				}
+				// return empty slice pointer
+				return nil, false, cut
+			}
+			parts = nil
 		default:
-			parts = findOne(constituentID, parser, path)
+			parts = findOne(constituent.name, parser, parent)
 			// if required part not found, we're done
 			if len(parts) < 1 {
-				// adjust indent back to current level
-				parser.log.indentLevel = parser.log.indentLevel - 2
-				// log missing part of sequence
-				parser.log.buffer.WriteString(parser.log.indent[:parser.log.indentLevel] + "missing " + constituentID[:len(constituentID)] + " on line " + strconv.Itoa(parser.log.currentLine) + "\n")
+				// trace missing part of sequence
+				if traced {
+					parser.tracer.depth--
+					trace(TraceEvent{Kind: TraceMiss, PartName: parent.Name, Sequence: Constituentseq, Constituent: constituentID, StartPos: start}, parser)
+				}
 				// return empty slice pointer
-				return parts
+				return nil, false, cut
+			}
+		}
+		// add parts that aren't Ignored (or all parts when keeping them), checking each part of a repetition as a
+		// handler may have changed its Ignore
+		for _, part := range parts {
+			if !part.Ignore || parser.keepIgnored {
+				Constituents = append(Constituents, part)
 			}
 		}
-		// add parts that aren't Ignored
-		if len(parts) > 0 && !parts[0].Ignore {
-			Constituents = append(Constituents, parts...)
+		if backreferences {
+			matched = append(matched, parts...)
 		}
 	}
-	// adjust indent back to current level
-	parser.log.indentLevel = parser.log.indentLevel - 2
-	// write to log buffer
-	parser.log.buffer.WriteString(parser.log.indent[:parser.log.indentLevel] + "found\n")
+	// trace the match
+	if traced {
+		parser.tracer.depth--
+		trace(TraceEvent{Kind: TraceMatch, PartName: parent.Name, Sequence: Constituentseq, StartPos: start}, parser)
+	}
 	// return slice pointer
-	return Constituents
+	return Constituents, true, cut
 }
@@ -0,0 +1,1419 @@
+package dialects_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AdamJonR/dialects"
+)
+
+// grammar provides a Dialectable for tests whose model records the names of the parts handled by record
+type grammar struct {
+	dialect dialects.Dialect
+}
+
+// newGrammar returns a grammar with the root part and part definitions
+func newGrammar(rootName string, partDefinitions map[string]dialects.PartDefinition) grammar {
+	return grammar{dialect: dialects.Dialect{Title: "test", RootName: rootName, PartDefinitions: partDefinitions}}
+}
+
+func (g grammar) NewDialect() *dialects.Dialect {
+	dialect := g.dialect
+	return &dialect
+}
+
+func (g grammar) NewModel() interface{} {
+	return &[]string{}
+}
+
+func (g grammar) GenerateOutput(model interface{}) (string, error) {
+	return strings.Join(*model.(*[]string), ","), nil
+}
+
+// record appends the name of the part to the model
+func record(part *dialects.Part, model interface{}) bool {
+	names := model.(*[]string)
+	*names = append(*names, part.Name)
+	return true
+}
+
+// wordList returns a grammar of whitespace-separated lowercase words
+func wordList() grammar {
+	return newGrammar("root", map[string]dialects.PartDefinition{
+		"root": {Constituents: [][]string{{"item+"}}},
+		"item": {Constituents: [][]string{{"word", "ws?"}}, Handler: record},
+		"word": {Regex: `[a-z]+`},
+		"ws":   {Regex: `[ \t\n]+`, Ignore: true},
+	})
+}
+
+func TestParseToTree(t *testing.T) {
+	root, err, _ := dialects.ParseToTree(wordList(), "ab cd ef")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(root.Constituents) != 3 {
+		t.Fatalf("expected 3 items, got %d", len(root.Constituents))
+	}
+	for i, item := range root.Constituents {
+		if item.Parent != root {
+			t.Errorf("item %d has the wrong parent", i)
+		}
+		word := item.Constituents[0]
+		if word.Parent != item || word.StartPos != i*3 || word.EndPos != i*3+2 {
+			t.Errorf("word %d has parent %p and span [%d:%d]", i, word.Parent, word.StartPos, word.EndPos)
+		}
+	}
+}
+
+// singleWord returns a grammar matching exactly one lowercase word
+func singleWord() grammar {
+	return newGrammar("word", map[string]dialects.PartDefinition{
+		"word": {Regex: `[a-z]+`, Handler: record},
+	})
+}
+
+func TestParseTrailingInput(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		ok    bool
+	}{
+		{"exactly consumed", "word", true},
+		{"trailing whitespace", "word  ", false},
+		{"trailing garbage", "word!!", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err, _ := dialects.Parse(singleWord(), test.input)
+			if test.ok && err != nil {
+				t.Fatalf("expected success, got %v", err)
+			}
+			if !test.ok {
+				var parseError *dialects.ParseError
+				if !errors.As(err, &parseError) || parseError.Offset != 4 || parseError.Line != 1 {
+					t.Fatalf("expected an error at offset 4 on line 1, got %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestParseAllowTrailing(t *testing.T) {
+	g := wordList()
+	g.dialect.AllowTrailing = true
+	output, err, _ := dialects.Parse(g, "ab cd!!")
+	if err != nil || output != "item,item" {
+		t.Errorf("expected the prefix to parse, got %q and %v", output, err)
+	}
+}
+
+func TestParsePrefix(t *testing.T) {
+	g := newGrammar("call", map[string]dialects.PartDefinition{
+		"call":   {Constituents: [][]string{{"name", "suffix*", "bang?"}}},
+		"name":   {Regex: `[a-z]+`, Handler: record},
+		"suffix": {Regex: `\.[a-z]+`, Handler: record},
+		"bang":   {Literal: "!", Handler: record},
+	})
+	g.dialect.SkipPattern = `[ ]+`
+	tests := []struct {
+		input    string
+		output   string
+		consumed int
+	}{
+		{input: "ab.cd! rest", output: "name,suffix,bang", consumed: 6},
+		// the repetition and option that match nothing, and the skipped text before them, aren't consumed
+		{input: "ab  }", output: "name", consumed: 2},
+		{input: "ab .cd  .}", output: "name,suffix", consumed: 6},
+		{input: "ab", output: "name", consumed: 2},
+	}
+	for _, test := range tests {
+		output, consumed, err, _ := dialects.ParsePrefix(g, test.input)
+		if err != nil || output != test.output || consumed != test.consumed {
+			t.Errorf("expected %q to parse to %q consuming %d bytes, got %q consuming %d and %v", test.input, test.output, test.consumed, output, consumed, err)
+		}
+	}
+	if _, consumed, err, _ := dialects.ParsePrefix(g, "12"); err == nil || consumed != 0 {
+		t.Errorf("expected an error consuming nothing, got %d and %v", consumed, err)
+	}
+}
+
+func TestParsePart(t *testing.T) {
+	g := wordList()
+	var items []string
+	item := g.dialect.PartDefinitions["item"]
+	item.Handler = func(part *dialects.Part, model interface{}) bool {
+		items = append(items, part.Value)
+		return len(*model.(*[]string)) == 0
+	}
+	g.dialect.PartDefinitions["item"] = item
+	part, err, _ := dialects.ParsePart(g, "item", "ab ")
+	if err != nil || part.Name != "item" || part.Value != "ab " || strings.Join(items, ",") != "ab " {
+		t.Fatalf("expected the item to parse with its handler run, got %v, %v, and %q", part, err, items)
+	}
+	// the part must consume the whole input, like the root part
+	var parseError *dialects.ParseError
+	if _, err, _ := dialects.ParsePart(g, "item", "ab cd"); !errors.As(err, &parseError) || parseError.Offset != 3 {
+		t.Errorf("expected an error at offset 3, got %v", err)
+	}
+	var dialectError *dialects.DialectError
+	if _, err, _ := dialects.ParsePart(g, "items", "ab"); !errors.As(err, &dialectError) || dialectError.PartName != "items" {
+		t.Errorf("expected a DialectError for the undefined part, got %v", err)
+	}
+}
+
+// number returns a grammar matching a single number
+func number() grammar {
+	return newGrammar("number", map[string]dialects.PartDefinition{
+		"number": {Regex: `[0-9]+`},
+	})
+}
+
+func TestRegexesAreAnchored(t *testing.T) {
+	g := number()
+	g.dialect.AllowTrailing = true
+	_, err, _ := dialects.ParseToTree(g, "abc123")
+	var parseError *dialects.ParseError
+	if !errors.As(err, &parseError) || parseError.Offset != 0 || parseError.PartName != "number" {
+		t.Fatalf("expected number to fail at offset 0, got %v", err)
+	}
+}
+
+func TestUnanchoredRegexes(t *testing.T) {
+	g := number()
+	g.dialect.AllowTrailing = true
+	g.dialect.UnanchoredRegexes = true
+	// the legacy behavior matches past the unexpected characters
+	root, err, _ := dialects.ParseToTree(g, "abc123")
+	if err != nil || root.Value != "123" {
+		t.Fatalf("expected the legacy match of 123, got %v and %v", root, err)
+	}
+}
+
+func TestParseEndOfInput(t *testing.T) {
+	tests := []struct {
+		name         string
+		grammar      grammar
+		input        string
+		constituents int
+	}{
+		{"single character", newGrammar("x", map[string]dialects.PartDefinition{
+			"x": {Regex: `x`},
+		}), "x", 0},
+		{"ending at a required token", newGrammar("block", map[string]dialects.PartDefinition{
+			"block": {Constituents: [][]string{{"open", "x*", "close"}}},
+			"open":  {Regex: `\{`},
+			"x":     {Regex: `x`},
+			"close": {Regex: `\}`},
+		}), "{xx}", 4},
+		{"empty input with an optional-only root", newGrammar("root", map[string]dialects.PartDefinition{
+			"root": {Constituents: [][]string{{"x*", "y?"}}},
+			"x":    {Regex: `x`},
+			"y":    {Regex: `y`},
+		}), "", 0},
+		{"ending with a repetition", newGrammar("root", map[string]dialects.PartDefinition{
+			"root": {Constituents: [][]string{{"y", "x+"}}},
+			"x":    {Regex: `x`},
+			"y":    {Regex: `y`},
+		}), "yxxx", 4},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root, err, _ := dialects.ParseToTree(test.grammar, test.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(root.Constituents) != test.constituents || root.EndPos != len(test.input) {
+				t.Errorf("expected %d constituents ending at %d, got %d ending at %d", test.constituents, len(test.input), len(root.Constituents), root.EndPos)
+			}
+		})
+	}
+}
+
+func TestZeroWidthRepetitions(t *testing.T) {
+	for _, modifier := range []string{"*", "+"} {
+		t.Run(modifier, func(t *testing.T) {
+			g := newGrammar("root", map[string]dialects.PartDefinition{
+				"root": {Constituents: [][]string{{"ws" + modifier, "x", "ws" + modifier}}},
+				"ws":   {Regex: `[ \t]*`},
+				"x":    {Regex: `x`},
+			})
+			// the trailing repetition only matches empty, which used to loop forever
+			root, err, _ := dialects.ParseToTree(g, "  x")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(root.Constituents) != 3 || root.Constituents[2].StartPos != 3 || root.Constituents[2].EndPos != 3 {
+				t.Errorf("expected ws, x, and an empty ws, got %d constituents", len(root.Constituents))
+			}
+		})
+	}
+}
+
+func TestPartPathAndParent(t *testing.T) {
+	paths := make(map[string][]string)
+	handle := func(part *dialects.Part, model interface{}) bool {
+		// the parents are set before the handler is called
+		identifier := part.Constituents[1]
+		if identifier.Parent != part || part.Parent == nil || part.Parent.Name != part.Path[len(part.Path)-1] {
+			t.Errorf("%s has parent %v but path %v", part.Name, part.Parent, part.Path)
+		}
+		paths[identifier.Name+":"+identifier.Value] = identifier.Path
+		return true
+	}
+	g := newGrammar("program", map[string]dialects.PartDefinition{
+		"program":      {Constituents: [][]string{{"decl+"}}},
+		"decl":         {Constituents: [][]string{{"functionDecl"}, {"varDecl"}}},
+		"functionDecl": {Constituents: [][]string{{"funcKeyword", "identifier", "body"}}, Handler: handle},
+		"varDecl":      {Constituents: [][]string{{"varKeyword", "identifier", "semi"}}, Handler: handle},
+		"body":         {Constituents: [][]string{{"open", "varDecl*", "close"}}, Ignore: true},
+		"funcKeyword":  {Regex: `func `},
+		"varKeyword":   {Regex: `var `},
+		"identifier":   {Regex: `[a-z]+`},
+		"semi":         {Regex: `;`},
+		"open":         {Regex: `\{`},
+		"close":        {Regex: `\}`},
+	})
+	if _, err, _ := dialects.Parse(g, "var a;func f{var b;var c;}var d;"); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"identifier:a": "program/decl/varDecl",
+		"identifier:f": "program/decl/functionDecl",
+		// the Ignored body still appears in the paths of its descendants
+		"identifier:b": "program/decl/functionDecl/body/varDecl",
+		"identifier:c": "program/decl/functionDecl/body/varDecl",
+		"identifier:d": "program/decl/varDecl",
+	}
+	for key, path := range expected {
+		if strings.Join(paths[key], "/") != path {
+			t.Errorf("expected %s to have path %s, got %v", key, path, paths[key])
+		}
+	}
+}
+
+func TestHandlerSeesEndPos(t *testing.T) {
+	input := "ab cd"
+	var spans []string
+	span := func(part *dialects.Part, model interface{}) bool {
+		spans = append(spans, input[part.StartPos:part.EndPos])
+		return true
+	}
+	g := newGrammar("root", map[string]dialects.PartDefinition{
+		"root": {Constituents: [][]string{{"pair"}}},
+		"pair": {Constituents: [][]string{{"word", "ws", "word"}}, Handler: span},
+		"word": {Regex: `[a-z]+`, Handler: span},
+		"ws":   {Regex: `[ ]+`, Ignore: true},
+	})
+	if _, err, _ := dialects.Parse(g, input); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(spans, "|") != "ab|cd|ab cd" {
+		t.Errorf("expected handlers to see the spans ab, cd and ab cd, got %q", spans)
+	}
+}
+
+func TestRegexPartHandler(t *testing.T) {
+	output, err, _ := dialects.Parse(singleWord(), "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output != "word" {
+		t.Errorf("expected the regex part's handler to record word, got %q", output)
+	}
+	// a rejecting handler fails the regex part like a failed match
+	g := newGrammar("word", map[string]dialects.PartDefinition{
+		"word": {Regex: `[a-z]+`, Handler: func(*dialects.Part, interface{}) bool { return false }},
+	})
+	if _, err, _ := dialects.Parse(g, "abc"); err == nil {
+		t.Error("expected the rejected regex part to fail the parse")
+	}
+	// a regex part's handler sees its Parent and Path like a composite part's
+	var paths []string
+	g = newGrammar("root", map[string]dialects.PartDefinition{
+		"root": {Constituents: [][]string{{"pair"}}},
+		"pair": {Constituents: [][]string{{"word", "ws", "word"}}},
+		"word": {Regex: `[a-z]+`, Handler: func(part *dialects.Part, model interface{}) bool {
+			if part.Parent == nil || part.Parent.Name != "pair" {
+				t.Errorf("expected %s to have parent pair, got %v", part.Value, part.Parent)
+			}
+			paths = append(paths, strings.Join(part.Path, "/"))
+			return true
+		}},
+		"ws": {Regex: `[ ]+`, Ignore: true},
+	})
+	if _, err, _ := dialects.Parse(g, "ab cd"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(paths, "|") != "root/pair|root/pair" {
+		t.Errorf("expected both words to have path root/pair, got %q", paths)
+	}
+}
+
+// ghostEntries returns a grammar whose first declaration alternative records a name before failing
+func ghostEntries(deferHandlers bool) grammar {
+	g := newGrammar("decl", map[string]dialects.PartDefinition{
+		"decl":       {Constituents: [][]string{{"assignment", "semi"}, {"name", "colon"}}},
+		"assignment": {Constituents: [][]string{{"name", "equals"}}},
+		"name":       {Regex: `[a-z]+`, Handler: record},
+		"equals":     {Regex: `=`},
+		"semi":       {Regex: `;`},
+		"colon":      {Regex: `:`},
+	})
+	g.dialect.DeferHandlers = deferHandlers
+	return g
+}
+
+func TestDeferHandlers(t *testing.T) {
+	// the first alternative records the name and then fails on the missing equals
+	output, err, _ := dialects.Parse(ghostEntries(false), "abc:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output != "name,name" {
+		t.Errorf("expected the failed alternative to leave a ghost entry without DeferHandlers, got %q", output)
+	}
+	output, err, _ = dialects.Parse(ghostEntries(true), "abc:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output != "name" {
+		t.Errorf("expected only the committed name with DeferHandlers, got %q", output)
+	}
+}
+
+func TestParseBrokenRegex(t *testing.T) {
+	g := newGrammar("root", map[string]dialects.PartDefinition{
+		"root":  {Constituents: [][]string{{"word"}, {"digit"}}},
+		"word":  {Regex: `[a-z]+`},
+		"digit": {Regex: `[0-9`},
+	})
+	// the broken part is never attempted for this input, but the error is still reported up front
+	_, err, _ := dialects.Parse(g, "abc")
+	var dialectErr *dialects.DialectError
+	if !errors.As(err, &dialectErr) {
+		t.Fatalf("expected a DialectError, got %v", err)
+	}
+	if dialectErr.PartName != "digit" {
+		t.Errorf("expected the error to name digit, got %s", dialectErr.PartName)
+	}
+	if !strings.Contains(err.Error(), "missing closing ]") {
+		t.Errorf("expected the error to include the regex syntax error, got %q", err)
+	}
+}
+
+// alternatives returns a grammar whose items try several alternatives that fail before the last one matches
+func alternatives() grammar {
+	return newGrammar("root", map[string]dialects.PartDefinition{
+		"root":   {Constituents: [][]string{{"item+"}}},
+		"item":   {Constituents: [][]string{{"number", "ws?"}, {"string", "ws?"}, {"symbol", "ws?"}, {"word", "ws?"}}},
+		"number": {Regex: `[0-9]+`},
+		"string": {Regex: `"[^"]*"`},
+		"symbol": {Regex: `[+\-*/=]`},
+		"word":   {Regex: `[a-z]+`},
+		"ws":     {Regex: `[ ]+`, Ignore: true},
+	})
+}
+
+func BenchmarkRegexFailingAlternatives(b *testing.B) {
+	// 5 MB of words, each preceded by failed attempts at a number, a string and a symbol
+	input := strings.Repeat("abcd ", 1<<20)
+	compiled, err := dialects.Compile(alternatives())
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(input)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err, _ := compiled.Parse(input); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestCompiledConcurrentParses(t *testing.T) {
+	compiled, err := dialects.Compile(wordList())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// parses sharing the compiled dialect and a coverage keep their own state, which -race checks
+	coverage := &dialects.Coverage{}
+	var wg sync.WaitGroup
+	for i := 1; i <= 16; i++ {
+		wg.Add(1)
+		go func(count int) {
+			defer wg.Done()
+			input := strings.Repeat("ab ", count)
+			options := dialects.Options{Memoize: count%2 == 0, Coverage: coverage, Diagnostics: &[]dialects.Diagnostic{}}
+			output, err, _ := compiled.ParseWithOptions(input, options)
+			if expected := strings.TrimSuffix(strings.Repeat("item,", count), ","); err != nil || output != expected {
+				t.Errorf("%d items: expected %q, got %q and %v", count, expected, output, err)
+			}
+			root, err, _ := compiled.ParseToTreeWithOptions(input, options)
+			if err != nil || len(root.Constituents) != count {
+				t.Errorf("%d items: expected as many items in the tree, got %v", count, err)
+			}
+			var parseError *dialects.ParseError
+			if _, err, _ := compiled.Parse(input + "12"); !errors.As(err, &parseError) || parseError.Offset != len(input) {
+				t.Errorf("%d items: expected an error at offset %d, got %v", count, len(input), err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if report := coverage.Report(wordList()); len(report.UncoveredParts) != 0 {
+		t.Errorf("expected every part to be covered, got %v", report.UncoveredParts)
+	}
+}
+
+func TestIgnoredPartsInRepetitions(t *testing.T) {
+	g := newGrammar("program", map[string]dialects.PartDefinition{
+		"program":   {Constituents: [][]string{{"line+"}}},
+		"line":      {Constituents: [][]string{{"statement"}, {"comment"}}, Handler: ignoreComments},
+		"statement": {Constituents: [][]string{{"word", "semi", "ws?"}}},
+		"comment":   {Regex: `#[^\n]*\n`, Ignore: true, Handler: record},
+		"word":      {Regex: `[a-z]+`},
+		"semi":      {Regex: `;`},
+		"ws":        {Regex: `[ \n]+`, Ignore: true},
+	})
+	root, err, _ := dialects.ParseToTree(g, "a; #one\nb;\n#two\n#three\nc;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var words []string
+	for _, line := range root.Constituents {
+		if line.Name != "line" || line.Constituents[0].Name != "statement" {
+			t.Fatalf("expected only statement lines, got %s with %v", line.Name, line.Constituents)
+		}
+		words = append(words, line.Constituents[0].Constituents[0].Value)
+	}
+	if strings.Join(words, ",") != "a,b,c" {
+		t.Errorf("expected the statements a, b and c, got %v", words)
+	}
+	// the Ignored comments still have their handlers called
+	output, err, _ := dialects.Parse(g, "a; #one\nb;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output != "comment" {
+		t.Errorf("expected the comment's handler to be called, got %q", output)
+	}
+}
+
+// ignoreComments leaves lines that only hold an Ignored comment out of the tree
+func ignoreComments(part *dialects.Part, model interface{}) bool {
+	part.Ignore = len(part.Constituents) == 0
+	return true
+}
+
+func TestCompositeValue(t *testing.T) {
+	root, err, _ := dialects.ParseToTree(wordList(), "ab  cd\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the Ignored whitespace is part of the text each part spans
+	if root.Value != "ab  cd\n" {
+		t.Errorf("expected the root to span the whole input, got %q", root.Value)
+	}
+	for i, expected := range []string{"ab  ", "cd\n"} {
+		if item := root.Constituents[i]; item.Value != expected || len(item.Constituents) != 1 {
+			t.Errorf("expected item %d to span %q with one constituent, got %q with %d", i, expected, item.Value, len(item.Constituents))
+		}
+	}
+}
+
+func TestPartLineAndColumn(t *testing.T) {
+	g := newGrammar("root", map[string]dialects.PartDefinition{
+		"root": {Constituents: [][]string{{"item+"}}},
+		"item": {Constituents: [][]string{{"word", "ws?"}}},
+		"word": {Regex: `\pL+`},
+		"ws":   {Regex: `[ \t\n]+`, Ignore: true},
+	})
+	root, err, _ := dialects.ParseToTree(g, "héllo wörld\n\tça va")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		word                                 string
+		startLine, startCol, endLine, endCol int
+	}{
+		{"héllo", 1, 1, 1, 6},
+		{"wörld", 1, 7, 1, 12},
+		// columns count runes, with the tab as one column
+		{"ça", 2, 2, 2, 4},
+		{"va", 2, 5, 2, 7},
+	}
+	for i, test := range tests {
+		word := root.Constituents[i].Constituents[0]
+		if word.Value != test.word || word.StartLine != test.startLine || word.StartCol != test.startCol || word.EndLine != test.endLine || word.EndCol != test.endCol {
+			t.Errorf("expected %s at %d:%d-%d:%d, got %s at %d:%d-%d:%d", test.word, test.startLine, test.startCol, test.endLine, test.endCol,
+				word.Value, word.StartLine, word.StartCol, word.EndLine, word.EndCol)
+		}
+	}
+	// the item ends after the whitespace, at the start of the next line
+	if item := root.Constituents[1]; item.EndLine != 2 || item.EndCol != 2 {
+		t.Errorf("expected the second item to end at 2:2, got %d:%d", item.EndLine, item.EndCol)
+	}
+}
+
+func TestLineAfterBacktracking(t *testing.T) {
+	g := newGrammar("root", map[string]dialects.PartDefinition{
+		"root":    {Constituents: [][]string{{"pair*", "word", "bang?", "word", "newline"}}},
+		"pair":    {Constituents: [][]string{{"word", "newline", "word", "newline", "semi"}}},
+		"bang":    {Constituents: [][]string{{"newline", "semi"}}},
+		"word":    {Regex: `[a-z]+`},
+		"newline": {Literal: "\n"},
+		"semi":    {Literal: ";"},
+	})
+	// the pair fails on line 3 after matching two lines, as does the optional bang after matching one, and the line
+	// goes back with the position each time
+	var misses []string
+	options := dialects.Options{TraceFunc: func(event dialects.TraceEvent) {
+		if event.Kind == dialects.TraceMiss {
+			misses = append(misses, event.PartName+":"+event.Constituent+"@"+strconv.Itoa(event.Line))
+		}
+	}}
+	_, err, _ := dialects.ParseWithOptions(g, "ab\ncd\n", options)
+	var parseError *dialects.ParseError
+	if !errors.As(err, &parseError) || parseError.Line != 3 || parseError.Column != 1 {
+		t.Fatalf("expected the farthest failure on line 3, got %v", err)
+	}
+	expected := []string{"pair:semi@3", "bang:semi@2", "root:word@1"}
+	if strings.Join(misses, " ") != strings.Join(expected, " ") {
+		t.Errorf("expected misses %q, got %q", expected, misses)
+	}
+}
+
+func TestRegexCaptureGroups(t *testing.T) {
+	g := newGrammar("root", map[string]dialects.PartDefinition{
+		"root":     {Constituents: [][]string{{"keyValue", "ws", "word"}}},
+		"keyValue": {Regex: `(?P<key>[a-z]+)=(?P<value>[0-9]+)(;)?`},
+		"word":     {Regex: `[a-z]+`},
+		"ws":       {Regex: `[ ]+`, Ignore: true},
+	})
+	root, err, _ := dialects.ParseToTree(g, "size=12 abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyValue := root.Constituents[0]
+	// the unmatched optional group is empty, and Value is still the whole match
+	if strings.Join(keyValue.Matches, "|") != "size=12|size|12|" || keyValue.Value != "size=12" {
+		t.Errorf("expected matches size=12, size, 12 and an empty group with value size=12, got %q and %q", keyValue.Matches, keyValue.Value)
+	}
+	if len(keyValue.Groups) != 2 || keyValue.Groups["key"] != "size" || keyValue.Groups["value"] != "12" {
+		t.Errorf("expected the groups key=size and value=12, got %v", keyValue.Groups)
+	}
+	// regexes without groups leave both unset
+	if word := root.Constituents[1]; word.Matches != nil || word.Groups != nil || word.Value != "abc" {
+		t.Errorf("expected only the value abc, got %q, %v and %q", word.Matches, word.Groups, word.Value)
+	}
+}
+
+func TestValidateMatchCtx(t *testing.T) {
+	var positions []string
+	g := newGrammar("root", map[string]dialects.PartDefinition{
+		"root": {Constituents: [][]string{{"section+"}}},
+		"section": {Regex: `\[([a-z]+)\]\n`, Handler: func(part *dialects.Part, model interface{}) bool {
+			names := model.(*[]string)
+			*names = append(*names, part.Matches[1])
+			return true
+		}, ValidateMatchCtx: func(matches []string, pos int, line int, model interface{}) (bool, string) {
+			positions = append(positions, strconv.Itoa(pos)+"/"+strconv.Itoa(line))
+			for _, name := range *model.(*[]string) {
+				if name == matches[1] {
+					return false, "duplicate section " + name
+				}
+			}
+			return true, ""
+		}, ValidateMatch: func([]string) (bool, string) {
+			t.Error("expected ValidateMatchCtx to be used in place of ValidateMatch")
+			return true, ""
+		}},
+	})
+	if output, err, _ := dialects.Parse(g, "[a]\n[b]\n"); err != nil || output != "a,b" {
+		t.Fatalf("expected a,b, got %q and %v", output, err)
+	}
+	positions = nil
+	_, err, _ := dialects.Parse(g, "[a]\n[b]\n[a]\n")
+	var parseError *dialects.ParseError
+	if !errors.As(err, &parseError) {
+		t.Fatalf("expected a ParseError, got %v", err)
+	}
+	// the validator's message is reported where the rejected match starts
+	if parseError.Message != "invalid section: duplicate section a" || parseError.Offset != 8 || parseError.Line != 3 {
+		t.Errorf("unexpected error %q at offset %d, line %d", parseError.Message, parseError.Offset, parseError.Line)
+	}
+	if strings.Join(positions, ",") != "0/1,4/2,8/3" {
+		t.Errorf("expected the validator to see each section's offset and line, got %v", positions)
+	}
+}
+
+func TestLiteralParts(t *testing.T) {
+	g := newGrammar("rule", map[string]dialects.PartDefinition{
+		"rule":  {Constituents: [][]string{{"word", "arrow", "word", "end"}}},
+		"word":  {Regex: `[a-z]+`},
+		"arrow": {Literal: " -> "},
+		// literals have no regex semantics, so nothing needs escaping
+		"end": {Literal: ".*\n"},
+	})
+	root, err, _ := dialects.ParseToTree(g, "a -> b.*\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if arrow := root.Constituents[1]; arrow.Value != " -> " || arrow.StartPos != 1 || arrow.EndPos != 5 {
+		t.Errorf("expected the arrow at [1:5], got %q at [%d:%d]", arrow.Value, arrow.StartPos, arrow.EndPos)
+	}
+	if end := root.Constituents[3]; end.EndLine != 2 || end.EndCol != 1 {
+		t.Errorf("expected the end to move to the next line, got %d:%d", end.EndLine, end.EndCol)
+	}
+	// missing literals are described by their text
+	_, err, _ = dialects.Parse(g, "a => b.*\n")
+	var parseError *dialects.ParseError
+	if !errors.As(err, &parseError) || parseError.Message != "expected ' -> '" || parseError.Offset != 1 {
+		t.Errorf("expected the arrow at offset 1, got %v", err)
+	}
+	if _, err, _ = dialects.Parse(g, "a -> b.+\n"); err == nil || !strings.Contains(err.Error(), "expected '.*\n'") {
+		t.Errorf("expected the end to be matched literally, got %v", err)
+	}
+}
+
+// conditions returns a grammar of if statements and assignments to identifiers
+func conditions() grammar {
+	return newGrammar("statement", map[string]dialects.PartDefinition{
+		"statement":  {Constituents: [][]string{{"ifKeyword", "ws", "identifier"}, {"identifier", "ws?", "equals", "ws?", "identifier"}}},
+		"ifKeyword":  {Keyword: "if"},
+		"identifier": {Regex: `[A-Za-z_][A-Za-z0-9_]*`},
+		"equals":     {Literal: "="},
+		"ws":         {Regex: `[ ]+`, Ignore: true},
+	})
+}
+
+func TestKeywordParts(t *testing.T) {
+	// the keyword doesn't match the start of the identifier ifTrue, so the statement is an assignment
+	root, err, _ := dialects.ParseToTree(conditions(), "ifTrue = x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root.Constituents[0].Name != "identifier" || root.Constituents[0].Value != "ifTrue" {
+		t.Errorf("expected the identifier ifTrue, got %s %q", root.Constituents[0].Name, root.Constituents[0].Value)
+	}
+	root, err, _ = dialects.ParseToTree(conditions(), "if ready")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root.Constituents[0].Name != "ifKeyword" {
+		t.Errorf("expected the if keyword, got %s", root.Constituents[0].Name)
+	}
+	// keywords are matched in their case unless the dialect says otherwise
+	_, err, _ = dialects.Parse(conditions(), "IF ready")
+	var parseError *dialects.ParseError
+	if !errors.As(err, &parseError) || parseError.Offset != 3 {
+		t.Fatalf("expected IF to be read as an identifier, missing its equals at offset 3, got %v", err)
+	}
+	g := conditions()
+	g.dialect.CaseInsensitiveKeywords = true
+	root, err, _ = dialects.ParseToTree(g, "IF ready")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keyword := root.Constituents[0]; keyword.Name != "ifKeyword" || keyword.Value != "IF" {
+		t.Errorf("expected the if keyword with its case kept, got %s %q", keyword.Name, keyword.Value)
+	}
+}
+
+func TestKeywordContinuation(t *testing.T) {
+	g := newGrammar("statement", map[string]dialects.PartDefinition{
+		"statement": {Constituents: [][]string{{"ifKeyword", "rest"}}},
+		"ifKeyword": {Keyword: "if"},
+		"rest":      {Regex: `.*`},
+	})
+	if _, err, _ := dialects.Parse(g, "if-x"); err != nil {
+		t.Errorf("expected a dash not to continue the keyword by default, got %v", err)
+	}
+	// a dialect whose identifiers contain dashes can say so
+	g.dialect.KeywordContinuation = `[A-Za-z0-9_-]`
+	_, err, _ := dialects.Parse(g, "if-x")
+	if err == nil || !strings.Contains(err.Error(), "expected keyword 'if'") {
+		t.Errorf("expected the keyword to be missing, got %v", err)
+	}
+}
+
+func TestCaseInsensitiveParts(t *testing.T) {
+	g := newGrammar("section", map[string]dialects.PartDefinition{
+		"section": {Constituents: [][]string{{"open", "name", "close", "ws", "enabled"}}},
+		"open":    {Literal: "[section "},
+		"name":    {Regex: `[a-z]+`},
+		"close":   {Literal: "]"},
+		"enabled": {Keyword: "true"},
+		"ws":      {Regex: `[ ]+`, Ignore: true},
+	})
+	// a part can match in any case by itself
+	def := g.dialect.PartDefinitions["enabled"]
+	def.CaseInsensitive = true
+	g.dialect.PartDefinitions["enabled"] = def
+	if _, err, _ := dialects.Parse(g, "[SECTION main] True"); err == nil {
+		t.Error("expected the literal to keep matching in its case")
+	}
+	root, err, _ := dialects.ParseToTree(g, "[section main] True")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enabled := root.Constituents[len(root.Constituents)-1]; enabled.Value != "True" {
+		t.Errorf("expected the keyword with its case kept, got %q", enabled.Value)
+	}
+	// or the dialect can match every part in any case, keeping the case of the input
+	g.dialect.CaseInsensitive = true
+	root, err, _ = dialects.ParseToTree(g, "[SECTION Main] TRUE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var values []string
+	for _, part := range root.Constituents {
+		values = append(values, part.Value)
+	}
+	if got := strings.Join(values, "|"); got != "[SECTION |Main|]|TRUE" {
+		t.Errorf("expected the values in the case of the input, got %q", got)
+	}
+	// unless a part stays case sensitive
+	def = g.dialect.PartDefinitions["name"]
+	def.CaseSensitive = true
+	g.dialect.PartDefinitions["name"] = def
+	_, err, _ = dialects.Parse(g, "[SECTION Main] TRUE")
+	var parseError *dialects.ParseError
+	if !errors.As(err, &parseError) || parseError.Offset != 9 {
+		t.Fatalf("expected the name to be missing at offset 9, got %v", err)
+	}
+	if _, err, _ := dialects.Parse(g, "[SECTION main] TRUE"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNegativeLookahead(t *testing.T) {
+	// a regex part as the lookahead keeps reserved words from being read as names
+	g := newGrammar("program", map[string]dialects.PartDefinition{
+		"program":   {Constituents: [][]string{{"statement+"}}},
+		"statement": {Constituents: [][]string{{"!reserved", "name", "ws?"}}},
+		"reserved":  {Regex: `(?:begin|end)\b`, Handler: record},
+		"name":      {Regex: `[a-z]+`, Handler: record},
+		"ws":        {Regex: `[ ]+`, Ignore: true},
+	})
+	output, err, _ := dialects.Parse(g, "a ending")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output != "name,name" {
+		t.Errorf("expected two names, got %q", output)
+	}
+	_, err, _ = dialects.Parse(g, "a end")
+	var parseError *dialects.ParseError
+	if !errors.As(err, &parseError) || parseError.Offset != 2 {
+		t.Fatalf("expected end not to be read as a name at offset 2, got %v", err)
+	}
+}
+
+func TestNegativeLookaheadComposite(t *testing.T) {
+	// a composite part as the lookahead ends the statements at the end clause without consuming it
+	g := newGrammar("block", map[string]dialects.PartDefinition{
+		"block":     {Constituents: [][]string{{"begin", "ws", "statement*", "endClause"}}},
+		"statement": {Constituents: [][]string{{"!endClause", "name", "ws"}}},
+		"endClause": {Constituents: [][]string{{"endKw", "semicolon"}}, Handler: record},
+		"endKw":     {Literal: "end", Handler: record},
+		"semicolon": {Literal: ";"},
+		"begin":     {Literal: "begin"},
+		"name":      {Regex: `[a-z]+`, Handler: record},
+		"ws":        {Regex: `[ ]+`, Ignore: true},
+	})
+	for _, memoize := range []bool{false, true} {
+		streamed := 0
+		options := dialects.Options{Memoize: memoize, OnPart: map[string]func(*dialects.Part){
+			"endKw": func(*dialects.Part) { streamed++ },
+		}}
+		compiled, err := dialects.Compile(g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the lookahead finds the end keyword in endx and end; but its handler calls and parts are discarded
+		output, err, _ := compiled.ParseWithOptions("begin a endx end;", options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if output != "name,name,endKw,endClause" {
+			t.Errorf("expected only the handlers of the parts kept to be called, got %q", output)
+		}
+		if streamed != 1 {
+			t.Errorf("expected the end keyword to be streamed once, got %d", streamed)
+		}
+		root, err, _ := compiled.ParseToTreeWithOptions("begin a endx end;", dialects.Options{Memoize: memoize})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, part := range root.Constituents {
+			names = append(names, part.Name)
+			if part.Name == "statement" && (len(part.Constituents) != 1 || part.Constituents[0].Name != "name") {
+				t.Errorf("expected a statement of just its name, got %s", part)
+			}
+		}
+		if got := strings.Join(names, " "); got != "begin statement statement endClause" {
+			t.Errorf("expected the statements between begin and end, got %q", got)
+		}
+	}
+}
+
+func TestPositiveLookahead(t *testing.T) {
+	g := newGrammar("value", map[string]dialects.PartDefinition{
+		"value": {Constituents: [][]string{{"&digit", "number"}, {"word"}}},
+		"digit": {Regex: `[0-9]`, Handler: record, ValidateMatch: func(matches []string) (bool, string) {
+			return matches[0] != "0", "leading zero"
+		}},
+		"number": {Regex: `[0-9]+`, Handler: record},
+		"word":   {Regex: `[a-z]+`, Handler: record},
+	})
+	// the lookahead leaves the number to match from the start, and its part and handler call are discarded
+	output, err, _ := dialects.Parse(g, "123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output != "number" {
+		t.Errorf("expected only the number's handler to be called, got %q", output)
+	}
+	root, err, _ := dialects.ParseToTree(g, "123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(root.Constituents) != 1 || root.Constituents[0].Name != "number" || root.Constituents[0].StartPos != 0 || root.Constituents[0].Value != "123" {
+		t.Errorf("expected just the number from offset 0, got %s", root)
+	}
+	if output, err, _ := dialects.Parse(g, "abc"); err != nil || output != "word" {
+		t.Errorf("expected a word, got %q, %v", output, err)
+	}
+	// the lookahead's rejected match fails the number branch without being reported
+	_, err, _ = dialects.Parse(g, "0")
+	if err == nil || strings.Contains(err.Error(), "leading zero") || !strings.Contains(err.Error(), "expected word") {
+		t.Errorf("expected the word to be missing, got %v", err)
+	}
+}
+
+func TestBoundedRepetition(t *testing.T) {
+	g := newGrammar("flags", map[string]dialects.PartDefinition{
+		"flags": {Constituents: [][]string{{"flag{1,3}", "extra*"}}},
+		"flag":  {Regex: `[a-z]`, Handler: record},
+		"extra": {Regex: `[a-z]`, Handler: record},
+	})
+	// the repetition stops at its upper bound, leaving the rest for the next constituent
+	output, err, _ := dialects.Parse(g, "abcde")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output != "flag,flag,flag,extra,extra" {
+		t.Errorf("expected three flags then the extras, got %q", output)
+	}
+	var log strings.Builder
+	if _, err, _ := dialects.ParseWithOptions(g, "", dialects.Options{TraceWriter: &log}); err == nil {
+		t.Fatal("expected a missing flag to fail the parse")
+	}
+	if !strings.Contains(log.String(), "expected at least 1 flag, found 0 on line 1") {
+		t.Errorf("expected the log to report the missing flag, got:\n%s", log.String())
+	}
+	tests := []struct {
+		constituentID string
+		input         string
+		expected      int
+	}{
+		{"flag{0,2}", "", 0},
+		{"flag{0,2}", "ab", 2},
+		{"flag{3}", "abc", 3},
+		{"flag{2,}", "abcd", 4},
+	}
+	for _, test := range tests {
+		g := newGrammar("flags", map[string]dialects.PartDefinition{
+			"flags": {Constituents: [][]string{{test.constituentID}}},
+			"flag":  {Regex: `[a-z]`},
+		})
+		root, err, _ := dialects.ParseToTree(g, test.input)
+		if err != nil {
+			t.Errorf("%s of %q: %v", test.constituentID, test.input, err)
+			continue
+		}
+		if len(root.Constituents) != test.expected {
+			t.Errorf("%s of %q: expected %d flags, got %d", test.constituentID, test.input, test.expected, len(root.Constituents))
+		}
+	}
+	// too few gives back what was found so another alternative can match from the same place
+	g = newGrammar("coordinates", map[string]dialects.PartDefinition{
+		"coordinates": {Constituents: [][]string{{"coordinate{3}"}, {"coordinate{2}", "rest"}}},
+		"coordinate":  {Regex: `[0-9]`},
+		"rest":        {Regex: `[a-z]+`},
+	})
+	root, err, _ := dialects.ParseToTree(g, "12z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(root.Constituents) != 3 || root.Constituents[2].Name != "rest" {
+		t.Errorf("expected two coordinates and the rest, got %s", root)
+	}
+}
+
+// items returns a grammar of bracketed lists of words separated by commas, using the list constituent
+func items(list string) grammar {
+	return newGrammar("list", map[string]dialects.PartDefinition{
+		"list":  {Constituents: [][]string{{"open", list, "close"}}},
+		"open":  {Literal: "["},
+		"close": {Literal: "]"},
+		"item":  {Regex: `[a-z]+`},
+		"comma": {Regex: `, *`},
+	})
+}
+
+func TestSeparatedLists(t *testing.T) {
+	tests := []struct {
+		list     string
+		input    string
+		expected string
+	}{
+		{"item%comma*", "[]", "open close"},
+		{"item%comma*", "[a]", "open item close"},
+		{"item%comma*", "[a, b,c]", "open item item item close"},
+		{"item%comma+", "[a, b]", "open item item close"},
+		{"item%%comma*", "[a, b,]", "open item item close"},
+		{"item%%comma*", "[a, b]", "open item item close"},
+	}
+	for _, test := range tests {
+		root, err, _ := dialects.ParseToTree(items(test.list), test.input)
+		if err != nil {
+			t.Errorf("%s of %q: %v", test.list, test.input, err)
+			continue
+		}
+		var names []string
+		for _, part := range root.Constituents {
+			names = append(names, part.Name)
+		}
+		if got := strings.Join(names, " "); got != test.expected {
+			t.Errorf("%s of %q: expected %q, got %q", test.list, test.input, test.expected, got)
+		}
+	}
+	failures := []struct {
+		list  string
+		input string
+	}{
+		{"item%comma+", "[]"},
+		{"item%comma*", "[a, b,]"},
+		{"item%comma*", "[,]"},
+	}
+	for _, test := range failures {
+		if _, err, _ := dialects.Parse(items(test.list), test.input); err == nil {
+			t.Errorf("%s of %q: expected the parse to fail", test.list, test.input)
+		}
+	}
+}
+
+func TestSeparatedListsKeepIgnored(t *testing.T) {
+	root, err, _ := dialects.ParseToTreeWithOptions(items("item%%comma*"), "[a, b,]", dialects.Options{KeepIgnored: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var values []string
+	for _, part := range root.Constituents {
+		values = append(values, part.Value)
+	}
+	if got := strings.Join(values, ""); got != "[a, b,]" {
+		t.Errorf("expected the separators to be kept, got %q", got)
+	}
+}
+
+// functions returns a grammar of function declarations or word statements, with the sequence given for declarations
+func functions(declaration []string) grammar {
+	return newGrammar("statement", map[string]dialects.PartDefinition{
+		"statement": {Constituents: [][]string{declaration, {"words", "semicolon"}}},
+		"funcKw":    {Keyword: "func"},
+		"name":      {Regex: `[a-z]+`},
+		"params":    {Literal: "()"},
+		"words":     {Regex: `[a-z ]+`},
+		"semicolon": {Literal: ";"},
+		"ws":        {Regex: `[ ]+`, Ignore: true},
+	})
+}
+
+func TestCut(t *testing.T) {
+	// without a cut, the words alternative gets farther, so its missing semicolon is reported
+	_, err, _ := dialects.Parse(functions([]string{"funcKw", "ws", "name", "params"}), "func foo bar")
+	var parseError *dialects.ParseError
+	if !errors.As(err, &parseError) || parseError.Offset != 12 {
+		t.Fatalf("expected the semicolon to be missing at offset 12, got %v", err)
+	}
+	// with a cut after the keyword, the declaration's own failure is reported
+	g := functions([]string{"funcKw", "^", "ws", "name", "params"})
+	_, err, _ = dialects.Parse(g, "func foo bar")
+	if !errors.As(err, &parseError) || parseError.Offset != 8 || !strings.Contains(err.Error(), "expected '()'") {
+		t.Fatalf("expected the params to be missing at offset 8, got %v", err)
+	}
+	// the later alternatives are still tried when the cut isn't reached
+	for _, input := range []string{"func foo()", "fun foo;"} {
+		if _, err, _ := dialects.Parse(g, input); err != nil {
+			t.Errorf("%q: %v", input, err)
+		}
+	}
+}
+
+func TestEOFParts(t *testing.T) {
+	g := newGrammar("program", map[string]dialects.PartDefinition{
+		"program":   {Constituents: [][]string{{"statement*", "eof"}}},
+		"statement": {Constituents: [][]string{{"word", "ws?"}}},
+		"word":      {Regex: `[a-z]+`},
+		"ws":        {Regex: `[ ]+`, Ignore: true},
+		"eof":       {EOF: true},
+	})
+	// the grammar itself requires the end of input, even when the dialect allows trailing input
+	g.dialect.AllowTrailing = true
+	root, err, _ := dialects.ParseToTree(g, "ab cd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(root.Constituents) != 2 {
+		t.Errorf("expected the end of input to be Ignored, got %s", root)
+	}
+	root, err, _ = dialects.ParseToTreeWithOptions(g, "ab cd", dialects.Options{KeepIgnored: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if eof := root.Constituents[len(root.Constituents)-1]; eof.Name != "eof" || !eof.Ignore || eof.StartPos != 5 || eof.EndPos != 5 {
+		t.Errorf("expected an Ignored end of input at offset 5, got %s", eof)
+	}
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"ab cd 12", "unexpected trailing input '12' at line 1, column 7 (offset 6)"},
+		{"ab 123456789012345678901234\nef", "unexpected trailing input '12345678901234567890...' at line 1, column 4 (offset 3)"},
+	}
+	for _, test := range tests {
+		_, err, _ := dialects.Parse(g, test.input)
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("%q: expected %q, got %v", test.input, test.expected, err)
+		}
+	}
+}
+
+func TestEOFPartsInAlternatives(t *testing.T) {
+	// an alternative ending at the end of input doesn't stop a later one from matching mid-input
+	g := newGrammar("program", map[string]dialects.PartDefinition{
+		"program":   {Constituents: [][]string{{"section+"}}},
+		"section":   {Constituents: [][]string{{"word", "eof"}, {"word", "semicolon"}}},
+		"word":      {Regex: `[a-z]+`},
+		"eof":       {EOF: true},
+		"semicolon": {Literal: ";"},
+	})
+	root, err, _ := dialects.ParseToTree(g, "ab;cd;ef")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(root.Constituents) != 3 {
+		t.Errorf("expected three sections, got %s", root)
+	}
+}
+
+// assignments returns a grammar of assignments that skips whitespace and comments rather than defining parts for them
+func assignments() grammar {
+	g := newGrammar("program", map[string]dialects.PartDefinition{
+		"program":    {Constituents: [][]string{{"assignment*"}}},
+		"assignment": {Constituents: [][]string{{"name", "equals", "value", "semicolon"}}},
+		"name":       {Regex: `[a-z]+`},
+		"equals":     {Literal: "="},
+		"value":      {Constituents: [][]string{{"number"}, {"string"}}},
+		"number":     {Regex: `[0-9]+`},
+		"string":     {Constituents: [][]string{{"quote", "chars", "quote"}}, NoSkip: true},
+		"quote":      {Literal: `"`},
+		"chars":      {Regex: `[^"]*`},
+		"semicolon":  {Literal: ";"},
+	})
+	g.dialect.SkipPattern = `(?:[ \t\n]+|#[^\n]*)+`
+	return g
+}
+
+func TestSkipPattern(t *testing.T) {
+	input := "  a = 1 ;\n# comment\nb= \" x y \";\n"
+	root, err, _ := dialects.ParseToTree(assignments(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(root.Constituents) != 2 {
+		t.Fatalf("expected two assignments, got %s", root)
+	}
+	// the skipped text is left out of the tree, while parts start at their own text
+	if name := root.Constituents[1].Constituents[0]; name.Value != "b" || name.StartPos != 20 {
+		t.Errorf("expected the name b at offset 20, got %q at %d", name.Value, name.StartPos)
+	}
+	// nothing is skipped within the NoSkip string
+	if chars := root.First("chars"); chars == nil || chars.Value != " x y " {
+		t.Errorf("expected the string's spaces to be kept, got %v", chars)
+	}
+	root, err, _ = dialects.ParseToTreeWithOptions(assignments(), input, dialects.Options{KeepIgnored: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if leafValues(root) != input {
+		t.Errorf("expected the skipped text to be kept, got %q", leafValues(root))
+	}
+	if skipped := root.Constituents[0]; skipped.Name != dialects.SkippedPartName || !skipped.Ignore || skipped.Value != "  " {
+		t.Errorf("expected the leading spaces as an Ignored part, got %s", skipped)
+	}
+	// failures are reported where the part was expected, after the skipped text
+	_, err, _ = dialects.Parse(assignments(), "a = ;")
+	var parseError *dialects.ParseError
+	if !errors.As(err, &parseError) || parseError.Offset != 4 {
+		t.Errorf("expected the value to be missing at offset 4, got %v", err)
+	}
+}
+
+// commentedAssignments returns the grammar of assignments skipping C-style comments rather than # comments
+func commentedAssignments() grammar {
+	g := assignments()
+	g.dialect.SkipPattern = `[ \t\n]+`
+	g.dialect.LineComment = "//"
+	g.dialect.BlockComment = [2]string{"/*", "*/"}
+	return g
+}
+
+func TestComments(t *testing.T) {
+	input := "a = 1; // one\n/* two\nlines */ b = \"/* kept */\"; // three"
+	root, err, _ := dialects.ParseToTree(commentedAssignments(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the block comment's lines are counted
+	if name := root.Constituents[1].Constituents[0]; name.Value != "b" || name.StartLine != 3 {
+		t.Errorf("expected the name b on line 3, got %q on line %d", name.Value, name.StartLine)
+	}
+	if chars := root.First("chars"); chars == nil || chars.Value != "/* kept */" {
+		t.Errorf("expected the comment within the string to be kept, got %v", chars)
+	}
+	root, err, _ = dialects.ParseToTreeWithOptions(commentedAssignments(), input, dialects.Options{KeepIgnored: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var comments []string
+	for _, comment := range root.FindAll(dialects.CommentPartName) {
+		comments = append(comments, comment.Value)
+	}
+	if got := strings.Join(comments, "|"); got != "// one|/* two\nlines */|// three" {
+		t.Errorf("expected the comments as Ignored parts, got %q", got)
+	}
+	if leafValues(root) != input {
+		t.Errorf("expected the leaves to round trip %q, got %q", input, leafValues(root))
+	}
+}
+
+func TestUnterminatedComment(t *testing.T) {
+	_, err, _ := dialects.Parse(commentedAssignments(), "a = 1;\n  /* oops\nb = 2;")
+	var parseError *dialects.ParseError
+	if !errors.As(err, &parseError) || parseError.Offset != 9 || !strings.Contains(err.Error(), "unterminated comment starting on line 2") {
+		t.Errorf("expected the unterminated comment to be reported at offset 9, got %v", err)
+	}
+}
+
+// matchBraces matches a block of balanced braces, whose value is the text within the outer braces
+func matchBraces(input string, pos int) (length int, value string, ok bool) {
+	depth := 0
+	for i := pos; i < len(input); i++ {
+		switch {
+		case input[i] == '{':
+			depth++
+		case input[i] == '}':
+			depth--
+		case depth == 0:
+			return 0, "", false
+		}
+		if depth == 0 {
+			return i + 1 - pos, input[pos+1 : i], true
+		}
+	}
+	return 0, "", false
+}
+
+// matchHeredoc matches a heredoc ended by the terminator named after its <<, whose value is the text in between
+func matchHeredoc(input string, pos int) (length int, value string, ok bool) {
+	rest, found := strings.CutPrefix(input[pos:], "<<")
+	if !found {
+		return 0, "", false
+	}
+	terminator, body, found := strings.Cut(rest, "\n")
+	if !found || terminator == "" {
+		return 0, "", false
+	}
+	end := strings.Index(body, "\n"+terminator)
+	if end < 0 {
+		return 0, "", false
+	}
+	return len(input[pos:]) - len(body) + end + 1 + len(terminator), body[:end], true
+}
+
+func TestMatchParts(t *testing.T) {
+	g := newGrammar("program", map[string]dialects.PartDefinition{
+		"program": {Constituents: [][]string{{"item+"}}},
+		"item":    {Constituents: [][]string{{"block"}, {"heredoc"}, {"ws"}}},
+		"block":   {Match: matchBraces},
+		"heredoc": {Match: matchHeredoc, FormatMatch: func(matches []string) string { return strings.ToUpper(matches[0]) }},
+		"ws":      {Regex: `[ \n]+`, Ignore: true},
+	})
+	root, err, _ := dialects.ParseToTree(g, "{a {b} c} <<EOT\nline one\nline two\nEOT {}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks := root.FindAll("block")
+	if len(blocks) != 2 || blocks[0].Value != "a {b} c" || blocks[1].Value != "" {
+		t.Errorf("expected the text within each block, got %v", blocks)
+	}
+	// the heredoc's value is formatted, and its lines are counted
+	if heredoc := root.First("heredoc"); heredoc == nil || heredoc.Value != "LINE ONE\nLINE TWO" || heredoc.EndLine != 4 {
+		t.Errorf("expected the formatted heredoc ending on line 4, got %v", heredoc)
+	}
+	if block := blocks[1]; block.StartLine != 4 || block.StartCol != 5 {
+		t.Errorf("expected the last block at line 4, column 5, got line %d, column %d", block.StartLine, block.StartCol)
+	}
+	_, err, _ = dialects.Parse(g, "{a {b}")
+	var parseError *dialects.ParseError
+	if !errors.As(err, &parseError) || parseError.Offset != 0 {
+		t.Errorf("expected the unbalanced block to fail at offset 0, got %v", err)
+	}
+}
+
+func TestMatchPartValidation(t *testing.T) {
+	g := newGrammar("block", map[string]dialects.PartDefinition{
+		"block": {Match: matchBraces, ValidateMatch: func(matches []string) (bool, string) {
+			return matches[0] != "", "empty block"
+		}},
+	})
+	if _, err, _ := dialects.Parse(g, "{}"); err == nil || !strings.Contains(err.Error(), "invalid block: empty block") {
+		t.Errorf("expected the empty block to be rejected, got %v", err)
+	}
+	// a match function consuming more than the input is reported rather than panicking
+	g = newGrammar("block", map[string]dialects.PartDefinition{
+		"block": {Match: func(input string, pos int) (int, string, bool) { return len(input) + 1, "", true }},
+	})
+	if _, err, _ := dialects.Parse(g, "{}"); err == nil || !strings.Contains(err.Error(), "match function of block returned an invalid length (3)") {
+		t.Errorf("expected the invalid length to be reported, got %v", err)
+	}
+}
+
+// sections returns a grammar of named sections that must end with the name they began with
+func sections() grammar {
+	g := newGrammar("section", map[string]dialects.PartDefinition{
+		"section": {Constituents: [][]string{{"begin", "name", "body*", "end", "=name"}}},
+		"begin":   {Keyword: "begin"},
+		"end":     {Keyword: "end"},
+		"name":    {Regex: `[a-z]+`},
+		"body":    {Constituents: [][]string{{"!end", "word"}}},
+		"word":    {Regex: `[0-9a-z]+`},
+	})
+	g.dialect.SkipPattern = `[ \n]+`
+	return g
+}
+
+func TestBackreferences(t *testing.T) {
+	root, err, _ := dialects.ParseToTree(sections(), "begin foo\n1 2\nend foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := root.FindAll("name")
+	if len(names) != 2 || names[1].Value != "foo" || names[1].StartLine != 3 {
+		t.Errorf("expected the closing name on line 3, got %v", names)
+	}
+	_, err, _ = dialects.Parse(sections(), "begin foo\n1 2\nend bar")
+	var parseError *dialects.ParseError
+	if !errors.As(err, &parseError) || parseError.Offset != 18 || parseError.Message != "expected 'foo' to match opening 'foo' on line 1, found 'bar'" {
+		t.Errorf("expected the mismatched name to be reported at offset 18, got %v", err)
+	}
+	_, err, _ = dialects.Parse(sections(), "begin foo\n1 2\nend")
+	if !errors.As(err, &parseError) || parseError.Message != "expected 'foo' to match opening 'foo' on line 1, found the end of the input" {
+		t.Errorf("expected the missing name to be reported, got %v", err)
+	}
+	// the repeated text is handled and streamed like any other part
+	g := sections()
+	g.dialect.PartDefinitions["name"] = dialects.PartDefinition{Regex: `[a-z]+`, Handler: func(part *dialects.Part, model interface{}) bool {
+		*model.(*[]string) = append(*model.(*[]string), part.Value)
+		return part.Value != "baz" || part.StartLine == 1
+	}}
+	compiled, err := dialects.Compile(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var streamed []string
+	output, err, _ := compiled.ParseWithOptions("begin foo\n1 2\nend foo", dialects.Options{OnPart: map[string]func(p *dialects.Part){
+		"name": func(p *dialects.Part) { streamed = append(streamed, strconv.Itoa(p.StartLine)+":"+p.Value) },
+	}})
+	if err != nil || output != "foo,foo" || strings.Join(streamed, ",") != "1:foo,3:foo" {
+		t.Errorf("expected the Handler and OnPart to receive both names, got %q, %v, and %v", output, streamed, err)
+	}
+	// a Handler rejecting the repeated text fails the sequence, though it accepted the opening text
+	if _, err, _ := dialects.Parse(g, "begin baz\nend baz"); err == nil {
+		t.Error("expected the rejected name to fail the parse")
+	}
+	// the opening text can be an Ignored part, like the delimiter of a heredoc, which is left out of the tree
+	g = sections()
+	g.dialect.PartDefinitions["name"] = dialects.PartDefinition{Regex: `[a-z]+`, Ignore: true}
+	root, err, _ = dialects.ParseToTree(g, "begin foo\n1 2\nend foo")
+	if err != nil || len(root.FindAll("name")) != 0 || len(root.FindAll("word")) != 2 {
+		t.Errorf("expected the Ignored names to match and be left out of the tree, got %v", err)
+	}
+	_, err, _ = dialects.Parse(g, "begin foo\nend bar")
+	if !errors.As(err, &parseError) || parseError.Message != "expected 'foo' to match opening 'foo' on line 1, found 'bar'" {
+		t.Errorf("expected the mismatched Ignored name to be reported, got %v", err)
+	}
+}
+
+func TestParseContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// the context is checked every so often, so the input needs enough parts to reach a check
+	_, err, _ := dialects.ParseContext(ctx, wordList(), strings.Repeat("ab ", 2000))
+	var parseError *dialects.ParseError
+	if !errors.As(err, &parseError) || !errors.Is(err, context.Canceled) || parseError.Offset == 0 || !strings.Contains(parseError.Message, "parse stopped: context canceled") {
+		t.Errorf("expected the parse to stop partway with the context's error, got %v", err)
+	}
+	if _, err, _ := dialects.ParseContext(context.Background(), wordList(), strings.Repeat("ab ", 2000)); err != nil {
+		t.Errorf("expected the parse to succeed, got %v", err)
+	}
+}
+
+func TestParseContextDeadline(t *testing.T) {
+	compiled, err := dialects.Compile(prefixHeavy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	// without memoization, the nested alternatives take exponential time, so only the deadline ends the parse
+	start := time.Now()
+	_, err, _ = compiled.ParseContext(ctx, nested(40), dialects.Options{NoTrace: true})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the deadline to stop the parse, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the parse to stop soon after the deadline, took %v", elapsed)
+	}
+}
+
+// annotated provides a normalized grammar whose output lists each item with its line, sliced from the input
+type annotated struct {
+	normalized
+}
+
+func (annotated) GenerateOutputWithTree(model interface{}, root *dialects.Part, input string) (string, error) {
+	var items []string
+	for _, item := range root.FindAll("word") {
+		items = append(items, strconv.Itoa(item.StartLine)+":"+input[item.StartPos:item.EndPos])
+	}
+	return strings.Join(items, ","), nil
+}
+
+func TestGenerateOutputWithTree(t *testing.T) {
+	// the positions are in the input as given, before its BOM and carriage returns were removed
+	output, err, _ := dialects.Parse(annotated{normalized{wordList()}}, "\ufeffab\r\ncd ef\r\n")
+	if err != nil || output != "1:ab,2:cd,2:ef" {
+		t.Errorf("expected the words with their lines, got %q and %v", output, err)
+	}
+}
@@ -0,0 +1,315 @@
+// Package dialectsgen generates Go code binding the parse tree of a dialect to typed AST structs mirroring its
+// grammar, so transformations work with fields rather than walking Part.Constituents by index
+package dialectsgen
+
+import (
+	"errors"
+	"go/format"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/AdamJonR/dialects"
+)
+
+// Config provides the Package the generated code belongs to, the TypeSuffix added to the names of the AST structs,
+// which is "AST" when empty, and the name of the BindName function binding the root part, which is "Bind" when empty,
+// so the code for more than one dialect can share a package
+type Config struct {
+	Package    string
+	TypeSuffix string
+	BindName   string
+}
+
+// field provides a field of an AST struct, holding the parts of the constituent named Part
+type field struct {
+	part string
+	name string
+	// composite reports whether the part has a struct of its own, rather than being held by its Value
+	composite bool
+	slice     bool
+}
+
+// generator provides the dialect being generated for, with the struct type of each composite part it reaches, in the
+// order they're reached
+type generator struct {
+	dialect *dialects.Dialect
+	config  Config
+	types   map[string]string
+	order   []string
+}
+
+// Generate returns the gofmt-ed source of a file in the package holding an AST struct for each part with constituents
+// or an expression that the root part reaches, named after the part with the TypeSuffix, as in PairAST, along with
+// the BindName function, which returns the struct for the root part of a parse tree, as in
+// Bind(root *dialects.Part) (*PairsAST, error); each struct holds its Part and a field for each part its constituents
+// name, other than Ignored parts, lookaheads, and the parts LoadGrammar defines for literals, holding the Value of a
+// part matching text, such as a regex, or a pointer to the struct of a composite part, in a slice when the part can
+// appear more than once, while the struct of an expression holds either a lone Operand or an Operator between its Left
+// and Right operands, and the grammar's problems, as found by ValidateDialect, are returned joined into one error
+func Generate(d *dialects.Dialect, config Config) ([]byte, error) {
+	if errs := dialects.ValidateDialect(d); len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	if config.TypeSuffix == "" {
+		config.TypeSuffix = "AST"
+	}
+	if config.BindName == "" {
+		config.BindName = "Bind"
+	}
+	g := &generator{dialect: d, config: config, types: make(map[string]string)}
+	if !composite(d.PartDefinitions[d.RootName]) {
+		return nil, &dialects.DialectError{PartName: d.RootName, Message: "is the root part but has no constituents or expression to generate a struct for"}
+	}
+	if err := g.addType(d.RootName); err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	b.WriteString("// Code generated by dialectsgen. DO NOT EDIT.\n\n")
+	b.WriteString("package " + config.Package + "\n\n")
+	b.WriteString("import (\n\t\"fmt\"\n\n\t\"github.com/AdamJonR/dialects\"\n)\n")
+	// add the types of the parts each struct refers to in turn, so every struct is reached from the root
+	for i := 0; i < len(g.order); i++ {
+		name := g.order[i]
+		fields, skipped, err := g.fields(name)
+		if err != nil {
+			return nil, err
+		}
+		g.writeStruct(&b, name, fields)
+		if _, ok := dialects.ExpressionOperand(d.PartDefinitions[name]); ok {
+			g.writeExpressionBinder(&b, name, fields[0])
+		} else {
+			g.writeBinder(&b, name, fields, skipped)
+		}
+	}
+	rootType := g.types[d.RootName]
+	b.WriteString("\n// " + config.BindName + " returns part (" + d.RootName + ") and its descendants as a " + rootType + "\n")
+	b.WriteString("func " + config.BindName + "(root *dialects.Part) (*" + rootType + ", error) {\n")
+	b.WriteString("\tif root.Name != " + strconv.Quote(d.RootName) + " {\n")
+	b.WriteString("\t\treturn nil, fmt.Errorf(\"expected part (" + d.RootName + "), found part (%s)\", root.Name)\n\t}\n")
+	b.WriteString("\treturn bind" + rootType + "(root)\n}\n")
+	return format.Source([]byte(b.String()))
+}
+
+// WriteFile writes the code Generate returns to the file at the path, which is how a go:generate directive typically
+// runs it, through a small program that builds the dialect
+func WriteFile(path string, d *dialects.Dialect, config Config) error {
+	source, err := Generate(d, config)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, source, 0644)
+}
+
+// composite reports whether the part gets a struct of its own
+func composite(partDefinition dialects.PartDefinition) bool {
+	_, expression := dialects.ExpressionOperand(partDefinition)
+	return len(partDefinition.Constituents) > 0 || expression
+}
+
+// addType names the struct of the composite part, unless it already has one, returning a DialectError when the name is
+// taken by another part's struct or the part's name can't be made into one
+func (g *generator) addType(name string) error {
+	if _, ok := g.types[name]; ok {
+		return nil
+	}
+	exported := exportedName(name)
+	if exported == "" {
+		return &dialects.DialectError{PartName: name, Message: "has a name that can't be made into the name of a struct"}
+	}
+	typeName := exported + g.config.TypeSuffix
+	for other, otherType := range g.types {
+		if otherType == typeName {
+			return &dialects.DialectError{PartName: name, Message: "would have the same struct name as part (" + other + "), " + typeName}
+		}
+	}
+	g.types[name] = typeName
+	g.order = append(g.order, name)
+	return nil
+}
+
+// fields returns the fields of the struct of the composite part in the order its constituents first name their parts,
+// adding the types of the composite parts they hold, along with the names of the parts the tree can hold that are left
+// out of the struct
+func (g *generator) fields(name string) (fields []field, skipped []string, err error) {
+	partDefinition := g.dialect.PartDefinitions[name]
+	if operand, ok := dialects.ExpressionOperand(partDefinition); ok {
+		operandField := field{part: operand, name: "Operand", composite: composite(g.dialect.PartDefinitions[operand])}
+		if operandField.composite {
+			if err := g.addType(operand); err != nil {
+				return nil, nil, err
+			}
+		}
+		return []field{operandField}, nil, nil
+	}
+	indexes := make(map[string]int)
+	names := map[string]string{"Part": ""}
+	// separators and parts whose names can't be fields are left out of the struct, unless they're fields elsewhere
+	var leftOut []string
+	for _, constituentSeq := range partDefinition.Constituents {
+		counts := make(map[string]int)
+		for _, constituentID := range constituentSeq {
+			reference := dialects.ParseConstituent(constituentID)
+			if reference.Separator != "" {
+				leftOut = append(leftOut, reference.Separator)
+			}
+			referenced := g.dialect.PartDefinitions[reference.Name]
+			if !reference.Consumes() || referenced.Ignore || referenced.EOF {
+				continue
+			}
+			counts[reference.Name]++
+			if i, ok := indexes[reference.Name]; ok {
+				fields[i].slice = fields[i].slice || reference.Repeats() || counts[reference.Name] > 1
+				continue
+			}
+			fieldName := exportedName(reference.Name)
+			if fieldName == "" {
+				leftOut = append(leftOut, reference.Name)
+				continue
+			}
+			if other, ok := names[fieldName]; ok {
+				message := "has constituents whose field names clash, as " + fieldName + " is the field for part (" + reference.Name + ")"
+				if other != "" {
+					message += " and part (" + other + ")"
+				}
+				return nil, nil, &dialects.DialectError{PartName: name, Message: message}
+			}
+			names[fieldName] = reference.Name
+			indexes[reference.Name] = len(fields)
+			fields = append(fields, field{part: reference.Name, name: fieldName, composite: composite(referenced), slice: reference.Repeats()})
+			if composite(referenced) {
+				if err := g.addType(reference.Name); err != nil {
+					return nil, nil, err
+				}
+			}
+		}
+	}
+	seen := make(map[string]bool)
+	for _, part := range leftOut {
+		if _, ok := indexes[part]; !ok && !seen[part] {
+			seen[part] = true
+			skipped = append(skipped, part)
+		}
+	}
+	return fields, skipped, nil
+}
+
+// fieldType returns the Go type of the field
+func (g *generator) fieldType(f field) string {
+	typeName := "string"
+	if f.composite {
+		typeName = "*" + g.types[f.part]
+	}
+	if f.slice {
+		return "[]" + typeName
+	}
+	return typeName
+}
+
+// writeStruct writes the AST struct of the part
+func (g *generator) writeStruct(b *strings.Builder, name string, fields []field) {
+	typeName := g.types[name]
+	if _, ok := dialects.ExpressionOperand(g.dialect.PartDefinitions[name]); ok {
+		b.WriteString("\n// " + typeName + " holds the expression of part (" + name + "), which is either a lone Operand or an Operator between its\n// Left and Right operands\n")
+		b.WriteString("type " + typeName + " struct {\n\tPart *dialects.Part\n")
+		b.WriteString("\tOperand " + g.fieldType(fields[0]) + "\n\tOperator string\n")
+		b.WriteString("\tLeft *" + typeName + "\n\tRight *" + typeName + "\n}\n")
+		return
+	}
+	b.WriteString("\n// " + typeName + " holds the constituents of part (" + name + ")\n")
+	b.WriteString("type " + typeName + " struct {\n\tPart *dialects.Part\n")
+	for _, f := range fields {
+		b.WriteString("\t" + f.name + " " + g.fieldType(f) + "\n")
+	}
+	b.WriteString("}\n")
+}
+
+// writeBinder writes the function binding a part with constituents to its struct
+func (g *generator) writeBinder(b *strings.Builder, name string, fields []field, skipped []string) {
+	typeName := g.types[name]
+	b.WriteString("\nfunc bind" + typeName + "(part *dialects.Part) (*" + typeName + ", error) {\n")
+	b.WriteString("\tast := &" + typeName + "{Part: part}\n")
+	b.WriteString("\tfor _, constituent := range part.Constituents {\n\t\tswitch constituent.Name {\n")
+	for _, f := range fields {
+		b.WriteString("\t\tcase " + strconv.Quote(f.part) + ":\n")
+		value := "constituent.Value"
+		if f.composite {
+			b.WriteString("\t\t\tbound, err := bind" + g.types[f.part] + "(constituent)\n")
+			b.WriteString("\t\t\tif err != nil {\n\t\t\t\treturn nil, err\n\t\t\t}\n")
+			value = "bound"
+		}
+		if f.slice {
+			b.WriteString("\t\t\tast." + f.name + " = append(ast." + f.name + ", " + value + ")\n")
+		} else {
+			b.WriteString("\t\t\tast." + f.name + " = " + value + "\n")
+		}
+	}
+	if len(skipped) > 0 {
+		quoted := make([]string, len(skipped))
+		for i, part := range skipped {
+			quoted[i] = strconv.Quote(part)
+		}
+		b.WriteString("\t\tcase " + strings.Join(quoted, ", ") + ":\n\t\t\t// left out of the struct\n")
+	}
+	writeUnexpected(b)
+	b.WriteString("\t\t}\n\t}\n\treturn ast, nil\n}\n")
+}
+
+// writeExpressionBinder writes the function binding an expression part, and the operations nested within it, to its
+// struct
+func (g *generator) writeExpressionBinder(b *strings.Builder, name string, operand field) {
+	typeName := g.types[name]
+	b.WriteString("\nfunc bind" + typeName + "(part *dialects.Part) (*" + typeName + ", error) {\n")
+	b.WriteString("\tast := &" + typeName + "{Part: part}\n")
+	b.WriteString("\tfor _, constituent := range part.Constituents {\n\t\tvar operand *" + typeName + "\n\t\tswitch constituent.Name {\n")
+	b.WriteString("\t\tcase dialects.OperatorPartName:\n\t\t\tast.Operator = constituent.Value\n\t\t\tcontinue\n")
+	b.WriteString("\t\tcase " + strconv.Quote(name) + ":\n")
+	b.WriteString("\t\t\tbound, err := bind" + typeName + "(constituent)\n")
+	b.WriteString("\t\t\tif err != nil {\n\t\t\t\treturn nil, err\n\t\t\t}\n\t\t\toperand = bound\n")
+	b.WriteString("\t\tcase " + strconv.Quote(operand.part) + ":\n")
+	value := "constituent.Value"
+	if operand.composite {
+		b.WriteString("\t\t\tbound, err := bind" + g.types[operand.part] + "(constituent)\n")
+		b.WriteString("\t\t\tif err != nil {\n\t\t\t\treturn nil, err\n\t\t\t}\n")
+		value = "bound"
+	}
+	b.WriteString("\t\t\toperand = &" + typeName + "{Part: constituent, Operand: " + value + "}\n")
+	writeUnexpected(b)
+	b.WriteString("\t\t}\n")
+	b.WriteString("\t\tif ast.Left == nil {\n\t\t\tast.Left = operand\n\t\t} else {\n\t\t\tast.Right = operand\n\t\t}\n\t}\n")
+	b.WriteString("\t// a lone operand is the expression itself\n")
+	b.WriteString("\tif ast.Right == nil && ast.Left != nil {\n\t\tast.Operand = ast.Left.Operand\n\t\tast.Left = nil\n\t}\n")
+	b.WriteString("\treturn ast, nil\n}\n")
+}
+
+// writeUnexpected writes the case of a binder's switch skipping Ignored parts, such as the text skipped with
+// KeepIgnored, and reporting any other part, which the grammar the code was generated from doesn't have there
+func writeUnexpected(b *strings.Builder) {
+	b.WriteString("\t\tdefault:\n\t\t\tif constituent.Ignore {\n\t\t\t\tcontinue\n\t\t\t}\n")
+	b.WriteString("\t\t\treturn nil, fmt.Errorf(\"line %d: unexpected part (%s) in part (%s)\", constituent.StartLine, constituent.Name, part.Name)\n")
+}
+
+// exportedName returns the part name as an exported Go name, dropping the characters that can't be in one and
+// capitalizing the letter after each, as in "query.if_stmt" to QueryIfStmt, or an empty string if that doesn't start
+// with an upper case letter
+func exportedName(name string) string {
+	var b strings.Builder
+	capitalize := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			capitalize = true
+			continue
+		}
+		if capitalize {
+			r = unicode.ToUpper(r)
+			capitalize = false
+		}
+		b.WriteRune(r)
+	}
+	if first, _ := utf8.DecodeRuneInString(b.String()); !unicode.IsUpper(first) {
+		return ""
+	}
+	return b.String()
+}
@@ -0,0 +1,70 @@
+package dialectsgen_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AdamJonR/dialects"
+	"github.com/AdamJonR/dialects/dialectsgen"
+	"github.com/AdamJonR/dialects/dialectsgen/internal/calc"
+)
+
+func TestGenerate(t *testing.T) {
+	// the calc package holds the generated code, so this keeps it current
+	source, err := dialectsgen.Generate(calc.Calc{}.NewDialect(), dialectsgen.Config{Package: "calc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := os.ReadFile(filepath.Join("internal", "calc", "ast.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(source, expected) {
+		t.Errorf("generated code doesn't match internal/calc/ast.go, so run go generate there:\n%s", source)
+	}
+	// the suffix and function name can be changed
+	source, err = dialectsgen.Generate(calc.Calc{}.NewDialect(), dialectsgen.Config{Package: "calc", TypeSuffix: "Node", BindName: "BindCalc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, text := range []string{"type ProgramNode struct", "func BindCalc(root *dialects.Part) (*ProgramNode, error)"} {
+		if !bytes.Contains(source, []byte(text)) {
+			t.Errorf("expected the generated code to contain %q:\n%s", text, source)
+		}
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := []struct {
+		name            string
+		partDefinitions map[string]dialects.PartDefinition
+		expected        string
+	}{
+		{"undefined part", map[string]dialects.PartDefinition{
+			"root": {Constituents: [][]string{{"missing"}}},
+		}, "part (root) references undefined part (missing)"},
+		{"terminal root", map[string]dialects.PartDefinition{
+			"root": {Regex: `[a-z]+`},
+		}, "part (root) is the root part but has no constituents"},
+		{"clashing structs", map[string]dialects.PartDefinition{
+			"root":   {Constituents: [][]string{{"first", "aList"}}},
+			"first":  {Constituents: [][]string{{"a.list"}}},
+			"a.list": {Constituents: [][]string{{"word"}}},
+			"aList":  {Constituents: [][]string{{"word"}}},
+			"word":   {Regex: `[a-z]+`},
+		}, "part (a.list) would have the same struct name as part (aList), AListAST"},
+		{"clashing fields", map[string]dialects.PartDefinition{
+			"root": {Constituents: [][]string{{"part"}}},
+			"part": {Regex: `[a-z]+`},
+		}, "part (root) has constituents whose field names clash, as Part is the field for part (part)"},
+	}
+	for _, test := range tests {
+		d := &dialects.Dialect{RootName: "root", PartDefinitions: test.partDefinitions}
+		if _, err := dialectsgen.Generate(d, dialectsgen.Config{Package: "test"}); err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("%s: expected an error containing %q, got %v", test.name, test.expected, err)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+// Code generated by dialectsgen. DO NOT EDIT.
+
+package calc
+
+import (
+	"fmt"
+
+	"github.com/AdamJonR/dialects"
+)
+
+// ProgramAST holds the constituents of part (program)
+type ProgramAST struct {
+	Part      *dialects.Part
+	Statement []*StatementAST
+}
+
+func bindProgramAST(part *dialects.Part) (*ProgramAST, error) {
+	ast := &ProgramAST{Part: part}
+	for _, constituent := range part.Constituents {
+		switch constituent.Name {
+		case "statement":
+			bound, err := bindStatementAST(constituent)
+			if err != nil {
+				return nil, err
+			}
+			ast.Statement = append(ast.Statement, bound)
+		case "newline":
+			// left out of the struct
+		default:
+			if constituent.Ignore {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: unexpected part (%s) in part (%s)", constituent.StartLine, constituent.Name, part.Name)
+		}
+	}
+	return ast, nil
+}
+
+// StatementAST holds the constituents of part (statement)
+type StatementAST struct {
+	Part       *dialects.Part
+	Assignment *AssignmentAST
+	Print      *PrintAST
+}
+
+func bindStatementAST(part *dialects.Part) (*StatementAST, error) {
+	ast := &StatementAST{Part: part}
+	for _, constituent := range part.Constituents {
+		switch constituent.Name {
+		case "assignment":
+			bound, err := bindAssignmentAST(constituent)
+			if err != nil {
+				return nil, err
+			}
+			ast.Assignment = bound
+		case "print":
+			bound, err := bindPrintAST(constituent)
+			if err != nil {
+				return nil, err
+			}
+			ast.Print = bound
+		default:
+			if constituent.Ignore {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: unexpected part (%s) in part (%s)", constituent.StartLine, constituent.Name, part.Name)
+		}
+	}
+	return ast, nil
+}
+
+// AssignmentAST holds the constituents of part (assignment)
+type AssignmentAST struct {
+	Part *dialects.Part
+	Name string
+	Expr *ExprAST
+}
+
+func bindAssignmentAST(part *dialects.Part) (*AssignmentAST, error) {
+	ast := &AssignmentAST{Part: part}
+	for _, constituent := range part.Constituents {
+		switch constituent.Name {
+		case "name":
+			ast.Name = constituent.Value
+		case "expr":
+			bound, err := bindExprAST(constituent)
+			if err != nil {
+				return nil, err
+			}
+			ast.Expr = bound
+		case "'='":
+			// left out of the struct
+		default:
+			if constituent.Ignore {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: unexpected part (%s) in part (%s)", constituent.StartLine, constituent.Name, part.Name)
+		}
+	}
+	return ast, nil
+}
+
+// PrintAST holds the constituents of part (print)
+type PrintAST struct {
+	Part         *dialects.Part
+	PrintKeyword string
+	Expr         *ExprAST
+}
+
+func bindPrintAST(part *dialects.Part) (*PrintAST, error) {
+	ast := &PrintAST{Part: part}
+	for _, constituent := range part.Constituents {
+		switch constituent.Name {
+		case "printKeyword":
+			ast.PrintKeyword = constituent.Value
+		case "expr":
+			bound, err := bindExprAST(constituent)
+			if err != nil {
+				return nil, err
+			}
+			ast.Expr = bound
+		default:
+			if constituent.Ignore {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: unexpected part (%s) in part (%s)", constituent.StartLine, constituent.Name, part.Name)
+		}
+	}
+	return ast, nil
+}
+
+// ExprAST holds the expression of part (expr), which is either a lone Operand or an Operator between its
+// Left and Right operands
+type ExprAST struct {
+	Part     *dialects.Part
+	Operand  *OperandAST
+	Operator string
+	Left     *ExprAST
+	Right    *ExprAST
+}
+
+func bindExprAST(part *dialects.Part) (*ExprAST, error) {
+	ast := &ExprAST{Part: part}
+	for _, constituent := range part.Constituents {
+		var operand *ExprAST
+		switch constituent.Name {
+		case dialects.OperatorPartName:
+			ast.Operator = constituent.Value
+			continue
+		case "expr":
+			bound, err := bindExprAST(constituent)
+			if err != nil {
+				return nil, err
+			}
+			operand = bound
+		case "operand":
+			bound, err := bindOperandAST(constituent)
+			if err != nil {
+				return nil, err
+			}
+			operand = &ExprAST{Part: constituent, Operand: bound}
+		default:
+			if constituent.Ignore {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: unexpected part (%s) in part (%s)", constituent.StartLine, constituent.Name, part.Name)
+		}
+		if ast.Left == nil {
+			ast.Left = operand
+		} else {
+			ast.Right = operand
+		}
+	}
+	// a lone operand is the expression itself
+	if ast.Right == nil && ast.Left != nil {
+		ast.Operand = ast.Left.Operand
+		ast.Left = nil
+	}
+	return ast, nil
+}
+
+// OperandAST holds the constituents of part (operand)
+type OperandAST struct {
+	Part   *dialects.Part
+	Number string
+	Name   string
+	Expr   *ExprAST
+}
+
+func bindOperandAST(part *dialects.Part) (*OperandAST, error) {
+	ast := &OperandAST{Part: part}
+	for _, constituent := range part.Constituents {
+		switch constituent.Name {
+		case "number":
+			ast.Number = constituent.Value
+		case "name":
+			ast.Name = constituent.Value
+		case "expr":
+			bound, err := bindExprAST(constituent)
+			if err != nil {
+				return nil, err
+			}
+			ast.Expr = bound
+		case "'('", "')'":
+			// left out of the struct
+		default:
+			if constituent.Ignore {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: unexpected part (%s) in part (%s)", constituent.StartLine, constituent.Name, part.Name)
+		}
+	}
+	return ast, nil
+}
+
+// Bind returns part (program) and its descendants as a ProgramAST
+func Bind(root *dialects.Part) (*ProgramAST, error) {
+	if root.Name != "program" {
+		return nil, fmt.Errorf("expected part (program), found part (%s)", root.Name)
+	}
+	return bindProgramAST(root)
+}
@@ -0,0 +1,43 @@
+// Package calc provides a dialect of assignments and print statements whose parse trees are bound to the AST structs
+// dialectsgen generates for it
+package calc
+
+import "github.com/AdamJonR/dialects"
+
+//go:generate go run ./gen
+
+// Calc provides the dialect
+type Calc struct{}
+
+// NewDialect returns the dialect, whose literals are named in single quotes, as LoadGrammar names them, so they're
+// left out of the AST structs
+func (Calc) NewDialect() *dialects.Dialect {
+	return &dialects.Dialect{Title: "calc", RootName: "program", SkipPattern: `[ \t]+`, PartDefinitions: map[string]dialects.PartDefinition{
+		"program":    {Constituents: [][]string{{"statement%newline*"}}},
+		"statement":  {Constituents: [][]string{{"assignment"}, {"print"}}},
+		"assignment": {Constituents: [][]string{{"name", "'='", "expr"}}},
+		"print":      {Constituents: [][]string{{"printKeyword", "expr"}}},
+		"expr": dialects.ExpressionPart("operand", []dialects.OpLevel{
+			{Operators: []string{"+", "-"}},
+			{Operators: []string{"*", "/"}},
+		}),
+		"operand":      {Constituents: [][]string{{"number"}, {"!printKeyword", "name"}, {"'('", "expr", "')'"}}},
+		"printKeyword": {Keyword: "print"},
+		"name":         {Regex: `[a-z]+`},
+		"number":       {Regex: `[0-9]+`},
+		"'='":          {Literal: "="},
+		"'('":          {Literal: "("},
+		"')'":          {Literal: ")"},
+		"newline":      {Literal: "\n"},
+	}}
+}
+
+// NewModel returns nothing, as the AST is the model
+func (Calc) NewModel() interface{} {
+	return nil
+}
+
+// GenerateOutput returns nothing, as the AST is the output
+func (Calc) GenerateOutput(model interface{}) (string, error) {
+	return "", nil
+}
@@ -0,0 +1,62 @@
+package calc_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/AdamJonR/dialects"
+	"github.com/AdamJonR/dialects/dialectsgen/internal/calc"
+)
+
+// evaluate returns the value of the expression, with the values of the names assigned so far
+func evaluate(expr *calc.ExprAST, values map[string]int) int {
+	if expr.Operand != nil {
+		switch {
+		case expr.Operand.Expr != nil:
+			return evaluate(expr.Operand.Expr, values)
+		case expr.Operand.Name != "":
+			return values[expr.Operand.Name]
+		}
+		number, _ := strconv.Atoi(expr.Operand.Number)
+		return number
+	}
+	left, right := evaluate(expr.Left, values), evaluate(expr.Right, values)
+	switch expr.Operator {
+	case "+":
+		return left + right
+	case "-":
+		return left - right
+	case "*":
+		return left * right
+	}
+	return left / right
+}
+
+func TestBind(t *testing.T) {
+	root, err, _ := dialects.ParseToTree(calc.Calc{}, "x = 2 * (3 + 4)\ny = x - 10 / 5 - 1\nprint y * 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	program, err := calc.Bind(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := make(map[string]int)
+	var printed []int
+	for _, statement := range program.Statement {
+		if statement.Assignment != nil {
+			values[statement.Assignment.Name] = evaluate(statement.Assignment.Expr, values)
+		} else {
+			printed = append(printed, evaluate(statement.Print.Expr, values))
+		}
+	}
+	if values["x"] != 14 || values["y"] != 11 || len(printed) != 1 || printed[0] != 22 {
+		t.Errorf("expected x = 14, y = 11, and 22 printed, got %v and %v", values, printed)
+	}
+	if printed := program.Statement[2].Print; printed.Part.StartLine != 3 || printed.PrintKeyword != "print" {
+		t.Errorf("expected the print statement on line 3, got %+v", printed)
+	}
+	if _, err := calc.Bind(root.Constituents[0]); err == nil || err.Error() != "expected part (program), found part (statement)" {
+		t.Errorf("expected an error binding a statement, got %v", err)
+	}
+}
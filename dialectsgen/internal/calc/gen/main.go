@@ -0,0 +1,15 @@
+// Command gen writes the AST structs of the calc dialect to ast.go
+package main
+
+import (
+	"log"
+
+	"github.com/AdamJonR/dialects/dialectsgen"
+	"github.com/AdamJonR/dialects/dialectsgen/internal/calc"
+)
+
+func main() {
+	if err := dialectsgen.WriteFile("ast.go", calc.Calc{}.NewDialect(), dialectsgen.Config{Package: "calc"}); err != nil {
+		log.Fatal(err)
+	}
+}
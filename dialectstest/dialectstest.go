@@ -0,0 +1,64 @@
+// Package dialectstest provides helpers for testing dialects with the testing package
+package dialectstest
+
+import (
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/AdamJonR/dialects"
+)
+
+// Examples runs each of the dialect's examples as a subtest, failing the ones that don't parse, with the error and
+// trace log, or that don't generate their expected output, and fails if the dialect has no examples or expects the
+// output of an example it doesn't have, as when an example is renamed
+func Examples(t *testing.T, dialectable dialects.Dialectable) {
+	t.Helper()
+	dialect := dialectable.NewDialect()
+	if len(dialect.Examples) == 0 {
+		t.Fatalf("dialect %s has no Examples", dialect.Title)
+	}
+	names := make([]string, 0, len(dialect.ExpectedOutputs))
+	for name := range dialect.ExpectedOutputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, ok := dialect.Examples[name]; !ok {
+			t.Errorf("dialect %s has ExpectedOutputs for %q, which isn't one of its Examples", dialect.Title, name)
+		}
+	}
+	for _, result := range dialects.TestExamples(dialectable) {
+		result := result
+		t.Run(result.Name, func(t *testing.T) {
+			t.Helper()
+			if result.Err != nil {
+				t.Fatalf("parsing %q: %v\n%s", result.Input, result.Err, result.Log)
+			}
+			if result.HasExpected && result.Output != result.Expected {
+				t.Errorf("parsing %q: expected output %q, got %q", result.Input, result.Expected, result.Output)
+			}
+		})
+	}
+}
+
+// CounterExamples runs each of the dialect's counter-examples as a subtest, failing the ones that parse, with their
+// output, or that fail with an error not containing the expected text
+func CounterExamples(t *testing.T, dialectable dialects.Dialectable) {
+	t.Helper()
+	for _, result := range dialects.TestCounterExamples(dialectable) {
+		result := result
+		t.Run(result.Name, func(t *testing.T) {
+			t.Helper()
+			var dialectError *dialects.DialectError
+			switch {
+			case result.Err == nil:
+				t.Fatalf("parsing %q: expected an error, got output %q", result.Input, result.Output)
+			case errors.As(result.Err, &dialectError):
+				t.Fatalf("compiling the dialect: %v", result.Err)
+			case result.Failed():
+				t.Errorf("parsing %q: expected an error containing %q, got %v", result.Input, result.Expected, result.Err)
+			}
+		})
+	}
+}
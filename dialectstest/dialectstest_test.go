@@ -0,0 +1,90 @@
+package dialectstest_test
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/AdamJonR/dialects"
+	"github.com/AdamJonR/dialects/dialectstest"
+)
+
+// words provides a dialect of space-separated words whose output lists them in upper case
+type words struct{}
+
+func (words) NewDialect() *dialects.Dialect {
+	return &dialects.Dialect{
+		Title:    "words",
+		RootName: "words",
+		PartDefinitions: map[string]dialects.PartDefinition{
+			"words": {Constituents: [][]string{{"word+"}}},
+			"word": {Regex: `[a-z]+`, Handler: func(part *dialects.Part, model interface{}) bool {
+				names := model.(*[]string)
+				*names = append(*names, strings.ToUpper(part.Value))
+				return true
+			}},
+		},
+		SkipPattern:     `[ ]+`,
+		Examples:        map[string]string{"two": "ab cd", "one": "ab"},
+		ExpectedOutputs: map[string]string{"two": "AB CD"},
+		CounterExamples: map[string]string{"digit": "ab 1", "empty": ""},
+		ExpectedErrors:  map[string]string{"digit": "column 4"},
+	}
+}
+
+func (words) NewModel() interface{} {
+	return &[]string{}
+}
+
+func (words) GenerateOutput(model interface{}) (string, error) {
+	return strings.Join(*model.(*[]string), " "), nil
+}
+
+func TestExamples(t *testing.T) {
+	dialectstest.Examples(t, words{})
+}
+
+func TestCounterExamples(t *testing.T) {
+	dialectstest.CounterExamples(t, words{})
+}
+
+// unexampled provides the words dialect with its examples changed by the function
+type unexampled struct {
+	words
+	change func(d *dialects.Dialect)
+}
+
+func (dialectable unexampled) NewDialect() *dialects.Dialect {
+	d := dialectable.words.NewDialect()
+	dialectable.change(d)
+	return d
+}
+
+func TestExamplesProblems(t *testing.T) {
+	tests := []struct {
+		name     string
+		change   func(d *dialects.Dialect)
+		expected string
+	}{
+		{"none", func(d *dialects.Dialect) { d.Examples = nil }, "dialect words has no Examples"},
+		{"renamed", func(d *dialects.Dialect) { d.ExpectedOutputs["three"] = "AB CD EF" }, `dialect words has ExpectedOutputs for "three", which isn't one of its Examples`},
+	}
+	// the helper fails the test it's given, so it's run in a copy of the test binary whose failure is expected
+	if name := os.Getenv("DIALECTSTEST_PROBLEM"); name != "" {
+		for _, test := range tests {
+			if test.name == name {
+				dialectstest.Examples(t, unexampled{change: test.change})
+			}
+		}
+		return
+	}
+	for _, test := range tests {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestExamplesProblems$")
+		cmd.Env = append(os.Environ(), "DIALECTSTEST_PROBLEM="+test.name)
+		output, err := cmd.CombinedOutput()
+		if err == nil || !strings.Contains(string(output), test.expected) {
+			t.Errorf("%s: expected the examples to fail with %q, got %v:\n%s", test.name, test.expected, err, output)
+		}
+	}
+}
@@ -0,0 +1,464 @@
+package dialects
+
+import (
+	"reflect"
+	"regexp/syntax"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ChangeKind provides what a Change to a dialect's grammar is
+type ChangeKind int
+
+const (
+	// PartAdded marks a part the new dialect defines but the old doesn't
+	PartAdded ChangeKind = iota
+	// PartRemoved marks a part the old dialect defines but the new doesn't
+	PartRemoved
+	// PartRedefined marks a part defined another way, such as by Constituents rather than a Regex
+	PartRedefined
+	// PatternChanged marks a part matching text whose Regex, Literal, or Keyword changed
+	PatternChanged
+	// AlternativeAdded marks a constituent sequence the new part has but the old doesn't
+	AlternativeAdded
+	// AlternativeRemoved marks a constituent sequence the old part has but the new doesn't
+	AlternativeRemoved
+	// AlternativesReordered marks alternatives both parts have that are tried in another order
+	AlternativesReordered
+	// SequenceChanged marks an alternative whose constituent sequence changed in place, or was widened wherever it moved
+	SequenceChanged
+	// ExpressionChanged marks an ExpressionPart whose operand or operators changed
+	ExpressionChanged
+	// IgnoreChanged marks a part whose Ignore changed
+	IgnoreChanged
+	// DescriptionChanged marks a part whose Description changed
+	DescriptionChanged
+	// SettingChanged marks another setting of a part, or of the dialect itself, that changed
+	SettingChanged
+)
+
+// String returns the kind of change in words, as in "part added"
+func (kind ChangeKind) String() string {
+	switch kind {
+	case PartAdded:
+		return "part added"
+	case PartRemoved:
+		return "part removed"
+	case PartRedefined:
+		return "part redefined"
+	case PatternChanged:
+		return "pattern changed"
+	case AlternativeAdded:
+		return "alternative added"
+	case AlternativeRemoved:
+		return "alternative removed"
+	case AlternativesReordered:
+		return "alternatives reordered"
+	case SequenceChanged:
+		return "sequence changed"
+	case ExpressionChanged:
+		return "expression changed"
+	case IgnoreChanged:
+		return "ignore changed"
+	case DescriptionChanged:
+		return "description changed"
+	}
+	return "setting changed"
+}
+
+// Change provides a difference between two versions of a dialect's grammar, with the PartName it's in (empty for a
+// setting of the dialect itself), the 1-based Alternative it's in, if any, the Setting that changed for a
+// SettingChanged, the Old and New forms of what changed, and whether it's Breaking, meaning input the old dialect
+// accepted may be rejected or parsed differently
+type Change struct {
+	Kind        ChangeKind
+	PartName    string
+	Alternative int
+	Setting     string
+	Old         string
+	New         string
+	Breaking    bool
+}
+
+// String returns the change in words, as in "part (value) changed alternative 1 from number to number unit?", noting
+// when it's potentially breaking
+func (change Change) String() string {
+	subject := "dialect"
+	if change.PartName != "" {
+		subject = "part (" + change.PartName + ")"
+	}
+	var text string
+	switch change.Kind {
+	case PartAdded:
+		text = subject + " added: " + change.New
+	case PartRemoved:
+		text = subject + " removed: " + change.Old
+	case PartRedefined:
+		text = subject + " redefined from " + change.Old + " to " + change.New
+	case PatternChanged:
+		text = subject + " changed from " + change.Old + " to " + change.New
+	case AlternativeAdded:
+		text = subject + " added alternative " + strconv.Itoa(change.Alternative) + ": " + change.New
+	case AlternativeRemoved:
+		text = subject + " removed alternative " + strconv.Itoa(change.Alternative) + ": " + change.Old
+	case AlternativesReordered:
+		text = subject + " reordered alternatives from " + change.Old + " to " + change.New
+	case SequenceChanged:
+		text = subject + " changed alternative " + strconv.Itoa(change.Alternative) + " from " + change.Old + " to " + change.New
+	case ExpressionChanged:
+		text = subject + " changed expression from " + change.Old + " to " + change.New
+	case IgnoreChanged:
+		text = subject + " changed Ignore from " + change.Old + " to " + change.New
+	case DescriptionChanged:
+		text = subject + " changed Description from " + change.Old + " to " + change.New
+	default:
+		text = subject + " changed " + change.Setting + " from " + change.Old + " to " + change.New
+	}
+	if change.Breaking {
+		text += " (potentially breaking)"
+	}
+	return text
+}
+
+// FormatChanges returns the changes in words, one per line, as for a changelog
+func FormatChanges(changes []Change) string {
+	var b strings.Builder
+	for _, change := range changes {
+		b.WriteString(change.String() + "\n")
+	}
+	return b.String()
+}
+
+// DiffDialects returns the changes from the grammar of the old dialect to that of the new one, starting with the
+// settings of the dialect itself, such as its RootName and SkipPattern, followed by those of each part in order of
+// name, where a part's changes are broken down into changes to its pattern, its alternatives (added, removed,
+// reordered, or changed), its expression, its Ignore, its Description, and its other settings, flagging those
+// that are potentially breaking, such as a removed alternative or a regex that isn't a widening of the old one;
+// functions, such as handlers, can't be compared, so changes to them aren't reported
+func DiffDialects(oldDialect *Dialect, newDialect *Dialect) []Change {
+	var changes []Change
+	for _, setting := range []struct {
+		name     string
+		old, new interface{}
+		// widens reports whether the new value only lets the dialect accept more input
+		widens bool
+	}{
+		{"RootName", oldDialect.RootName, newDialect.RootName, false},
+		{"SkipPattern", oldDialect.SkipPattern, newDialect.SkipPattern, oldDialect.SkipPattern == ""},
+		{"LineComment", oldDialect.LineComment, newDialect.LineComment, oldDialect.LineComment == ""},
+		{"BlockComment", oldDialect.BlockComment, newDialect.BlockComment, oldDialect.BlockComment == [2]string{}},
+		{"KeywordContinuation", oldDialect.KeywordContinuation, newDialect.KeywordContinuation, false},
+		{"CaseInsensitiveKeywords", oldDialect.CaseInsensitiveKeywords, newDialect.CaseInsensitiveKeywords, newDialect.CaseInsensitiveKeywords},
+		{"CaseInsensitive", oldDialect.CaseInsensitive, newDialect.CaseInsensitive, newDialect.CaseInsensitive},
+		{"AllowTrailing", oldDialect.AllowTrailing, newDialect.AllowTrailing, newDialect.AllowTrailing},
+		{"UnanchoredRegexes", oldDialect.UnanchoredRegexes, newDialect.UnanchoredRegexes, false},
+		{"NormalizeLineEndings", oldDialect.NormalizeLineEndings, newDialect.NormalizeLineEndings, false},
+		{"AllowInvalidUTF8", oldDialect.AllowInvalidUTF8, newDialect.AllowInvalidUTF8, newDialect.AllowInvalidUTF8},
+		{"VersionPart", oldDialect.VersionPart, newDialect.VersionPart, newDialect.VersionPart == ""},
+	} {
+		if !reflect.DeepEqual(setting.old, setting.new) {
+			changes = append(changes, Change{Kind: SettingChanged, Setting: setting.name, Old: settingText(setting.old), New: settingText(setting.new), Breaking: !setting.widens})
+		}
+	}
+	names := sortedPartNames(oldDialect)
+	for _, name := range sortedPartNames(newDialect) {
+		if _, ok := oldDialect.PartDefinitions[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		oldPart, inOld := oldDialect.PartDefinitions[name]
+		newPart, inNew := newDialect.PartDefinitions[name]
+		switch {
+		case !inOld:
+			changes = append(changes, Change{Kind: PartAdded, PartName: name, New: definitionText(newPart)})
+		case !inNew:
+			changes = append(changes, Change{Kind: PartRemoved, PartName: name, Old: definitionText(oldPart), Breaking: true})
+		default:
+			changes = append(changes, diffParts(name, oldPart, newPart)...)
+		}
+	}
+	return changes
+}
+
+// diffParts returns the changes from the old definition of the part to the new one
+func diffParts(name string, oldPart PartDefinition, newPart PartDefinition) []Change {
+	var changes []Change
+	if definitionKind(oldPart) != definitionKind(newPart) {
+		changes = append(changes, Change{Kind: PartRedefined, PartName: name, Old: definitionText(oldPart), New: definitionText(newPart), Breaking: true})
+	} else {
+		if oldText, newText := definitionText(oldPart), definitionText(newPart); len(oldPart.Constituents) == 0 && oldPart.expression == nil && oldText != newText {
+			changes = append(changes, Change{Kind: PatternChanged, PartName: name, Old: oldText, New: newText, Breaking: oldPart.Regex == "" || !regexWidens(oldPart.Regex, newPart.Regex)})
+		}
+		changes = append(changes, diffAlternatives(name, oldPart.Constituents, newPart.Constituents)...)
+		if oldPart.expression != nil {
+			if oldText, newText := expressionText(oldPart.expression), expressionText(newPart.expression); oldText != newText {
+				changes = append(changes, Change{Kind: ExpressionChanged, PartName: name, Old: oldText, New: newText, Breaking: !expressionWidens(oldPart.expression, newPart.expression)})
+			}
+		}
+	}
+	if oldPart.Ignore != newPart.Ignore {
+		changes = append(changes, Change{Kind: IgnoreChanged, PartName: name, Old: strconv.FormatBool(oldPart.Ignore), New: strconv.FormatBool(newPart.Ignore)})
+	}
+	if oldPart.Description != newPart.Description {
+		changes = append(changes, Change{Kind: DescriptionChanged, PartName: name, Old: strconv.Quote(oldPart.Description), New: strconv.Quote(newPart.Description)})
+	}
+	for _, setting := range []struct {
+		name     string
+		old, new interface{}
+		widens   bool
+	}{
+		{"CaseInsensitive", oldPart.CaseInsensitive, newPart.CaseInsensitive, newPart.CaseInsensitive},
+		{"CaseSensitive", oldPart.CaseSensitive, newPart.CaseSensitive, !newPart.CaseSensitive},
+		{"NoSkip", oldPart.NoSkip, newPart.NoSkip, false},
+		{"AllowLeftRecursion", oldPart.AllowLeftRecursion, newPart.AllowLeftRecursion, newPart.AllowLeftRecursion},
+		{"RecoverAt", oldPart.RecoverAt, newPart.RecoverAt, true},
+		{"PrintHint", oldPart.PrintHint, newPart.PrintHint, true},
+		{"Exported", oldPart.Exported, newPart.Exported, true},
+	} {
+		if !reflect.DeepEqual(setting.old, setting.new) {
+			changes = append(changes, Change{Kind: SettingChanged, PartName: name, Setting: setting.name, Old: settingText(setting.old), New: settingText(setting.new), Breaking: !setting.widens})
+		}
+	}
+	return changes
+}
+
+// diffAlternatives returns the changes from the old alternatives of the part to the new ones, where an alternative
+// the new part widens, wherever it moved to, or else replaces with one the old part doesn't have in the same place is
+// changed, and the others are added, removed, or reordered
+func diffAlternatives(name string, oldSeqs [][]string, newSeqs [][]string) []Change {
+	oldTexts, newTexts := sequenceTexts(oldSeqs), sequenceTexts(newSeqs)
+	inOld, inNew := make(map[string]bool), make(map[string]bool)
+	for _, text := range oldTexts {
+		inOld[text] = true
+	}
+	for _, text := range newTexts {
+		inNew[text] = true
+	}
+	// pair the alternatives only one of the parts has, first by widening, then by place
+	newFor, oldFor := make(map[int]int), make(map[int]int)
+	for i, oldText := range oldTexts {
+		for j, newText := range newTexts {
+			if _, paired := oldFor[j]; !paired && !inNew[oldText] && !inOld[newText] && sequenceWidens(oldSeqs[i], newSeqs[j]) {
+				newFor[i], oldFor[j] = j, i
+				break
+			}
+		}
+	}
+	for i := 0; i < len(oldTexts) && i < len(newTexts); i++ {
+		_, oldPaired := newFor[i]
+		_, newPaired := oldFor[i]
+		if !oldPaired && !newPaired && !inNew[oldTexts[i]] && !inOld[newTexts[i]] {
+			newFor[i], oldFor[i] = i, i
+		}
+	}
+	var changes []Change
+	for i := range oldTexts {
+		if j, paired := newFor[i]; paired {
+			changes = append(changes, Change{Kind: SequenceChanged, PartName: name, Alternative: i + 1, Old: oldTexts[i], New: newTexts[j], Breaking: !sequenceWidens(oldSeqs[i], newSeqs[j])})
+		}
+	}
+	for i, text := range oldTexts {
+		if _, paired := newFor[i]; !paired && !inNew[text] {
+			changes = append(changes, Change{Kind: AlternativeRemoved, PartName: name, Alternative: i + 1, Old: text, Breaking: true})
+		}
+	}
+	for j, text := range newTexts {
+		if _, paired := oldFor[j]; !paired && !inOld[text] {
+			changes = append(changes, Change{Kind: AlternativeAdded, PartName: name, Alternative: j + 1, New: text})
+		}
+	}
+	// alternatives are tried in order, so the first to match can change when the ones kept or changed are reordered,
+	// which is told by the old text of each
+	var oldKept, newKept, oldOrder, newOrder []string
+	for i, text := range oldTexts {
+		if _, paired := newFor[i]; paired || inNew[text] {
+			oldKept, oldOrder = append(oldKept, text), append(oldOrder, text)
+		}
+	}
+	for j, text := range newTexts {
+		if i, paired := oldFor[j]; paired {
+			newKept, newOrder = append(newKept, text), append(newOrder, oldTexts[i])
+		} else if inOld[text] {
+			newKept, newOrder = append(newKept, text), append(newOrder, text)
+		}
+	}
+	if !reflect.DeepEqual(oldOrder, newOrder) {
+		changes = append(changes, Change{Kind: AlternativesReordered, PartName: name, Old: strings.Join(oldKept, " | "), New: strings.Join(newKept, " | "), Breaking: true})
+	}
+	return changes
+}
+
+// sequenceTexts returns each constituent sequence as its constituent IDs separated by spaces
+func sequenceTexts(constituents [][]string) []string {
+	texts := make([]string, len(constituents))
+	for i, constituentSeq := range constituents {
+		texts[i] = strings.Join(constituentSeq, " ")
+	}
+	return texts
+}
+
+// sequenceWidens reports whether the new constituent sequence is the old one with only optional constituents added,
+// so it accepts everything the old one did
+func sequenceWidens(oldSeq []string, newSeq []string) bool {
+	i := 0
+	for _, constituentID := range newSeq {
+		if i < len(oldSeq) && oldSeq[i] == constituentID {
+			i++
+			continue
+		}
+		switch reference := parseConstituent(constituentID); reference.modifier {
+		case "?", "*":
+		case "{}":
+			if reference.min > 0 {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return i == len(oldSeq)
+}
+
+// regexWidens reports whether the new regex accepts everything the old one did as far as can be told by comparing
+// them, which is when the new one is an alternation including the old one, or a character class including the old one
+func regexWidens(oldRegex string, newRegex string) bool {
+	oldParsed, oldErr := syntax.Parse(oldRegex, syntax.Perl)
+	newParsed, newErr := syntax.Parse(newRegex, syntax.Perl)
+	if oldErr != nil || newErr != nil {
+		return false
+	}
+	oldParsed, newParsed = oldParsed.Simplify(), newParsed.Simplify()
+	if newParsed.Op == syntax.OpAlternate {
+		for _, sub := range newParsed.Sub {
+			if sub.Equal(oldParsed) {
+				return true
+			}
+		}
+	}
+	return classIncludes(newParsed, oldParsed)
+}
+
+// classIncludes reports whether the outer regex is a character class, or a repetition of one, including every
+// character of the inner regex, which is the same with a class of its own
+func classIncludes(outer *syntax.Regexp, inner *syntax.Regexp) bool {
+	if outer.Op != inner.Op {
+		return false
+	}
+	switch outer.Op {
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest:
+		return classIncludes(outer.Sub[0], inner.Sub[0])
+	case syntax.OpCharClass:
+		for i := 0; i < len(inner.Rune); i += 2 {
+			included := false
+			for j := 0; j < len(outer.Rune); j += 2 {
+				if outer.Rune[j] <= inner.Rune[i] && inner.Rune[i+1] <= outer.Rune[j+1] {
+					included = true
+					break
+				}
+			}
+			if !included {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// expressionWidens reports whether the new expression has the old one's operand and keeps each of its operators at
+// the same precedence relative to the others, with the same associativity, adding operators at most
+func expressionWidens(oldExpression *expression, newExpression *expression) bool {
+	if oldExpression.operand != newExpression.operand {
+		return false
+	}
+	newLevels := make(map[string]int)
+	for i, level := range newExpression.levels {
+		for _, operator := range level.Operators {
+			newLevels[operator] = i
+		}
+	}
+	previous := -1
+	for _, level := range oldExpression.levels {
+		current := -1
+		for _, operator := range level.Operators {
+			newLevel, ok := newLevels[operator]
+			if !ok || newLevel <= previous || (current >= 0 && newLevel != current) || newExpression.levels[newLevel].RightAssociative != level.RightAssociative {
+				return false
+			}
+			current = newLevel
+		}
+		if current >= 0 {
+			previous = current
+		}
+	}
+	return true
+}
+
+// definitionKind returns which of the ways to define a part the part uses
+func definitionKind(partDefinition PartDefinition) string {
+	switch {
+	case len(partDefinition.Constituents) > 0:
+		return "constituents"
+	case partDefinition.expression != nil:
+		return "expression"
+	case partDefinition.Regex != "":
+		return "regex"
+	case partDefinition.Literal != "":
+		return "literal"
+	case partDefinition.Keyword != "":
+		return "keyword"
+	case partDefinition.EOF:
+		return "EOF"
+	case partDefinition.Match != nil:
+		return "match"
+	}
+	return ""
+}
+
+// definitionText returns the part's definition as written in a change, with its alternatives separated by bars
+func definitionText(partDefinition PartDefinition) string {
+	switch {
+	case len(partDefinition.Constituents) > 0:
+		return strings.Join(sequenceTexts(partDefinition.Constituents), " | ")
+	case partDefinition.expression != nil:
+		return expressionText(partDefinition.expression)
+	case partDefinition.Keyword != "":
+		return "keyword " + strconv.Quote(partDefinition.Keyword)
+	}
+	// a definition with nothing to match, which ValidateDialect reports, has no alternatives
+	if alternatives := ebnfAlternatives(partDefinition, nil); len(alternatives) > 0 {
+		return alternatives[0]
+	}
+	return "nothing"
+}
+
+// expressionText returns the operand of the expression and its operators, from lowest to highest precedence, as in
+// "operand with + -, * /, ^ (right associative)"
+func expressionText(expression *expression) string {
+	levels := make([]string, len(expression.levels))
+	for i, level := range expression.levels {
+		levels[i] = strings.Join(level.Operators, " ")
+		if level.RightAssociative {
+			levels[i] += " (right associative)"
+		}
+	}
+	return expression.operand + " with " + strings.Join(levels, ", ")
+}
+
+// settingText returns the value of a setting as written in a change
+func settingText(value interface{}) string {
+	switch value := value.(type) {
+	case string:
+		return strconv.Quote(value)
+	case []string:
+		return "[" + strings.Join(value, " ") + "]"
+	case [2]string:
+		return strconv.Quote(value[0]) + " " + strconv.Quote(value[1])
+	case bool:
+		return strconv.FormatBool(value)
+	}
+	return ""
+}
@@ -0,0 +1,80 @@
+package dialects_test
+
+import (
+	"testing"
+
+	"github.com/AdamJonR/dialects"
+)
+
+func TestDiffDialects(t *testing.T) {
+	v1 := &dialects.Dialect{RootName: "statements", Version: 1, PartDefinitions: map[string]dialects.PartDefinition{
+		"statements": {Constituents: [][]string{{"statement%semi*"}}},
+		"statement":  {Constituents: [][]string{{"name", "equals", "expr"}, {"print", "expr"}, {"pass"}}},
+		"expr":       dialects.ExpressionPart("operand", []dialects.OpLevel{{Operators: []string{"+"}}, {Operators: []string{"*"}}}),
+		"operand":    {Constituents: [][]string{{"number"}, {"name"}}},
+		"name":       {Regex: `[a-z]+`, Description: "name"},
+		"number":     {Regex: `[0-9]+`},
+		"equals":     {Literal: "="},
+		"semi":       {Literal: ";"},
+		"print":      {Keyword: "print"},
+		"pass":       {Keyword: "pass"},
+		"ws":         {Regex: `[ ]+`},
+	}}
+	v2 := &dialects.Dialect{RootName: "statements", Version: 2, SkipPattern: `[ \t]+`, PartDefinitions: map[string]dialects.PartDefinition{
+		"statements": {Constituents: [][]string{{"statement%semi*"}}},
+		"statement":  {Constituents: [][]string{{"print", "expr"}, {"name", "type?", "equals", "expr"}, {"import"}}},
+		"expr":       dialects.ExpressionPart("operand", []dialects.OpLevel{{Operators: []string{"+", "-"}}, {Operators: []string{"*"}}}),
+		"operand":    {Constituents: [][]string{{"name"}, {"number"}}},
+		"name":       {Regex: `[a-zA-Z]+`, Description: "identifier"},
+		"number":     {Regex: `[0-9]`},
+		"equals":     {Literal: ":="},
+		"semi":       {Literal: ";", RecoverAt: []string{"semi"}},
+		"print":      {Keyword: "print"},
+		"import":     {Keyword: "import"},
+		"type":       {Constituents: [][]string{{"name"}}},
+		"ws":         {Regex: `[ ]+`, Ignore: true},
+	}}
+	changes := dialects.DiffDialects(v1, v2)
+	expected := `dialect changed SkipPattern from "" to "[ \\t]+"
+part (equals) changed from "=" to ":=" (potentially breaking)
+part (expr) changed expression from operand with +, * to operand with + -, *
+part (import) added: keyword "import"
+part (name) changed from /[a-z]+/ to /[a-zA-Z]+/
+part (name) changed Description from "name" to "identifier"
+part (number) changed from /[0-9]+/ to /[0-9]/ (potentially breaking)
+part (operand) reordered alternatives from number | name to name | number (potentially breaking)
+part (pass) removed: keyword "pass" (potentially breaking)
+part (semi) changed RecoverAt from [] to [semi]
+part (statement) changed alternative 1 from name equals expr to name type? equals expr
+part (statement) changed alternative 3 from pass to import (potentially breaking)
+part (statement) reordered alternatives from name equals expr | print expr | pass to print expr | name type? equals expr | import (potentially breaking)
+part (type) added: name
+part (ws) changed Ignore from false to true
+`
+	if output := dialects.FormatChanges(changes); output != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, output)
+	}
+	if changes[0].Kind != dialects.SettingChanged || changes[0].Setting != "SkipPattern" || changes[0].Breaking {
+		t.Errorf("expected a change to SkipPattern that isn't breaking, got %+v", changes[0])
+	}
+	if changes := dialects.DiffDialects(v1, v1); len(changes) != 0 {
+		t.Errorf("expected no changes, got %v", changes)
+	}
+}
+
+func TestDiffDialectsEmptyPart(t *testing.T) {
+	v1 := &dialects.Dialect{RootName: "root", PartDefinitions: map[string]dialects.PartDefinition{
+		"root": {Constituents: [][]string{{"word"}}},
+		"word": {Regex: `[a-z]+`},
+	}}
+	v2 := &dialects.Dialect{RootName: "root", PartDefinitions: map[string]dialects.PartDefinition{
+		"root":  {Constituents: [][]string{{"word"}}},
+		"word":  {},
+		"draft": {},
+	}}
+	// parts with nothing to match are invalid, but can still be diffed
+	expected := "part (draft) added: nothing\npart (word) redefined from /[a-z]+/ to nothing (potentially breaking)\n"
+	if output := dialects.FormatChanges(dialects.DiffDialects(v1, v2)); output != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, output)
+	}
+}
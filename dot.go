@@ -0,0 +1,89 @@
+package dialects
+
+import "strings"
+
+// ExportDOT returns the dialect's grammar as a Graphviz digraph, with a node for each part, starting with the root,
+// which is drawn bold, then the others in order of name, where the parts that match text, such as regexes, are
+// ellipses labeled with what they match, those with constituents are boxes, and Ignored parts are dashed, and with an
+// edge for each part a part refers to, labeled with the modifier of the constituent, if any, along with dotted edges
+// to the parts it recovers at
+func ExportDOT(d *Dialect) string {
+	title := d.Title
+	if title == "" {
+		title = "grammar"
+	}
+	var b strings.Builder
+	b.WriteString("digraph " + dotQuote(title) + " {\n")
+	names := []string{d.RootName}
+	for _, name := range sortedPartNames(d) {
+		if name != d.RootName {
+			names = append(names, name)
+		}
+	}
+	for _, name := range names {
+		partDefinition, ok := d.PartDefinitions[name]
+		if !ok {
+			continue
+		}
+		var attributes []string
+		if expression := ebnfAlternatives(partDefinition, d); len(partDefinition.Constituents) == 0 && partDefinition.expression == nil {
+			attributes = append(attributes, "shape=ellipse", "label="+dotQuote(name+"\n"+expression[0]))
+		} else {
+			attributes = append(attributes, "shape=box")
+		}
+		var styles []string
+		if name == d.RootName {
+			styles = append(styles, "bold")
+		}
+		if partDefinition.Ignore {
+			styles = append(styles, "dashed")
+		}
+		if len(styles) > 0 {
+			attributes = append(attributes, "style="+dotQuote(strings.Join(styles, ",")))
+		}
+		b.WriteString("\t" + dotQuote(name) + " [" + strings.Join(attributes, ", ") + "];\n")
+	}
+	for _, name := range names {
+		partDefinition, ok := d.PartDefinitions[name]
+		if !ok {
+			continue
+		}
+		// an edge is drawn once however many alternatives refer to the part the same way
+		drawn := make(map[string]bool)
+		edge := func(to string, attributes string) {
+			line := "\t" + dotQuote(name) + " -> " + dotQuote(to) + attributes + ";\n"
+			if to != "" && !drawn[line] {
+				drawn[line] = true
+				b.WriteString(line)
+			}
+		}
+		for _, constituentSeq := range partDefinition.Constituents {
+			for _, constituentID := range constituentSeq {
+				reference := parseConstituent(constituentID)
+				modifier := reference.modifier
+				if modifier == "{}" {
+					modifier = constituentID[strings.LastIndexByte(constituentID, '{'):]
+				}
+				if modifier == "" {
+					edge(reference.name, "")
+				} else {
+					edge(reference.name, " [label="+dotQuote(modifier)+"]")
+				}
+				edge(reference.separator, " [label=\"separator\"]")
+			}
+		}
+		if partDefinition.expression != nil {
+			edge(partDefinition.expression.operand, " [label=\"operand\"]")
+		}
+		for _, recoverAt := range partDefinition.RecoverAt {
+			edge(recoverAt, " [style=dotted, label=\"recover\"]")
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// dotQuote returns the text as a quoted DOT string, with newlines written as line breaks
+func dotQuote(text string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(text) + `"`
+}
@@ -0,0 +1,24 @@
+package dialects_test
+
+import (
+	"testing"
+
+	"github.com/AdamJonR/dialects"
+)
+
+func TestExportDOT(t *testing.T) {
+	d := &dialects.Dialect{Title: "statements", RootName: "statements", PartDefinitions: map[string]dialects.PartDefinition{
+		"statements": {Constituents: [][]string{{"statement%semi*"}}},
+		"statement":  {Constituents: [][]string{{"name", "ws?", "equals", "ws?", "expr"}, {"name"}}, RecoverAt: []string{"semi"}},
+		"expr":       dialects.ExpressionPart("operand", []dialects.OpLevel{{Operators: []string{"+"}}}),
+		"operand":    {Constituents: [][]string{{"number{1,3}"}, {"!number", "name"}, {"open", "expr", "close"}}},
+		"name":       {Regex: `[a-z]+`},
+		"number":     {Regex: `[0-9]`},
+		"ws":         {Regex: `[ \t]+`, Ignore: true},
+		"equals":     {Literal: "="},
+		"semi":       {Literal: ";"},
+		"open":       {Literal: "("},
+		"close":      {Literal: `"`},
+	}}
+	golden(t, "grammar.dot", []byte(dialects.ExportDOT(d)))
+}
@@ -0,0 +1,145 @@
+package dialects
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ExportEBNF returns the dialect's grammar as EBNF production rules in the W3C style, for documentation, starting with
+// the root part and continuing breadth-first through the parts it refers to, followed by any parts it can't reach in
+// order of name, where each rule is preceded by its Description and whether it's Ignored as a comment, an alternative
+// after the first starts a line of its own, constituents keep their modifiers, regexes are written between slashes,
+// and the parts LoadGrammar defines for literals and regexes within rules are written in place
+func ExportEBNF(d *Dialect) string {
+	var b strings.Builder
+	for i, name := range exportOrder(d) {
+		partDefinition := d.PartDefinitions[name]
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		var notes []string
+		if partDefinition.Description != "" {
+			notes = append(notes, partDefinition.Description)
+		}
+		if partDefinition.Ignore {
+			notes = append(notes, "ignored")
+		}
+		if len(notes) > 0 {
+			b.WriteString("/* " + strings.Join(notes, ", ") + " */\n")
+		}
+		b.WriteString(name + " ::= ")
+		for j, expression := range ebnfAlternatives(partDefinition, d) {
+			if j > 0 {
+				b.WriteString("\n" + strings.Repeat(" ", len(name)+3) + "| ")
+			}
+			b.WriteString(expression)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// exportOrder returns the names of the parts to export, starting with the root part, then breadth-first through the
+// parts referred to by each part's constituents and expression operand in turn, then those left in order of name
+func exportOrder(d *Dialect) []string {
+	seen := make(map[string]bool)
+	var order []string
+	visit := func(name string) {
+		if _, ok := d.PartDefinitions[name]; ok && !seen[name] && !inlinedPart(name, d.PartDefinitions[name]) {
+			seen[name] = true
+			order = append(order, name)
+		}
+	}
+	visit(d.RootName)
+	for i := 0; i < len(order); i++ {
+		partDefinition := d.PartDefinitions[order[i]]
+		for _, constituentSeq := range partDefinition.Constituents {
+			for _, constituentID := range constituentSeq {
+				reference := parseConstituent(constituentID)
+				visit(reference.name)
+				visit(reference.separator)
+			}
+		}
+		if partDefinition.expression != nil {
+			visit(partDefinition.expression.operand)
+		}
+	}
+	for _, name := range sortedPartNames(d) {
+		visit(name)
+	}
+	return order
+}
+
+// ebnfAlternatives returns the expressions of the part's alternatives, or the expression of its terminal
+func ebnfAlternatives(partDefinition PartDefinition, d *Dialect) []string {
+	switch {
+	case partDefinition.Literal != "":
+		return []string{strconv.Quote(partDefinition.Literal)}
+	case partDefinition.Keyword != "":
+		return []string{strconv.Quote(partDefinition.Keyword)}
+	case partDefinition.Regex != "":
+		return []string{slash(partDefinition.Regex)}
+	case partDefinition.EOF:
+		return []string{"<end of input>"}
+	case partDefinition.Match != nil:
+		return []string{"<matched by a function>"}
+	case partDefinition.expression != nil:
+		var operators []string
+		for _, level := range partDefinition.expression.levels {
+			for _, operator := range level.Operators {
+				operators = append(operators, strconv.Quote(operator))
+			}
+		}
+		operand := ebnfName(partDefinition.expression.operand, d)
+		return []string{operand + " ((" + strings.Join(operators, " | ") + ") " + operand + ")*"}
+	}
+	alternatives := make([]string, len(partDefinition.Constituents))
+	for i, constituentSeq := range partDefinition.Constituents {
+		terms := make([]string, len(constituentSeq))
+		for j, constituentID := range constituentSeq {
+			terms[j] = ebnfTerm(constituentID, d)
+		}
+		alternatives[i] = strings.Join(terms, " ")
+	}
+	return alternatives
+}
+
+// ebnfTerm returns the constituent as an EBNF term, writing a separated list as the part followed by a repetition
+// of the separator and the part, and then the separator if it can trail
+func ebnfTerm(constituentID string, d *Dialect) string {
+	reference := parseConstituent(constituentID)
+	name := ebnfName(reference.name, d)
+	switch reference.modifier {
+	case "^":
+		return "^"
+	case "!", "&", "=":
+		return reference.modifier + name
+	case "{}":
+		return name + constituentID[strings.LastIndexByte(constituentID, '{'):]
+	}
+	if reference.separator == "" {
+		return name + reference.modifier
+	}
+	separator := ebnfName(reference.separator, d)
+	list := name + " (" + separator + " " + name + ")*"
+	if reference.trailing {
+		list += " " + separator + "?"
+	}
+	if reference.modifier == "*" {
+		return "(" + list + ")?"
+	}
+	return list
+}
+
+// ebnfName returns the name of a part as written in a term, which is the text of a part LoadGrammar defined for a
+// literal or regex within a rule
+func ebnfName(name string, d *Dialect) string {
+	partDefinition := d.PartDefinitions[name]
+	if !inlinedPart(name, partDefinition) {
+		return name
+	}
+	if partDefinition.Literal != "" {
+		return strconv.Quote(partDefinition.Literal)
+	}
+	return slash(partDefinition.Regex)
+}
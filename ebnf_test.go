@@ -0,0 +1,32 @@
+package dialects_test
+
+import (
+	"testing"
+
+	"github.com/AdamJonR/dialects"
+)
+
+func TestExportEBNF(t *testing.T) {
+	// the parts of a representative grammar, with one LoadGrammar defined for a literal
+	d := &dialects.Dialect{RootName: "statements", PartDefinitions: map[string]dialects.PartDefinition{
+		"statements":   {Constituents: [][]string{{"statement%%semi*", "eof"}}},
+		"statement":    {Constituents: [][]string{{"assignment"}, {"print"}}, Description: "statement"},
+		"assignment":   {Constituents: [][]string{{"name", "ws?", "'='", "^", "ws?", "expr"}}},
+		"print":        {Constituents: [][]string{{"printKeyword", "ws", "name%comma+"}, {"printKeyword", "!name", "name{1,3}"}}},
+		"printKeyword": {Keyword: "print"},
+		"expr":         dialects.ExpressionPart("operand", []dialects.OpLevel{{Operators: []string{"+", "-"}}, {Operators: []string{"*"}}}),
+		"operand":      {Constituents: [][]string{{"number"}, {"name"}, {"&open", "open", "expr", "close"}}},
+		"name":         {Regex: `[a-z]+`, Description: "name"},
+		"number":       {Regex: `[0-9]+/[0-9]+`},
+		"ws":           {Regex: `[ \t]+`, Ignore: true, Description: "whitespace"},
+		"'='":          {Literal: "="},
+		"semi":         {Literal: ";"},
+		"comma":        {Literal: ","},
+		"open":         {Literal: "("},
+		"close":        {Literal: ")"},
+		"eof":          {EOF: true},
+		"unused":       {Match: func(input string, pos int) (int, string, bool) { return 0, "", false }},
+		"alsoUnused":   {Constituents: [][]string{{"=name", "name"}}},
+	}}
+	golden(t, "ebnf.txt", []byte(dialects.ExportEBNF(d)))
+}
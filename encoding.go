@@ -0,0 +1,76 @@
+package dialects
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// EncodingError reports that the input isn't UTF-8, either because it starts with the byte order mark of another
+// Encoding, such as "UTF-16LE", or because it holds an invalid byte sequence, leaving Encoding empty, where the
+// ParseError gives its position, so callers can ask for the input to be re-saved as UTF-8 rather than reporting a
+// syntax error
+type EncodingError struct {
+	*ParseError
+	Encoding string
+}
+
+// byteOrderMarks provides the byte order marks of the encodings that aren't UTF-8, with the longer marks that start
+// with a shorter one first
+var byteOrderMarks = []struct {
+	mark     string
+	encoding string
+}{
+	{"\xff\xfe\x00\x00", "UTF-32LE"},
+	{"\x00\x00\xfe\xff", "UTF-32BE"},
+	{"\xff\xfe", "UTF-16LE"},
+	{"\xfe\xff", "UTF-16BE"},
+}
+
+// utf8BOM provides the byte order mark some editors start UTF-8 files with
+const utf8BOM = "\ufeff"
+
+// checkEncoding returns the input without its UTF-8 byte order mark, if any, along with the OffsetMap back to the
+// input, or nil if it has none, returning an EncodingError if the input is in another encoding or, unless the dialect
+// allows it, holds an invalid byte sequence
+func checkEncoding(input string, dialect *Dialect) (string, *OffsetMap, error) {
+	for _, bom := range byteOrderMarks {
+		if strings.HasPrefix(input, bom.mark) {
+			// the rest of the input would be garbled as UTF-8, so it's left out of the error
+			parseError := &ParseError{Line: 1, Column: 1, VisualColumn: 1, Message: "input appears to be " + bom.encoding + "; please re-save as UTF-8"}
+			return input, nil, &EncodingError{ParseError: parseError, Encoding: bom.encoding}
+		}
+	}
+	if !dialect.AllowInvalidUTF8 && !utf8.ValidString(input) {
+		return input, nil, invalidUTF8(input, dialect.OmitSnippets)
+	}
+	if !strings.HasPrefix(input, utf8BOM) {
+		return input, nil, nil
+	}
+	offsets := &OffsetMap{}
+	offsets.Add(0, len(utf8BOM))
+	return input[len(utf8BOM):], offsets, nil
+}
+
+// invalidUTF8 returns the EncodingError for the first invalid byte sequence of the input
+func invalidUTF8(input string, omitSnippets bool) *EncodingError {
+	offset := 0
+	for offset < len(input) {
+		r, size := utf8.DecodeRuneInString(input[offset:])
+		if r == utf8.RuneError && size == 1 {
+			break
+		}
+		offset += size
+	}
+	parseError := &ParseError{
+		Offset:       offset,
+		Line:         strings.Count(input[:offset], "\n") + 1,
+		Column:       column(input, offset),
+		Message:      "invalid UTF-8 byte sequence",
+		VisualColumn: visualColumn(input, offset, 0),
+	}
+	// the invalid bytes are shown as replacement characters, which can't move the caret as they follow it
+	if !omitSnippets {
+		parseError.LineText = strings.ToValidUTF8(lineText(input, offset), "\uFFFD")
+	}
+	return &EncodingError{ParseError: parseError}
+}
@@ -0,0 +1,63 @@
+package dialects_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/AdamJonR/dialects"
+)
+
+func TestByteOrderMark(t *testing.T) {
+	input := "\ufeffab cd\nef 12"
+	_, err, _ := dialects.Parse(wordList(), input)
+	var parseError *dialects.ParseError
+	if !errors.As(err, &parseError) {
+		t.Fatalf("expected a ParseError rather than a failure at the BOM, got %v", err)
+	}
+	// the error is reported at its offset in the input, past the BOM
+	if offset := strings.Index(input, "12"); parseError.Offset != offset || parseError.Line != 2 || parseError.Column != 4 {
+		t.Errorf("expected offset %d at line 2, column 4, got offset %d at line %d, column %d", offset, parseError.Offset, parseError.Line, parseError.Column)
+	}
+	root, err, _ := dialects.ParseToTree(wordList(), "\ufeffab cd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first := root.Constituents[0]; first.Value != "ab " || first.StartPos != 3 || root.EndPos != 8 {
+		t.Errorf("expected the first item %q to start at offset 3 and the root to end at 8, got %q at %d and %d", "ab ", first.Value, first.StartPos, root.EndPos)
+	}
+}
+
+func TestEncodingErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		encoding string
+		expected string
+	}{
+		{"UTF-16LE", "\xff\xfea\x00b\x00", "UTF-16LE", "dialects error: input appears to be UTF-16LE; please re-save as UTF-8 at line 1, column 1 (offset 0)"},
+		{"UTF-16BE", "\xfe\xff\x00a\x00b", "UTF-16BE", "dialects error: input appears to be UTF-16BE; please re-save as UTF-8 at line 1, column 1 (offset 0)"},
+		{"UTF-32LE", "\xff\xfe\x00\x00a\x00\x00\x00", "UTF-32LE", "dialects error: input appears to be UTF-32LE; please re-save as UTF-8 at line 1, column 1 (offset 0)"},
+		{"invalid byte", "ab cd\nef \xffgh ij", "", "dialects error: invalid UTF-8 byte sequence at line 2, column 4 (offset 9)\nef \uFFFDgh ij\n   ^"},
+	}
+	for _, test := range tests {
+		_, err, _ := dialects.Parse(wordList(), test.input)
+		var encodingError *dialects.EncodingError
+		if !errors.As(err, &encodingError) || encodingError.Encoding != test.encoding {
+			t.Errorf("%s: expected an EncodingError for %q, got %#v", test.name, test.encoding, err)
+			continue
+		}
+		if err.Error() != test.expected {
+			t.Errorf("%s: expected:\n%s\ngot:\n%s", test.name, test.expected, err)
+		}
+	}
+	// dialects for legacy encodings can parse invalid UTF-8 byte by byte
+	latin1 := newGrammar("root", map[string]dialects.PartDefinition{"root": {Regex: `[^\n]+`}})
+	if _, err, _ := dialects.Parse(latin1, "caf\xe9"); !errors.As(err, new(*dialects.EncodingError)) {
+		t.Errorf("expected an EncodingError, got %v", err)
+	}
+	latin1.dialect.AllowInvalidUTF8 = true
+	if _, err, _ := dialects.Parse(latin1, "caf\xe9"); err != nil {
+		t.Errorf("expected the invalid UTF-8 to be allowed, got %v", err)
+	}
+}
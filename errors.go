@@ -0,0 +1,365 @@
+package dialects
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ParseError provides the location and reason for a failed parse
+type ParseError struct {
+	Offset   int
+	Line     int
+	Column   int
+	PartName string
+	Message  string
+	Expected []string
+	// Err holds the error returned by a HandlerE, or the error of the context that stopped the parse, if that's what
+	// caused the failure
+	Err error
+	// LineText holds the line of input containing the failure, unless the dialect omits snippets
+	LineText string
+	// RuneOffset and RuneColumn hold the offset and 1-based column counted in runes rather than bytes, and are only
+	// set when parsing with Options.RunePositions
+	RuneOffset int
+	RuneColumn int
+	// VisualColumn holds the 1-based column as displayed, counting runes and advancing each tab to the next tab stop
+	// of TabWidth, which holds Options.TabWidth, so a tab takes one column when it's zero
+	VisualColumn int
+	TabWidth     int
+	// Suggestion holds the literal or keyword text that the word at the failure is probably a misspelling of, if any
+	Suggestion string
+	// length holds the length of the part whose handler failed, or of the misspelled word, for its Diagnostic
+	length int
+}
+
+// Error returns the message along with the line, column, and offset where parsing failed, followed by the snippet if
+// present, using the rune column when it's set
+func (e *ParseError) Error() string {
+	col := e.Column
+	if e.RuneColumn > 0 {
+		col = e.RuneColumn
+	}
+	message := "dialects error: " + e.Message + " at line " + strconv.Itoa(e.Line) + ", column " + strconv.Itoa(col) + " (offset " + strconv.Itoa(e.Offset) + ")"
+	if e.LineText != "" {
+		message = message + "\n" + e.Snippet()
+	}
+	return message
+}
+
+// Unwrap returns the error returned by a HandlerE, or the error of the context that stopped the parse, if any
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Snippet returns the line containing the failure with a caret beneath the failing column, or an empty string if
+// the line wasn't captured, expanding tabs to spaces when TabWidth is set
+func (e *ParseError) Snippet() string {
+	if e.LineText == "" {
+		return ""
+	}
+	// keep the caret within the line, as the fields may have been set by hand
+	col := e.Column
+	if col < 1 {
+		col = 1
+	} else if col > len(e.LineText)+1 {
+		col = len(e.LineText) + 1
+	}
+	if e.TabWidth > 0 {
+		return expandTabs(e.LineText, e.TabWidth) + "\n" + strings.Repeat(" ", visualColumn(e.LineText, col-1, e.TabWidth)-1) + "^"
+	}
+	// copy tabs from the line so the caret lines up however tabs are displayed
+	var caret strings.Builder
+	for _, r := range e.LineText[:col-1] {
+		if r == '\t' {
+			caret.WriteRune('\t')
+		} else {
+			caret.WriteRune(' ')
+		}
+	}
+	caret.WriteString("^")
+	return e.LineText + "\n" + caret.String()
+}
+
+// TraceError reports that the trace couldn't be written to Options.TraceWriter, which doesn't stop the parse, so it's
+// returned along with the results of an otherwise successful parse
+type TraceError struct {
+	Err error
+}
+
+// Error returns the first error returned by the trace writer
+func (e *TraceError) Error() string {
+	return "dialects error: unable to write trace: " + e.Err.Error()
+}
+
+// Unwrap returns the first error returned by the trace writer
+func (e *TraceError) Unwrap() error {
+	return e.Err
+}
+
+// DialectError provides the details of a problem with the grammar of a dialect
+type DialectError struct {
+	PartName string
+	Message  string
+	Err      error
+}
+
+// Error returns the message for the part (or for the dialect if there's no part), followed by the underlying error if
+// present
+func (e *DialectError) Error() string {
+	message := "dialects error: " + e.Message
+	if e.PartName != "" {
+		message = "dialects error: part (" + e.PartName + ") " + e.Message
+	}
+	if e.Err != nil {
+		message = message + ": " + e.Err.Error()
+	}
+	return message
+}
+
+// Unwrap returns the underlying error, such as a regex syntax error
+func (e *DialectError) Unwrap() error {
+	return e.Err
+}
+
+// newParseError returns a ParseError for the offset and line, computing the column and snippet from the input
+func newParseError(parser Parser, offset int, line int, partName string, message string) *ParseError {
+	parseError := &ParseError{
+		Offset:       offset,
+		Line:         line,
+		Column:       column(parser.input, offset),
+		PartName:     partName,
+		Message:      message,
+		VisualColumn: visualColumn(parser.input, offset, parser.tabWidth),
+		TabWidth:     parser.tabWidth,
+	}
+	if !parser.dialect.OmitSnippets {
+		parseError.LineText = lineText(parser.input, offset)
+	}
+	// count the runes once for the error rather than tracking them for every failure
+	if parser.runePositions {
+		parseError.RuneOffset = utf8.RuneCountInString(parser.input[:offset])
+		parseError.RuneColumn = utf8.RuneCountInString(parser.input[strings.LastIndex(parser.input[:offset], "\n")+1:offset]) + 1
+		if splitsCRLF(parser.input, offset) {
+			parseError.RuneColumn--
+		}
+	}
+	return parseError
+}
+
+// lineText returns the line of the input containing the offset, without its line ending, whether LF or CRLF
+func lineText(input string, offset int) string {
+	start := strings.LastIndex(input[:offset], "\n") + 1
+	end := strings.Index(input[offset:], "\n")
+	if end < 0 {
+		return strings.TrimSuffix(input[start:], "\r")
+	}
+	return strings.TrimSuffix(input[start:offset+end], "\r")
+}
+
+// column returns the 1-based byte column of the offset within its line, where the \r of a CRLF line ending is in the
+// same column as its \n
+func column(input string, offset int) int {
+	col := offset - (strings.LastIndex(input[:offset], "\n") + 1) + 1
+	if splitsCRLF(input, offset) {
+		col--
+	}
+	return col
+}
+
+// failure tracks the farthest position at which parts failed to match, along with the names of those parts
+type failure struct {
+	offset    int
+	line      int
+	partNames []string
+	// messages hold the explanations for failures at the offset, such as matches rejected by a validator
+	messages []string
+	// texts hold the texts of the literal and keyword parts that failed at the offset, which summarizing the failure
+	// keeps, so a misspelling of one can be suggested
+	texts    []string
+	semantic *ParseError
+	abort    error
+}
+
+// recordFailure notes the part that failed to match at the current position if it's the farthest failure so far
+func recordFailure(partName string, parser Parser) {
+	// parts failing within a lookahead don't make the parse fail
+	if parser.lookahead {
+		return
+	}
+	pos := *parser.currentPosPointer
+	switch {
+	case len(parser.failure.partNames) > 0 && pos < parser.failure.offset:
+		return
+	case pos > parser.failure.offset:
+		// a farther position replaces the parts that failed before it
+		parser.failure.partNames = nil
+		parser.failure.messages = nil
+		parser.failure.texts = nil
+	}
+	parser.failure.offset = pos
+	parser.failure.line = currentLine(parser)
+	// add the part name once
+	for _, name := range parser.failure.partNames {
+		if name == partName {
+			return
+		}
+	}
+	parser.failure.partNames = append(parser.failure.partNames, partName)
+}
+
+// recordInvalid notes the part whose match was rejected by its validator like recordFailure, keeping the validator's
+// message so it can be reported if this is the farthest failure
+func recordInvalid(partName string, message string, parser Parser) {
+	invalid := "invalid " + partName
+	if message != "" {
+		invalid = invalid + ": " + message
+	}
+	recordFailureMessage(partName, invalid, parser)
+}
+
+// recordFailureMessage notes the part that failed like recordFailure, keeping a message that explains the failure
+// better than the part itself so it can be reported if this is the farthest failure
+func recordFailureMessage(partName string, message string, parser Parser) {
+	if parser.lookahead {
+		return
+	}
+	recordFailure(partName, parser)
+	if parser.failure.offset != *parser.currentPosPointer {
+		return
+	}
+	parser.failure.messages = append(parser.failure.messages, message)
+}
+
+// recordSemanticError keeps the first error returned by a HandlerE so it can be reported if the parse fails
+func recordSemanticError(err error, part *Part, parser Parser, line int) {
+	if parser.failure.semantic != nil {
+		return
+	}
+	parser.failure.semantic = newParseError(parser, part.StartPos, line, part.Name, err.Error())
+	parser.failure.semantic.Err = err
+	parser.failure.semantic.length = part.EndPos - part.StartPos
+}
+
+// abortParse stops the parse with the error, keeping the first error if it has already been aborted
+func abortParse(err error, parser Parser) {
+	if parser.failure.abort == nil {
+		parser.failure.abort = err
+	}
+}
+
+// LimitError reports that a parse was stopped for making more attempts to find parts than Options.MaxAttempts
+// allows, where the ParseError gives the position reached, and PartName and PartAttempts give the part attempted
+// most often and how often, so callers can tell an input too complex for the budget from an invalid one
+type LimitError struct {
+	*ParseError
+	Attempts     int
+	PartAttempts int
+}
+
+// newLimitError returns the error for the parse exceeding its budget of attempts at the part at the current position
+func newLimitError(partName string, parser Parser) *LimitError {
+	limitError := &LimitError{Attempts: parser.maxAttempts}
+	mostAttempted := partName
+	for name, count := range parser.partAttempts {
+		// break ties by name so the part reported doesn't depend on the map's order
+		if count > parser.partAttempts[mostAttempted] || (count == parser.partAttempts[mostAttempted] && name < mostAttempted) {
+			mostAttempted = name
+		}
+	}
+	limitError.PartAttempts = parser.partAttempts[mostAttempted]
+	message := "parse complexity limit exceeded (" + strconv.Itoa(parser.maxAttempts) + " attempts, " + strconv.Itoa(limitError.PartAttempts) + " of them at " + mostAttempted + ")"
+	limitError.ParseError = newParseError(parser, *parser.currentPosPointer, currentLine(parser), mostAttempted, message)
+	return limitError
+}
+
+// failureMark provides a snapshot of the failure tracker taken before a part is attempted
+type failureMark struct {
+	offset int
+	count  int
+}
+
+// markFailure returns a snapshot of the failure tracker
+func markFailure(parser Parser) failureMark {
+	return failureMark{offset: parser.failure.offset, count: len(parser.failure.partNames)}
+}
+
+// summarizeFailure replaces the failures recorded at the current position since the mark with the part itself, so
+// described composite parts are reported instead of the constituents they failed to find
+func summarizeFailure(partName string, mark failureMark, parser Parser) {
+	pos := *parser.currentPosPointer
+	// only failures at the start of the part can be summarized
+	if len(parser.failure.partNames) < 1 || parser.failure.offset != pos {
+		return
+	}
+	if mark.offset == pos {
+		parser.failure.partNames = parser.failure.partNames[:mark.count]
+	} else {
+		parser.failure.partNames = nil
+	}
+	parser.failure.messages = nil
+	recordFailure(partName, parser)
+}
+
+// describePart returns the Description of the part for error messages, or its quoted text for a literal or keyword
+// part without one, "end of input" for an EOF part, or else its name
+func describePart(partName string, dialect *Dialect) string {
+	partDefinition := dialect.PartDefinitions[partName]
+	switch {
+	case partDefinition.Description != "":
+		return partDefinition.Description
+	case partDefinition.Literal != "":
+		return "'" + partDefinition.Literal + "'"
+	case partDefinition.Keyword != "":
+		return "keyword '" + partDefinition.Keyword + "'"
+	case partDefinition.EOF:
+		return "end of input"
+	}
+	return partName
+}
+
+// expectedError returns a ParseError describing the parts expected at the farthest failure
+func expectedError(parser Parser) *ParseError {
+	// describe each part by its Description when present, dropping duplicates
+	var expected []string
+	seen := make(map[string]bool)
+	for _, name := range parser.failure.partNames {
+		description := describePart(name, parser.dialect)
+		if !seen[description] {
+			seen[description] = true
+			expected = append(expected, description)
+		}
+	}
+	message := "expected " + expected[0]
+	if len(expected) > 1 {
+		message = "expected " + strings.Join(expected[:len(expected)-1], ", ") + " or " + expected[len(expected)-1]
+	}
+	// a rejected match or trailing input explains the failure better than the parts that were expected
+	if len(parser.failure.messages) > 0 {
+		message = strings.Join(parser.failure.messages, "; ")
+	}
+	parseError := newParseError(parser, parser.failure.offset, parser.failure.line, parser.failure.partNames[0], message)
+	parseError.Expected = expected
+	// a word close to an expected keyword is probably a misspelling of it
+	if suggestion, length := suggestText(parser); suggestion != "" {
+		parseError.Message = message + ", did you mean '" + suggestion + "'?"
+		parseError.Suggestion, parseError.length = suggestion, length
+	}
+	return parseError
+}
+
+// maxTrailingText provides the number of runes of trailing input quoted in error messages
+const maxTrailingText = 20
+
+// trailingText returns the quoted text of the rest of the line from the offset for error messages, shortened to
+// maxTrailingText runes
+func trailingText(input string, offset int) string {
+	text := input[offset:]
+	if end := strings.IndexByte(text, '\n'); end >= 0 {
+		text = strings.TrimSuffix(text[:end], "\r")
+	}
+	if utf8.RuneCountInString(text) > maxTrailingText {
+		text = string([]rune(text)[:maxTrailingText]) + "..."
+	}
+	return "'" + text + "'"
+}
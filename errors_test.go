@@ -0,0 +1,79 @@
+package dialects_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/AdamJonR/dialects"
+)
+
+func TestParseErrorSnippet(t *testing.T) {
+	_, err, _ := dialects.Parse(wordList(), "ab\n\tcd 12\nef")
+	var parseError *dialects.ParseError
+	if !errors.As(err, &parseError) {
+		t.Fatalf("expected a ParseError, got %v", err)
+	}
+	if parseError.Line != 2 || parseError.Column != 5 {
+		t.Errorf("expected line 2, column 5, got line %d, column %d", parseError.Line, parseError.Column)
+	}
+	// the tab is copied so the caret lines up
+	if snippet := parseError.Snippet(); snippet != "\tcd 12\n\t   ^" {
+		t.Errorf("unexpected snippet %q", snippet)
+	}
+	if !strings.HasSuffix(err.Error(), "\n"+parseError.Snippet()) {
+		t.Errorf("expected the error to end with the snippet, got %q", err.Error())
+	}
+}
+
+func TestParseErrorOmitSnippets(t *testing.T) {
+	g := wordList()
+	g.dialect.OmitSnippets = true
+	_, err, _ := dialects.Parse(g, "ab 12")
+	if err == nil || strings.Contains(err.Error(), "\n") {
+		t.Errorf("expected a single-line error, got %q", err)
+	}
+}
+
+func TestParseErrorSnippetClampsColumn(t *testing.T) {
+	for _, col := range []int{0, 1, 4, 100} {
+		parseError := &dialects.ParseError{Line: 1, Column: col, LineText: "abc"}
+		snippet := parseError.Snippet()
+		if !strings.HasPrefix(snippet, "abc\n") || !strings.HasSuffix(snippet, "^") {
+			t.Errorf("unexpected snippet %q for column %d", snippet, col)
+		}
+	}
+}
+
+// letters returns a grammar of whitespace-separated words of any letters
+func letters() grammar {
+	return newGrammar("root", map[string]dialects.PartDefinition{
+		"root": {Constituents: [][]string{{"item+"}}},
+		"item": {Constituents: [][]string{{"word", "ws?"}}},
+		"word": {Regex: `\pL+`},
+		"ws":   {Regex: `[ \n]+`, Ignore: true},
+	})
+}
+
+func TestParseErrorRunePositions(t *testing.T) {
+	input := "maß\ngröße straße 1"
+	_, err, _ := dialects.Parse(letters(), input)
+	var parseError *dialects.ParseError
+	if !errors.As(err, &parseError) {
+		t.Fatalf("expected a ParseError, got %v", err)
+	}
+	// without the option only the byte column is set
+	if parseError.Column != 17 || parseError.RuneColumn != 0 {
+		t.Errorf("expected byte column 17 and no rune column, got %d and %d", parseError.Column, parseError.RuneColumn)
+	}
+	_, err, _ = dialects.ParseWithOptions(letters(), input, dialects.Options{RunePositions: true})
+	if !errors.As(err, &parseError) {
+		t.Fatalf("expected a ParseError, got %v", err)
+	}
+	if parseError.Offset != 21 || parseError.RuneOffset != 17 || parseError.Column != 17 || parseError.RuneColumn != 14 {
+		t.Errorf("expected offset 21 (rune 17) and column 17 (rune 14), got %d (%d) and %d (%d)", parseError.Offset, parseError.RuneOffset, parseError.Column, parseError.RuneColumn)
+	}
+	if !strings.Contains(err.Error(), "at line 2, column 14 (offset 21)") {
+		t.Errorf("expected the message to use the rune column, got %q", err)
+	}
+}
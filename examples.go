@@ -0,0 +1,69 @@
+package dialects
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// ExampleResult provides the outcome of parsing one of a dialect's examples, or counter-examples when Counter is set,
+// where Expected and HasExpected give the example's entry in Dialect.ExpectedOutputs, or the counter-example's entry
+// in Dialect.ExpectedErrors, if any, and Log holds the trace log even when the parse fails
+type ExampleResult struct {
+	Name        string
+	Input       string
+	Output      string
+	Expected    string
+	HasExpected bool
+	Err         error
+	Log         string
+	Counter     bool
+}
+
+// Failed returns whether the example failed to parse or generated other than its expected output, or whether the
+// counter-example parsed or failed with an error not containing its expected text, where either fails if the dialect
+// doesn't compile
+func (result ExampleResult) Failed() bool {
+	if !result.Counter {
+		return result.Err != nil || (result.HasExpected && result.Output != result.Expected)
+	}
+	var dialectError *DialectError
+	return result.Err == nil || errors.As(result.Err, &dialectError) || (result.HasExpected && !strings.Contains(result.Err.Error(), result.Expected))
+}
+
+// TestExamples parses each of the dialect's Examples, mapping names to inputs, returning their results in order of
+// name, where every result holds the error if the dialect doesn't compile
+func TestExamples(dialectable Dialectable) []ExampleResult {
+	dialect := dialectable.NewDialect()
+	return testInputs(dialectable, dialect.Examples, dialect.ExpectedOutputs, false)
+}
+
+// TestCounterExamples parses each of the dialect's CounterExamples, mapping names to inputs that should fail to parse,
+// returning their results in order of name, where every result holds the error if the dialect doesn't compile
+func TestCounterExamples(dialectable Dialectable) []ExampleResult {
+	dialect := dialectable.NewDialect()
+	return testInputs(dialectable, dialect.CounterExamples, dialect.ExpectedErrors, true)
+}
+
+// testInputs returns the results of parsing the named inputs with the dialect, in order of name
+func testInputs(dialectable Dialectable, inputs map[string]string, expected map[string]string, counter bool) []ExampleResult {
+	names := make([]string, 0, len(inputs))
+	for name := range inputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	compiled, err := Compile(dialectable)
+	results := make([]ExampleResult, 0, len(names))
+	for _, name := range names {
+		result := ExampleResult{Name: name, Input: inputs[name], Err: err, Counter: counter}
+		result.Expected, result.HasExpected = expected[name]
+		if err == nil {
+			// write the log as the parse goes, since it isn't returned when the parse fails
+			log := &strings.Builder{}
+			result.Output, result.Err, _ = compiled.ParseWithOptions(result.Input, Options{TraceWriter: log})
+			result.Log = log.String()
+		}
+		results = append(results, result)
+	}
+	return results
+}
@@ -0,0 +1,63 @@
+package dialects_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/AdamJonR/dialects"
+)
+
+func TestTestExamples(t *testing.T) {
+	g := wordList()
+	g.dialect.Examples = map[string]string{"three": "ab cd ef", "one": "ab", "number": "ab 1"}
+	g.dialect.ExpectedOutputs = map[string]string{"three": "item,item,item", "one": "item,item"}
+	results := dialects.TestExamples(g)
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	number, one, three := results[0], results[1], results[2]
+	if number.Name != "number" || !number.Failed() || number.HasExpected || number.Err == nil || !strings.Contains(number.Log, "missing word") {
+		t.Errorf("expected number to fail to parse with its log, got %+v", number)
+	}
+	if one.Name != "one" || !one.Failed() || one.Err != nil || one.Output != "item" || one.Expected != "item,item" {
+		t.Errorf("expected one to fail on its output, got %+v", one)
+	}
+	if three.Name != "three" || three.Failed() || three.Input != "ab cd ef" {
+		t.Errorf("expected three to pass, got %+v", three)
+	}
+}
+
+func TestTestExamplesInvalidDialect(t *testing.T) {
+	g := newGrammar("root", map[string]dialects.PartDefinition{"root": {Regex: `[`}})
+	g.dialect.Examples = map[string]string{"any": "x"}
+	results := dialects.TestExamples(g)
+	if len(results) != 1 || !results[0].Failed() || !strings.Contains(results[0].Err.Error(), "invalid regex") {
+		t.Errorf("expected the example to fail with the dialect error, got %+v", results)
+	}
+}
+
+func TestTestCounterExamples(t *testing.T) {
+	g := wordList()
+	g.dialect.CounterExamples = map[string]string{"number": "ab 1", "misplaced": "ab 1", "valid": "ab"}
+	g.dialect.ExpectedErrors = map[string]string{"number": "line 1, column 4", "misplaced": "column 1"}
+	results := dialects.TestCounterExamples(g)
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	misplaced, number, valid := results[0], results[1], results[2]
+	if !misplaced.Counter || !misplaced.Failed() || misplaced.Err == nil {
+		t.Errorf("expected misplaced to fail on its error, got %+v", misplaced)
+	}
+	if number.Failed() || !number.HasExpected {
+		t.Errorf("expected number to be rejected where expected, got %+v", number)
+	}
+	if !valid.Failed() || valid.Err != nil || valid.Output != "item" {
+		t.Errorf("expected valid to fail by parsing, got %+v", valid)
+	}
+	// a dialect that doesn't compile fails its counter-examples rather than rejecting them
+	g = newGrammar("root", map[string]dialects.PartDefinition{"root": {Regex: `[`}})
+	g.dialect.CounterExamples = map[string]string{"any": "x"}
+	if results := dialects.TestCounterExamples(g); len(results) != 1 || !results[0].Failed() {
+		t.Errorf("expected the counter-example to fail with the dialect error, got %+v", results)
+	}
+}
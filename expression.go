@@ -0,0 +1,183 @@
+package dialects
+
+// OperatorPartName provides the name of the parts holding the operators found by an expression part
+const OperatorPartName = "$operator"
+
+// OpLevel provides the operators sharing a precedence level of an expression part, which group from the left unless
+// RightAssociative is set
+type OpLevel struct {
+	Operators        []string
+	RightAssociative bool
+}
+
+// expression provides the operand part of an expression part and its operator levels, ordered from lowest to highest
+// precedence
+type expression struct {
+	operand string
+	levels  []OpLevel
+}
+
+// ExpressionPart returns the definition of a part matching operands joined by the operators of the levels, which are
+// ordered from lowest to highest precedence, nesting the parts found so each operator's part holds its operands, as in
+// 1+2*3 where the part for * is within the part for +
+func ExpressionPart(operand string, levels []OpLevel) PartDefinition {
+	return PartDefinition{Description: "expression", expression: &expression{operand: operand, levels: levels}}
+}
+
+// findExpression returns the expression part, finding its operands and operators in order and then nesting them by
+// precedence, so no part is needed for each level of precedence
+func findExpression(partDefinition PartDefinition, part *Part, parser Parser, start state) []*Part {
+	// each operand and operator is kept along with any skipped text found before it
+	operands := [][]*Part{findOne(partDefinition.expression.operand, parser, part)}
+	if len(operands[0]) < 1 {
+		return nil
+	}
+	var operators [][]*Part
+	var levels []int
+	for {
+		beforeOperator := saveState(parser)
+		operator, level := findOperator(partDefinition, parser, part)
+		if operator == nil {
+			break
+		}
+		operand := findOne(partDefinition.expression.operand, parser, part)
+		// an operator without an operand after it isn't part of the expression
+		if len(operand) < 1 {
+			restoreState(beforeOperator, parser)
+			break
+		}
+		operators = append(operators, operator)
+		levels = append(levels, level)
+		operands = append(operands, operand)
+	}
+	// nest the operands and operators by precedence climbing, where next indexes the next operator
+	var nodes []*Part
+	next := 0
+	var climb func(minLevel int) []*Part
+	climb = func(minLevel int) []*Part {
+		left := operands[next]
+		for next < len(operators) && levels[next] >= minLevel {
+			operator, level := operators[next], levels[next]
+			next++
+			// operators of the same level group from the left unless they're right associative
+			nextLevel := level + 1
+			if partDefinition.expression.levels[level].RightAssociative {
+				nextLevel = level
+			}
+			right := climb(nextLevel)
+			left = nestOperation(left, operator, right, part, parser)
+			nodes = append(nodes, left[len(left)-1])
+		}
+		return left
+	}
+	top := climb(0)
+	// the outermost operation is the expression part itself
+	if len(nodes) > 0 {
+		nodes = nodes[:len(nodes)-1]
+		top = top[len(top)-1].Constituents
+	}
+	for _, constituent := range top {
+		if !constituent.Ignore || parser.keepIgnored {
+			part.Constituents = append(part.Constituents, constituent)
+		}
+	}
+	reparent(part, parser)
+	part.EndPos = *parser.currentPosPointer
+	part.EndLine, part.EndCol = currentLine(parser), parser.cursor.column
+	part.EndRune = parser.cursor.runes
+	part.Value = parser.input[part.StartPos:part.EndPos]
+	part.Source = part.Value
+	// call Handler for each operation from the innermost out, as for nested composite parts
+	for _, node := range append(nodes, part) {
+		if !callHandler(partDefinition, node, parser, start) {
+			return nil
+		}
+		streamParts([]*Part{node}, parser)
+	}
+	return []*Part{part}
+}
+
+// findOperator returns the longest operator of the expression part at the current position, skipping text first like
+// findPart, along with the index of its precedence level
+func findOperator(partDefinition PartDefinition, parser Parser, parent *Part) (operator []*Part, level int) {
+	start := saveState(parser)
+	var skipped []*Part
+	if skipping(parser) && !parser.noSkip {
+		skipped = skipText(parser, parent)
+	}
+	match := ""
+	ignoreCase := caseInsensitive(partDefinition, parser.dialect)
+	for i, opLevel := range partDefinition.expression.levels {
+		for _, text := range opLevel.Operators {
+			if found, ok := matchText(text, ignoreCase, parser); ok && len(found) > len(match) {
+				match, level = found, i
+			}
+		}
+	}
+	if match == "" {
+		restoreState(start, parser)
+		return nil, 0
+	}
+	part := &Part{
+		Name:      OperatorPartName,
+		Parent:    parent,
+		Value:     match,
+		Found:     true,
+		Source:    match,
+		StartPos:  *parser.currentPosPointer,
+		StartLine: currentLine(parser),
+		StartCol:  parser.cursor.column,
+		StartRune: parser.cursor.runes,
+	}
+	recordToken(OperatorPartName, false, match, parser)
+	advance(match, parser)
+	part.EndPos = (*parser.currentPosPointer)
+	part.EndLine, part.EndCol = currentLine(parser), parser.cursor.column
+	part.EndRune = parser.cursor.runes
+	return append(skipped, part), level
+}
+
+// nestOperation returns a part for the operation with the operator between the left and right operands, preceded by
+// the text skipped before the left operand, dropping Ignored parts unless keeping them
+func nestOperation(left []*Part, operator []*Part, right []*Part, expressionPart *Part, parser Parser) []*Part {
+	first, last := left[len(left)-1], right[len(right)-1]
+	node := &Part{
+		Name:      expressionPart.Name,
+		Ignore:    expressionPart.Ignore,
+		StartPos:  first.StartPos,
+		StartLine: first.StartLine,
+		StartCol:  first.StartCol,
+		StartRune: first.StartRune,
+		EndPos:    last.EndPos,
+		EndLine:   last.EndLine,
+		EndCol:    last.EndCol,
+		EndRune:   last.EndRune,
+		Value:     parser.input[first.StartPos:last.EndPos],
+		Found:     true,
+	}
+	node.Source = node.Value
+	for _, constituent := range append(append([]*Part{first}, operator...), right...) {
+		if !constituent.Ignore || parser.keepIgnored {
+			node.Constituents = append(node.Constituents, constituent)
+		}
+	}
+	return append(left[:len(left)-1:len(left)-1], node)
+}
+
+// reparent sets the Parent and Path of the descendants of the part, which were found before the operations holding
+// them were known
+func reparent(part *Part, parser Parser) {
+	for _, constituent := range part.Constituents {
+		constituent.Parent = part
+		constituent.Path = childPath(part, parser)
+		reparent(constituent, parser)
+	}
+}
+
+// ExpressionOperand returns the operand part of a part defined by ExpressionPart, with ok reporting whether it is one
+func ExpressionOperand(partDefinition PartDefinition) (operand string, ok bool) {
+	if partDefinition.expression == nil {
+		return "", false
+	}
+	return partDefinition.expression.operand, true
+}
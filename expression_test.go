@@ -0,0 +1,123 @@
+package dialects_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/AdamJonR/dialects"
+)
+
+// operations returns a grammar of arithmetic expressions, with ^ grouping from the right
+func operations() grammar {
+	g := newGrammar("expr", map[string]dialects.PartDefinition{
+		"expr": dialects.ExpressionPart("operand", []dialects.OpLevel{
+			{Operators: []string{"+", "-"}},
+			{Operators: []string{"*", "/", "**"}},
+			{Operators: []string{"^"}, RightAssociative: true},
+		}),
+		"operand": {Constituents: [][]string{{"number"}, {"open", "expr", "close"}}},
+		"number":  {Regex: `[0-9]+`},
+		"open":    {Literal: "(", Ignore: true},
+		"close":   {Literal: ")", Ignore: true},
+	})
+	g.dialect.SkipPattern = `[ ]+`
+	return g
+}
+
+// nesting returns the operations of the expression part in parentheses, so 1+2*3 becomes (1+(2*3))
+func nesting(part *dialects.Part) string {
+	switch part.Name {
+	case "expr":
+		if len(part.Constituents) == 1 {
+			return nesting(part.Constituents[0])
+		}
+		var b strings.Builder
+		b.WriteString("(")
+		for _, constituent := range part.Constituents {
+			b.WriteString(nesting(constituent))
+		}
+		b.WriteString(")")
+		return b.String()
+	case "operand":
+		return nesting(part.Constituents[0])
+	}
+	return part.Value
+}
+
+func TestExpressionPart(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"7", "7"},
+		{"1+2*3", "(1+(2*3))"},
+		{"1*2+3", "((1*2)+3)"},
+		{"5-3-1", "((5-3)-1)"},
+		{"2^3^2", "(2^(3^2))"},
+		{"1 + 2 ** 3 - 4", "((1+(2**3))-4)"},
+		{"(1+2)*3", "((1+2)*3)"},
+		{"1+2^3*4", "(1+((2^3)*4))"},
+	}
+	for _, test := range tests {
+		root, err, _ := dialects.ParseToTree(operations(), test.input)
+		if err != nil {
+			t.Errorf("%s: %v", test.input, err)
+			continue
+		}
+		if actual := nesting(root); actual != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.input, test.expected, actual)
+		}
+	}
+}
+
+func TestExpressionPartTree(t *testing.T) {
+	root, err, _ := dialects.ParseToTree(operations(), "1 + 2 * 3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root.Value != "1 + 2 * 3" || len(root.Constituents) != 3 {
+		t.Fatalf("expected the whole input with three constituents, got %q with %d", root.Value, len(root.Constituents))
+	}
+	operator, product := root.Constituents[1], root.Constituents[2]
+	if operator.Name != dialects.OperatorPartName || operator.Value != "+" || operator.StartCol != 3 {
+		t.Errorf("expected the + operator at column 3, got %s %q at column %d", operator.Name, operator.Value, operator.StartCol)
+	}
+	if product.Name != "expr" || product.Value != "2 * 3" || product.Parent != root || product.StartCol != 5 {
+		t.Errorf("expected the product within the sum from column 5, got %s %q at column %d", product.Name, product.Value, product.StartCol)
+	}
+	if path := product.Constituents[0].Path; len(path) != 2 || path[1] != "expr" {
+		t.Errorf("expected the product's operand within two expressions, got %v", path)
+	}
+}
+
+func TestExpressionPartHandlers(t *testing.T) {
+	g := operations()
+	var handled []string
+	expr := g.dialect.PartDefinitions["expr"]
+	expr.Handler = func(part *dialects.Part, model interface{}) bool {
+		handled = append(handled, part.Value)
+		return true
+	}
+	g.dialect.PartDefinitions["expr"] = expr
+	if _, err, _ := dialects.Parse(g, "1*2+3"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(handled, ",") != "1*2,1*2+3" {
+		t.Errorf("expected the inner operation to be handled first, got %v", handled)
+	}
+}
+
+func TestExpressionPartTrailingOperator(t *testing.T) {
+	g := operations()
+	if _, err, _ := dialects.Parse(g, "1+2*"); err == nil || !strings.Contains(err.Error(), "expected number or '(' at line 1, column 5") {
+		t.Errorf("expected the missing operand to be reported, got %v", err)
+	}
+	g.dialect.AllowTrailing = true
+	root, err, _ := dialects.ParseToTree(g, "1+2*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := nesting(root); actual != "(1+2)" {
+		t.Errorf("expected (1+2), got %s", actual)
+	}
+}
@@ -0,0 +1,329 @@
+package dialects
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// grammarNotation provides the dialect of the text LoadGrammar reads, in which each rule names a part and gives its
+// alternatives, as in `pair = key "=" value | key ;`
+type grammarNotation struct{}
+
+func (grammarNotation) NewDialect() *Dialect {
+	return &Dialect{
+		Title:    "grammar",
+		RootName: "grammar",
+		PartDefinitions: map[string]PartDefinition{
+			"grammar":      {Constituents: [][]string{{"rule+"}}},
+			"rule":         {Constituents: [][]string{{"annotation*", "name", "equals", "alternatives", "semicolon"}}},
+			"annotation":   {Regex: `@[a-z]+`, Description: "annotation"},
+			"name":         {Regex: `[A-Za-z_][A-Za-z0-9_.]*`, Description: "rule name"},
+			"equals":       {Literal: "="},
+			"alternatives": {Constituents: [][]string{{"sequence%bar+"}}},
+			"bar":          {Literal: "|"},
+			"sequence":     {Constituents: [][]string{{"element+"}}},
+			"element":      {Constituents: [][]string{{"prefix?", "primary", "suffix?"}}},
+			"prefix":       {Regex: `[!&]`},
+			"primary":      {Constituents: [][]string{{"literal"}, {"regex"}, {"reference"}}},
+			"literal":      {Regex: `"(?:[^"\\\n]|\\.)*"`, Description: "quoted literal"},
+			"regex":        {Regex: `/(?:[^/\\\n]|\\.)*/`, Description: "regex"},
+			"reference":    {Regex: `[A-Za-z_][A-Za-z0-9_.]*`, Description: "part name"},
+			"suffix":       {Regex: `[?*+]`},
+			"semicolon":    {Literal: ";"},
+		},
+		SkipPattern: `\s+`,
+		LineComment: "#",
+	}
+}
+
+func (grammarNotation) NewModel() interface{} {
+	return nil
+}
+
+func (grammarNotation) GenerateOutput(model interface{}) (string, error) {
+	return "", nil
+}
+
+// GrammarOption configures how LoadGrammar builds the part definitions of a grammar
+type GrammarOption func(loader *grammarLoader)
+
+// grammarLoader provides the part definitions being built from the rules of a grammar
+type grammarLoader struct {
+	handlers map[string]func(*Part, interface{}) bool
+	dialect  *Dialect
+	errs     []error
+}
+
+// WithHandlers attaches the handlers to the parts of the grammar they're named after, as handlers can't be written in
+// the grammar's text
+func WithHandlers(handlers map[string]func(*Part, interface{}) bool) GrammarOption {
+	return func(loader *grammarLoader) {
+		loader.handlers = handlers
+	}
+}
+
+// LoadGrammar returns the dialect whose parts are defined by the rules of the grammar text, where the first rule is
+// the root, returning the ParseError if the text can't be read, or the problems with the grammar joined into one
+// error, including those ValidateDialect finds; a rule names a part followed by "=", its alternatives separated by
+// "|", and ";", as in `pair = key ws? "=" ws? value ;`, where each constituent is a part name, a quoted literal, or a
+// regex between slashes, optionally preceded by a ! or & lookahead or followed by a ?, *, or + modifier, a rule
+// that's just a literal or a regex defines a Literal or Regex part, and an @ignore annotation before a rule makes
+// its part Ignored
+func LoadGrammar(src string, options ...GrammarOption) (*Dialect, error) {
+	tree, err, _ := ParseToTreeWithOptions(grammarNotation{}, src, Options{NoTrace: true})
+	if err != nil {
+		return nil, err
+	}
+	loader := &grammarLoader{dialect: &Dialect{PartDefinitions: make(map[string]PartDefinition)}}
+	for _, option := range options {
+		option(loader)
+	}
+	for _, rule := range tree.Constituents {
+		loader.addRule(rule)
+		if loader.dialect.RootName == "" {
+			loader.dialect.RootName = childrenNamed(rule, "name")[0].Value
+		}
+	}
+	handled := make([]string, 0, len(loader.handlers))
+	for name := range loader.handlers {
+		handled = append(handled, name)
+	}
+	sort.Strings(handled)
+	for _, name := range handled {
+		partDefinition, ok := loader.dialect.PartDefinitions[name]
+		if !ok {
+			loader.errs = append(loader.errs, &DialectError{PartName: name, Message: "has a handler but isn't defined by the grammar"})
+			continue
+		}
+		partDefinition.Handler = loader.handlers[name]
+		loader.dialect.PartDefinitions[name] = partDefinition
+	}
+	errs := append(loader.errs, ValidateDialect(loader.dialect)...)
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return loader.dialect, nil
+}
+
+// addRule defines the part named by the rule
+func (loader *grammarLoader) addRule(rule *Part) {
+	name := childrenNamed(rule, "name")[0].Value
+	if _, ok := loader.dialect.PartDefinitions[name]; ok {
+		loader.errs = append(loader.errs, &DialectError{PartName: name, Message: "is defined by more than one rule"})
+		return
+	}
+	var partDefinition PartDefinition
+	for _, annotation := range childrenNamed(rule, "annotation") {
+		if annotation.Value != "@ignore" {
+			loader.errs = append(loader.errs, &DialectError{PartName: name, Message: "has unknown annotation " + annotation.Value})
+			continue
+		}
+		partDefinition.Ignore = true
+	}
+	sequences := childrenNamed(childrenNamed(rule, "alternatives")[0], "sequence")
+	// a rule of just a literal or a regex defines the part itself rather than a part of it
+	if elements := sequences[0].Constituents; len(sequences) == 1 && len(elements) == 1 && len(elements[0].Constituents) == 1 {
+		switch terminal := elements[0].Constituents[0].Constituents[0]; terminal.Name {
+		case "literal":
+			partDefinition.Literal = loader.unquote(name, terminal.Value)
+		case "regex":
+			partDefinition.Regex = unslash(terminal.Value)
+		}
+	}
+	if partDefinition.Literal == "" && partDefinition.Regex == "" {
+		for _, sequence := range sequences {
+			var constituentSeq []string
+			for _, element := range sequence.Constituents {
+				constituentSeq = append(constituentSeq, loader.constituentID(name, element))
+			}
+			partDefinition.Constituents = append(partDefinition.Constituents, constituentSeq)
+		}
+	}
+	loader.dialect.PartDefinitions[name] = partDefinition
+}
+
+// constituentID returns the constituent ID of the element of a rule, defining a part for its literal or regex
+func (loader *grammarLoader) constituentID(ruleName string, element *Part) string {
+	var id string
+	for _, part := range element.Constituents {
+		switch part.Name {
+		case "prefix", "suffix":
+			id += part.Value
+		case "primary":
+			switch terminal := part.Constituents[0]; terminal.Name {
+			case "literal":
+				text := loader.unquote(ruleName, terminal.Value)
+				id += literalPartName(text)
+				loader.dialect.PartDefinitions[literalPartName(text)] = PartDefinition{Literal: text}
+			case "regex":
+				regex := unslash(terminal.Value)
+				id += regexPartName(regex)
+				loader.dialect.PartDefinitions[regexPartName(regex)] = PartDefinition{Regex: regex}
+			default:
+				id += terminal.Value
+			}
+		}
+	}
+	return id
+}
+
+// unquote returns the text of the quoted literal, noting an error for the rule if its escapes are invalid
+func (loader *grammarLoader) unquote(ruleName string, quoted string) string {
+	text, err := strconv.Unquote(quoted)
+	if err != nil {
+		loader.errs = append(loader.errs, &DialectError{PartName: ruleName, Message: "has an invalid literal " + quoted, Err: err})
+	}
+	return text
+}
+
+// unslash returns the regex between the slashes, with escaped slashes unescaped
+func unslash(slashed string) string {
+	var b strings.Builder
+	inner := slashed[1 : len(slashed)-1]
+	for i := 0; i < len(inner); i++ {
+		if inner[i] == '\\' && i+1 < len(inner) {
+			if inner[i+1] != '/' {
+				b.WriteByte('\\')
+			}
+			i++
+		}
+		b.WriteByte(inner[i])
+	}
+	return b.String()
+}
+
+// literalPartName returns the name of the part LoadGrammar defines for a literal within a rule, the text in single
+// quotes, with backslashes and percent signs escaped so the name can't be read as having a separator
+func literalPartName(text string) string {
+	return "'" + escapePartName(text) + "'"
+}
+
+// regexPartName returns the name of the part LoadGrammar defines for a regex within a rule, the regex between slashes
+func regexPartName(regex string) string {
+	return "/" + escapePartName(regex) + "/"
+}
+
+// escapePartName returns the text with backslashes and percent signs escaped
+func escapePartName(text string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(text, `\`, `\\`), "%", `\x25`)
+}
+
+// childrenNamed returns the constituents of the part with the name
+func childrenNamed(part *Part, name string) []*Part {
+	var children []*Part
+	for _, child := range part.Constituents {
+		if child.Name == name {
+			children = append(children, child)
+		}
+	}
+	return children
+}
+
+// FormatGrammar returns the text of the dialect's grammar in the notation LoadGrammar reads, starting with the root
+// part, then the other parts in order of name, with the parts LoadGrammar defines for literals and regexes within
+// rules written in place, returning a DialectError for a part the notation can't express, such as a Keyword part,
+// a part with a validator, or a constituent with bounds, leaving out handlers, which WithHandlers attaches
+func FormatGrammar(d *Dialect) (string, error) {
+	if _, ok := d.PartDefinitions[d.RootName]; !ok {
+		return "", &DialectError{PartName: d.RootName, Message: "is the root part but is not defined"}
+	}
+	names := []string{d.RootName}
+	for _, name := range sortedPartNames(d) {
+		if name != d.RootName && !inlinedPart(name, d.PartDefinitions[name]) {
+			names = append(names, name)
+		}
+	}
+	var b strings.Builder
+	for _, name := range names {
+		partDefinition := d.PartDefinitions[name]
+		expressible := partDefinition
+		expressible.Constituents, expressible.Literal, expressible.Regex, expressible.Ignore, expressible.Handler, expressible.HandlerE = nil, "", "", false, nil, nil
+		if !samePartDefinition(expressible, PartDefinition{}, false) {
+			return "", &DialectError{PartName: name, Message: "can't be written in the grammar notation, which only has constituents, literals, regexes, and Ignore"}
+		}
+		if partDefinition.Ignore {
+			b.WriteString("@ignore ")
+		}
+		b.WriteString(name + " =")
+		switch {
+		case partDefinition.Literal != "":
+			b.WriteString(" " + strconv.Quote(partDefinition.Literal))
+		case partDefinition.Regex != "":
+			b.WriteString(" " + slash(partDefinition.Regex))
+		}
+		for i, constituentSeq := range partDefinition.Constituents {
+			if i > 0 {
+				b.WriteString(" |")
+			}
+			for _, constituentID := range constituentSeq {
+				text, ok := formatConstituent(constituentID, d)
+				if !ok {
+					return "", &DialectError{PartName: name, Message: "has constituent " + strconv.Quote(constituentID) + ", which can't be written in the grammar notation"}
+				}
+				b.WriteString(" " + text)
+			}
+		}
+		b.WriteString(" ;\n")
+	}
+	return b.String(), nil
+}
+
+// inlinedPart reports whether the part is one LoadGrammar defines for a literal or regex within a rule, which
+// FormatGrammar writes in place
+func inlinedPart(name string, partDefinition PartDefinition) bool {
+	switch {
+	case partDefinition.Literal != "":
+		return name == literalPartName(partDefinition.Literal) && samePartDefinition(partDefinition, PartDefinition{Literal: partDefinition.Literal}, false)
+	case partDefinition.Regex != "":
+		return name == regexPartName(partDefinition.Regex) && samePartDefinition(partDefinition, PartDefinition{Regex: partDefinition.Regex}, false)
+	}
+	return false
+}
+
+// formatConstituent returns the constituent ID in the grammar notation, with ok reporting whether the notation can
+// express its modifier
+func formatConstituent(constituentID string, d *Dialect) (text string, ok bool) {
+	reference := parseConstituent(constituentID)
+	prefix, suffix := "", ""
+	switch reference.modifier {
+	case "":
+	case "!", "&":
+		prefix = reference.modifier
+	case "?", "*", "+":
+		if reference.separator != "" {
+			return "", false
+		}
+		suffix = reference.modifier
+	default:
+		return "", false
+	}
+	text = reference.name
+	if partDefinition := d.PartDefinitions[reference.name]; inlinedPart(reference.name, partDefinition) {
+		text = slash(partDefinition.Regex)
+		if partDefinition.Literal != "" {
+			text = strconv.Quote(partDefinition.Literal)
+		}
+	}
+	return prefix + text + suffix, true
+}
+
+// slash returns the regex between slashes, with its slashes escaped, whether they were escaped in the regex or not
+func slash(regex string) string {
+	var b strings.Builder
+	b.WriteByte('/')
+	for i := 0; i < len(regex); i++ {
+		switch {
+		case regex[i] == '/':
+			b.WriteString(`\/`)
+		case regex[i] == '\\' && i+1 < len(regex):
+			b.WriteString(regex[i : i+2])
+			i++
+		default:
+			b.WriteByte(regex[i])
+		}
+	}
+	b.WriteByte('/')
+	return b.String()
+}
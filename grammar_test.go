@@ -0,0 +1,93 @@
+package dialects_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/AdamJonR/dialects"
+)
+
+// pairsText provides a grammar of key=value pairs in the notation LoadGrammar reads
+const pairsText = `# pairs separated by commas
+pairs = pair more* ;
+more = "," ws? pair ;
+pair = key ws? "=" ws? value | !"=" key ;
+key = /[a-z]+/ ;
+value = /[0-9]+/ | /"[a-z\/]*"/ ;
+@ignore ws = /[ \t]+/ ;
+`
+
+func TestLoadGrammar(t *testing.T) {
+	var keys []string
+	d, err := dialects.LoadGrammar(pairsText, dialects.WithHandlers(map[string]func(*dialects.Part, interface{}) bool{
+		"key": func(part *dialects.Part, model interface{}) bool {
+			keys = append(keys, part.Value)
+			return true
+		},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.RootName != "pairs" || !d.PartDefinitions["ws"].Ignore || d.PartDefinitions["key"].Regex != `[a-z]+` || d.PartDefinitions["'='"].Literal != "=" {
+		t.Errorf("expected the rules to define the parts, got %+v", d.PartDefinitions)
+	}
+	// the escaped slash is unescaped, and other escapes are kept
+	if regex := d.PartDefinitions["value"].Constituents[1][0]; d.PartDefinitions[regex].Regex != `"[a-z/]*"` {
+		t.Errorf("expected the quoted value's regex, got %q", d.PartDefinitions[regex].Regex)
+	}
+	if strings.Join(d.PartDefinitions["pair"].Constituents[1], ", ") != "!'=', key" {
+		t.Errorf("expected the lookahead of a literal, got %v", d.PartDefinitions["pair"].Constituents)
+	}
+	g := newGrammar(d.RootName, d.PartDefinitions)
+	g.dialect.DeferHandlers = true
+	_, err, _ = dialects.ParseToTree(g, `a=1, b = "x/y",c`)
+	if err != nil || strings.Join(keys, ",") != "a,b,c" {
+		t.Errorf("expected the loaded grammar to parse, got %q and %v", keys, err)
+	}
+}
+
+func TestFormatGrammar(t *testing.T) {
+	d, err := dialects.LoadGrammar(pairsText)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err := dialects.FormatGrammar(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "grammar.txt", []byte(text))
+	// loading the text again gives the same grammar, which formats to the same text
+	reloaded, err := dialects.LoadGrammar(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(reloaded, d) {
+		t.Errorf("expected the reloaded grammar to match, got %+v rather than %+v", reloaded, d)
+	}
+	if again, _ := dialects.FormatGrammar(reloaded); again != text {
+		t.Errorf("expected the reloaded grammar to format the same, got %q rather than %q", again, text)
+	}
+	// parts beyond the notation are reported rather than left out
+	d.PartDefinitions["key"] = dialects.PartDefinition{Keyword: "key"}
+	var dialectError *dialects.DialectError
+	if _, err := dialects.FormatGrammar(d); !errors.As(err, &dialectError) || dialectError.PartName != "key" {
+		t.Errorf("expected a DialectError for the keyword part, got %v", err)
+	}
+}
+
+func TestLoadGrammarErrors(t *testing.T) {
+	_, err := dialects.LoadGrammar("pairs = pair ;\npair = key \"=\" ;\n")
+	if err == nil || !strings.Contains(err.Error(), "part (pair) references undefined part (key)") {
+		t.Errorf("expected the undefined part to be reported, got %v", err)
+	}
+	var parseError *dialects.ParseError
+	if _, err := dialects.LoadGrammar("pairs = pair\npair = key ;\n"); !errors.As(err, &parseError) || parseError.Line != 2 {
+		t.Errorf("expected a ParseError on line 2 for the missing semicolon, got %v", err)
+	}
+	_, err = dialects.LoadGrammar("pairs = /a/ ;\n", dialects.WithHandlers(map[string]func(*dialects.Part, interface{}) bool{"pair": nil}))
+	if err == nil || !strings.Contains(err.Error(), "part (pair) has a handler but isn't defined by the grammar") {
+		t.Errorf("expected the handler of an undefined part to be reported, got %v", err)
+	}
+}
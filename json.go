@@ -0,0 +1,80 @@
+package dialects
+
+import "encoding/json"
+
+// partJSON provides the JSON form of a Part, which leaves out Parent and Path since they're implied by the nesting,
+// marks the parts the parser didn't find rather than those it did, which are most of them, and gives the Source only
+// where it differs from the Value
+type partJSON struct {
+	Name         string      `json:"name"`
+	StartPos     int         `json:"startPos"`
+	EndPos       int         `json:"endPos"`
+	Value        string      `json:"value,omitempty"`
+	Source       *string     `json:"source,omitempty"`
+	Ignore       bool        `json:"ignore,omitempty"`
+	Synthesized  bool        `json:"synthesized,omitempty"`
+	Constituents []*partJSON `json:"constituents,omitempty"`
+}
+
+// PartToJSON returns the JSON encoding of the part and its constituents, leaving out the Values of the parts unless
+// includeValues is set, which keeps the encoding small for huge inputs
+func PartToJSON(p *Part, includeValues bool) ([]byte, error) {
+	return json.Marshal(toPartJSON(p, includeValues))
+}
+
+// MarshalJSON returns the JSON encoding of the part and its constituents, including their Values
+func (part *Part) MarshalJSON() ([]byte, error) {
+	return PartToJSON(part, true)
+}
+
+// UnmarshalJSON decodes the part and its constituents, setting the Parent and Path of each constituent
+func (part *Part) UnmarshalJSON(data []byte) error {
+	var decoded partJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	fromPartJSON(&decoded, part, nil)
+	return nil
+}
+
+// toPartJSON returns the JSON form of the part, which is built in full before encoding so nested parts aren't
+// encoded again at every level
+func toPartJSON(p *Part, includeValues bool) *partJSON {
+	if p == nil {
+		return nil
+	}
+	encoded := &partJSON{Name: p.Name, StartPos: p.StartPos, EndPos: p.EndPos, Ignore: p.Ignore, Synthesized: !p.Found}
+	if includeValues {
+		encoded.Value = p.Value
+		if p.Found && p.Source != p.Value {
+			source := p.Source
+			encoded.Source = &source
+		}
+	}
+	for _, constituent := range p.Constituents {
+		encoded.Constituents = append(encoded.Constituents, toPartJSON(constituent, includeValues))
+	}
+	return encoded
+}
+
+// fromPartJSON sets the part from its JSON form, linking it and its constituents to their parents
+func fromPartJSON(decoded *partJSON, part *Part, parent *Part) {
+	*part = Part{Name: decoded.Name, StartPos: decoded.StartPos, EndPos: decoded.EndPos, Value: decoded.Value, Ignore: decoded.Ignore, Parent: parent, Found: !decoded.Synthesized}
+	if part.Found {
+		part.Source = decoded.Value
+	}
+	if decoded.Source != nil {
+		part.Source = *decoded.Source
+	}
+	if parent != nil {
+		part.Path = append(append([]string{}, parent.Path...), parent.Name)
+	}
+	for _, constituent := range decoded.Constituents {
+		if constituent == nil {
+			continue
+		}
+		child := &Part{}
+		fromPartJSON(constituent, child, part)
+		part.Constituents = append(part.Constituents, child)
+	}
+}
@@ -0,0 +1,84 @@
+package dialects_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AdamJonR/dialects"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// golden compares the output to the golden file in testdata, rewriting the file instead when run with -update
+func golden(t *testing.T, name string, output []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, output, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(output, expected) {
+		t.Errorf("output doesn't match %s:\n%s", path, output)
+	}
+}
+
+// indentJSON returns the JSON indented for readable golden files
+func indentJSON(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, data, "", "  "); err != nil {
+		t.Fatal(err)
+	}
+	return append(indented.Bytes(), '\n')
+}
+
+func TestPartJSON(t *testing.T) {
+	root, err, _ := dialects.ParseToTreeWithOptions(wordList(), "ab cd", dialects.Options{KeepIgnored: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "part.json", indentJSON(t, data))
+	data, err = dialects.PartToJSON(root, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "part_without_values.json", indentJSON(t, data))
+}
+
+func TestPartJSONRoundTrip(t *testing.T) {
+	root, err, _ := dialects.ParseToTree(declarations(), "var a;func f{var b;}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded dialects.Part
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := json.Marshal(&decoded); !bytes.Equal(again, data) {
+		t.Errorf("expected the decoded tree to encode the same, got %s", again)
+	}
+	// the parents and paths are linked again
+	identifier := decoded.First("functionDecl").First("varDecl").First("identifier")
+	if identifier.Value != "b" || identifier.Parent.Name != "varDecl" || identifier.Parent.Parent.Parent.Name != "decl" ||
+		strings.Join(identifier.Path, "/") != "program/decl/functionDecl/varDecl" {
+		t.Errorf("unexpected identifier %+v", identifier)
+	}
+}
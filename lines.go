@@ -0,0 +1,85 @@
+package dialects
+
+import (
+	"sort"
+	"strings"
+)
+
+// lineIndex provides the offsets where the lines of an input start, found the first time a line is needed, so the
+// line of any offset can be found by a binary search rather than by counting the newlines of every match, and
+// rewinding the position rewinds the line with it
+type lineIndex struct {
+	input  string
+	starts []int
+}
+
+// line returns the 1-based line containing the offset, where a newline belongs to the line it ends
+func (index *lineIndex) line(offset int) int {
+	if index.starts == nil {
+		index.starts = lineStarts(index.input)
+	}
+	return sort.SearchInts(index.starts, offset+1)
+}
+
+// lineStarts returns the offsets where the lines of the input start, the first of which is zero
+func lineStarts(input string) []int {
+	starts := []int{0}
+	for start := 0; ; {
+		end := strings.IndexByte(input[start:], '\n')
+		if end < 0 {
+			return starts
+		}
+		start += end + 1
+		starts = append(starts, start)
+	}
+}
+
+// splitsCRLF reports whether the offset falls between the \r and \n of a CRLF line ending, which ends a line like a
+// lone \n, so the offset is in the same column as the \r
+func splitsCRLF(input string, offset int) bool {
+	return offset > 0 && offset < len(input) && input[offset-1] == '\r' && input[offset] == '\n'
+}
+
+// visualColumn returns the 1-based column of the offset within its line as displayed, counting runes and advancing
+// each tab to the next tab stop of the tab width, or by one column when it's zero, where the \r of a CRLF line ending
+// is in the same column as its \n
+func visualColumn(input string, offset int, tabWidth int) int {
+	col := 1
+	for _, r := range input[strings.LastIndexByte(input[:offset], '\n')+1 : offset] {
+		col = nextColumn(col, r, tabWidth)
+	}
+	if splitsCRLF(input, offset) {
+		col--
+	}
+	return col
+}
+
+// nextColumn returns the column after the rune displayed at the column, which is the next tab stop for a tab when
+// the tab width is set
+func nextColumn(col int, r rune, tabWidth int) int {
+	if r == '\t' && tabWidth > 0 {
+		return col + tabWidth - (col-1)%tabWidth
+	}
+	return col + 1
+}
+
+// expandTabs returns the line with each tab replaced by the spaces up to its next tab stop
+func expandTabs(line string, tabWidth int) string {
+	var expanded strings.Builder
+	col := 1
+	for _, r := range line {
+		next := nextColumn(col, r, tabWidth)
+		if r == '\t' {
+			expanded.WriteString(strings.Repeat(" ", next-col))
+		} else {
+			expanded.WriteRune(r)
+		}
+		col = next
+	}
+	return expanded.String()
+}
+
+// currentLine returns the line of the parser's current position
+func currentLine(parser Parser) int {
+	return parser.lines.line(*parser.currentPosPointer)
+}
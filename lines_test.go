@@ -0,0 +1,206 @@
+package dialects_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/AdamJonR/dialects"
+)
+
+// lineSettings returns a grammar of name = value lines with # comments, whose values run to the end of the line
+func lineSettings() grammar {
+	g := newGrammar("settings", map[string]dialects.PartDefinition{
+		"settings": {Constituents: [][]string{{"setting%newline+"}}},
+		"setting":  {Constituents: [][]string{{"name", "equals", "value"}}},
+		"name":     {Regex: `[a-z]+`, Description: "name"},
+		"equals":   {Literal: "="},
+		"value": {Regex: `[0-9][^\n#]*`, Description: "value", HandlerE: func(part *dialects.Part, model interface{}) error {
+			if strings.HasPrefix(part.Value, "0") {
+				return &dialects.Warning{Message: "leading zero"}
+			}
+			return nil
+		}},
+		"newline": {Regex: `\r?\n`},
+	})
+	g.dialect.SkipPattern = `[ \t]+`
+	g.dialect.LineComment = "#"
+	return g
+}
+
+// positions returns the lines and columns of the parts of the tree, one part per line
+func positions(root *dialects.Part) string {
+	var b strings.Builder
+	dialects.Walk(root, func(p *dialects.Part, depth int) bool {
+		fmt.Fprintf(&b, "%s %d:%d-%d:%d\n", p.Name, p.StartLine, p.StartCol, p.EndLine, p.EndCol)
+		return true
+	})
+	return b.String()
+}
+
+func TestCRLFPositions(t *testing.T) {
+	compiled, err := dialects.Compile(lineSettings())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"valid", "a = 01 # one\nb = 2 \nc = 3"},
+		{"missing value", "a = 01\nb = \nc = 3"},
+		{"missing value at the end", "a = 1\nb ="},
+		{"unexpected input", "a = 1\nb = 2\n!"},
+	}
+	for _, test := range tests {
+		var results [2]string
+		for i, input := range []string{test.input, strings.ReplaceAll(test.input, "\n", "\r\n")} {
+			var diagnostics []dialects.Diagnostic
+			root, err, _ := compiled.ParseToTreeWithOptions(input, dialects.Options{Diagnostics: &diagnostics, KeepIgnored: true})
+			var b strings.Builder
+			for _, diagnostic := range diagnostics {
+				b.WriteString(diagnostic.String() + "\n")
+			}
+			var parseError *dialects.ParseError
+			if errors.As(err, &parseError) {
+				b.WriteString(parseError.Snippet() + "\n")
+			}
+			if root != nil {
+				b.WriteString(positions(root))
+			}
+			results[i] = b.String()
+		}
+		if results[0] != results[1] {
+			t.Errorf("%s: expected the same positions for LF and CRLF, got:\n%s\nand:\n%s", test.name, results[0], results[1])
+		}
+	}
+	// empty matches between the \r and \n of a line ending stay in the column of the \r
+	split := newGrammar("root", map[string]dialects.PartDefinition{
+		"root": {Constituents: [][]string{{"word", "cr", "opt", "opt", "nl", "word"}}},
+		"word": {Regex: `[a-z]+`},
+		"cr":   {Literal: "\r"},
+		"opt":  {Regex: `x*`},
+		"nl":   {Literal: "\n"},
+	})
+	root, err, _ := dialects.ParseToTree(split, "ab\r\ncd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "root 1:1-2:3\nword 1:1-1:3\ncr 1:3-1:3\nopt 1:3-1:3\nopt 1:3-1:3\nnl 1:3-2:1\nword 2:1-2:3\n"
+	if actual := positions(root); actual != expected {
+		t.Errorf("expected positions:\n%s\ngot:\n%s", expected, actual)
+	}
+}
+
+func TestNormalizeLineEndings(t *testing.T) {
+	g := lineSettings()
+	g.dialect.PartDefinitions["value"] = dialects.PartDefinition{Regex: `[0-9]+`}
+	g.dialect.PartDefinitions["newline"] = dialects.PartDefinition{Literal: "\n"}
+	input := "a = 1\r\nb = 2"
+	if _, err, _ := dialects.ParseToTree(g, input); err == nil {
+		t.Error("expected the CRLF line endings to fail without normalizing them")
+	}
+	g.dialect.NormalizeLineEndings = true
+	root, err, _ := dialects.ParseToTree(g, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// positions are in the input as it was given
+	second := root.Constituents[len(root.Constituents)-1]
+	if second.StartPos != 7 || second.EndPos != 12 || second.StartLine != 2 || second.EndCol != 6 {
+		t.Errorf("expected the second setting at 7-12 ending in column 6, got %d-%d ending at %d:%d", second.StartPos, second.EndPos, second.EndLine, second.EndCol)
+	}
+	_, err, _ = dialects.ParseToTree(g, "a = 1\r\nb = \r\nc = 3")
+	var parseError *dialects.ParseError
+	if !errors.As(err, &parseError) || parseError.Offset != 11 || parseError.Line != 2 || parseError.Column != 5 || parseError.LineText != "b = " {
+		t.Errorf("expected an error at offset 11, line 2, column 5 of %q, got %#v", "b = ", err)
+	}
+}
+
+func TestTabWidth(t *testing.T) {
+	compiled, err := dialects.Compile(lineSettings())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the value on the second line is missing after mixed tab and space indentation
+	input := "a = 1\n\t  \tb =\t \t?"
+	tests := []struct {
+		tabWidth     int
+		visualColumn int
+		snippet      string
+	}{
+		{0, 11, "\t  \tb =\t \t?\n\t  \t   \t \t^"},
+		{4, 17, "        b =     ?\n                ^"},
+		{8, 33, strings.Repeat(" ", 16) + "b =" + strings.Repeat(" ", 13) + "?\n" + strings.Repeat(" ", 32) + "^"},
+	}
+	for _, test := range tests {
+		var diagnostics []dialects.Diagnostic
+		_, err, _ := compiled.ParseWithOptions(input, dialects.Options{TabWidth: test.tabWidth, Diagnostics: &diagnostics})
+		var parseError *dialects.ParseError
+		if !errors.As(err, &parseError) {
+			t.Fatalf("tab width %d: expected a ParseError, got %v", test.tabWidth, err)
+		}
+		// the byte column and offset don't depend on the tab width
+		if parseError.Offset != 16 || parseError.Column != 11 || parseError.VisualColumn != test.visualColumn {
+			t.Errorf("tab width %d: expected offset 16, column 11, and visual column %d, got %d, %d, and %d", test.tabWidth, test.visualColumn, parseError.Offset, parseError.Column, parseError.VisualColumn)
+		}
+		if snippet := parseError.Snippet(); snippet != test.snippet {
+			t.Errorf("tab width %d: expected snippet:\n%s\ngot:\n%s", test.tabWidth, test.snippet, snippet)
+		}
+		if len(diagnostics) != 1 || diagnostics[0].Offset != 16 || diagnostics[0].VisualColumn != test.visualColumn {
+			t.Errorf("tab width %d: expected a diagnostic at offset 16 in visual column %d, got %+v", test.tabWidth, test.visualColumn, diagnostics)
+		}
+	}
+}
+
+// restarted returns a grammar of lines of words whose first alternative reads every line before failing at the end,
+// so the second reads them again from the start
+func restarted() grammar {
+	return newGrammar("root", map[string]dialects.PartDefinition{
+		"root":  {Constituents: [][]string{{"words", "bang"}, {"first", "words"}}},
+		"first": {Constituents: [][]string{{"word", "nl"}}},
+		"words": {Constituents: [][]string{{"word%nl+"}}},
+		"word":  {Regex: `[a-z]+`},
+		"nl":    {Literal: "\n"},
+		"bang":  {Literal: "!"},
+	})
+}
+
+func TestLinesBacktracking(t *testing.T) {
+	compiled, err := dialects.Compile(restarted())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the lines of the second attempt are looked up after those of the later lines the first reached
+	expected := "root 1:1-3:3\nfirst 1:1-2:1\nword 1:1-1:3\nnl 1:3-2:1\nwords 2:1-3:3\nword 2:1-2:3\nword 3:1-3:3\n"
+	for _, memoize := range []bool{false, true} {
+		root, err, _ := compiled.ParseToTreeWithOptions("ab\ncd\nef", dialects.Options{Memoize: memoize})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := positions(root); actual != expected {
+			t.Errorf("memoize %v: expected positions:\n%s\ngot:\n%s", memoize, expected, actual)
+		}
+	}
+}
+
+func TestLinesAtEnd(t *testing.T) {
+	compiled, err := dialects.Compile(restarted())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the end of an input ending in a newline is on the line after it
+	_, err, _ = compiled.ParseToTreeWithOptions("ab\ncd\n", dialects.Options{})
+	var parseError *dialects.ParseError
+	if !errors.As(err, &parseError) || parseError.Offset != 6 || parseError.Line != 3 || parseError.Column != 1 {
+		t.Errorf("expected an error at offset 6, line 3, column 1, got %v", err)
+	}
+	root, err, _ := compiled.ParseToTreeWithOptions("ab\ncd", dialects.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root.EndPos != 5 || root.EndLine != 2 || root.EndCol != 3 {
+		t.Errorf("expected the root to end at offset 5, line 2, column 3, got %d, %d, %d", root.EndPos, root.EndLine, root.EndCol)
+	}
+}
@@ -0,0 +1,82 @@
+package dialects
+
+import "sort"
+
+// LSPDiagnostic provides a Diagnostic in the shape of the Language Server Protocol's Diagnostic, so a language
+// server can marshal it straight into a textDocument/publishDiagnostics notification
+type LSPDiagnostic struct {
+	Range    LSPRange `json:"range"`
+	Severity int      `json:"severity"`
+	Source   string   `json:"source,omitempty"`
+	Message  string   `json:"message"`
+}
+
+// LSPRange provides the start and end of the text a diagnostic covers, where the end is exclusive
+type LSPRange struct {
+	Start LSPPosition `json:"start"`
+	End   LSPPosition `json:"end"`
+}
+
+// LSPPosition provides a zero-based line and a zero-based character offset within it, counted in the UTF-16 code
+// units the protocol uses by default
+type LSPPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+const (
+	// LSPSeverityError provides the protocol's severity for an error
+	LSPSeverityError = 1
+	// LSPSeverityWarning provides the protocol's severity for a warning
+	LSPSeverityWarning = 2
+)
+
+// DiagnosticsToLSP returns the diagnostics of a parse of the input in the shape of the Language Server Protocol, with
+// their offsets converted to the line and UTF-16 character the protocol expects and the source naming the tool that
+// reported them, such as the dialect's Title (or left out of the JSON when empty)
+func DiagnosticsToLSP(diagnostics []Diagnostic, input string, source string) []LSPDiagnostic {
+	starts := lineStarts(input)
+	converted := make([]LSPDiagnostic, 0, len(diagnostics))
+	for _, diagnostic := range diagnostics {
+		severity := LSPSeverityError
+		if diagnostic.Severity == SeverityWarning {
+			severity = LSPSeverityWarning
+		}
+		converted = append(converted, LSPDiagnostic{
+			Range: LSPRange{
+				Start: lspPosition(input, starts, diagnostic.Offset),
+				End:   lspPosition(input, starts, diagnostic.Offset+diagnostic.Length),
+			},
+			Severity: severity,
+			Source:   source,
+			Message:  diagnostic.Message,
+		})
+	}
+	return converted
+}
+
+// lspPosition returns the zero-based line and UTF-16 character of the byte offset of the input, given the offsets
+// where its lines start
+func lspPosition(input string, lineStarts []int, offset int) LSPPosition {
+	// clamp the offset in case the diagnostic was built by hand or for another input
+	if offset < 0 {
+		offset = 0
+	} else if offset > len(input) {
+		offset = len(input)
+	}
+	line := sort.SearchInts(lineStarts, offset+1) - 1
+	character := 0
+	for _, r := range input[lineStarts[line]:offset] {
+		// runes outside the Basic Multilingual Plane, such as most emoji, take a surrogate pair
+		if r > 0xFFFF {
+			character += 2
+		} else {
+			character++
+		}
+	}
+	// an offset within a CRLF line ending is at the \r, as LSP treats the two as one line ending
+	if splitsCRLF(input, offset) {
+		character--
+	}
+	return LSPPosition{Line: line, Character: character}
+}
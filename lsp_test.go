@@ -0,0 +1,52 @@
+package dialects_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/AdamJonR/dialects"
+)
+
+func TestDiagnosticsToLSP(t *testing.T) {
+	g := settings()
+	key := g.dialect.PartDefinitions["key"]
+	key.Regex = `[^ =\n]+`
+	g.dialect.PartDefinitions["key"] = key
+	value := g.dialect.PartDefinitions["value"]
+	value.HandlerE = func(part *dialects.Part, model interface{}) error {
+		if part.Value == "0" {
+			return &dialects.Warning{Message: "zero disables the setting"}
+		}
+		return nil
+	}
+	g.dialect.PartDefinitions["value"] = value
+	// the CJK characters take 3 bytes but 1 UTF-16 unit each, and the emoji takes 4 bytes but a surrogate pair
+	input := "名前 = 0\n\U0001F600a = x\n"
+	var diagnostics []dialects.Diagnostic
+	dialects.ParseWithOptions(g, input, dialects.Options{Diagnostics: &diagnostics})
+	converted := dialects.DiagnosticsToLSP(diagnostics, input, "settings")
+	expected := []dialects.LSPDiagnostic{
+		{Range: dialects.LSPRange{Start: dialects.LSPPosition{Line: 0, Character: 5}, End: dialects.LSPPosition{Line: 0, Character: 6}}, Severity: dialects.LSPSeverityWarning, Source: "settings", Message: "zero disables the setting"},
+		{Range: dialects.LSPRange{Start: dialects.LSPPosition{Line: 1, Character: 6}, End: dialects.LSPPosition{Line: 1, Character: 6}}, Severity: dialects.LSPSeverityError, Source: "settings", Message: "expected value"},
+	}
+	if len(converted) != len(expected) {
+		t.Fatalf("expected %d diagnostics, got %+v", len(expected), converted)
+	}
+	for i := range expected {
+		if converted[i] != expected[i] {
+			t.Errorf("expected %+v, got %+v", expected[i], converted[i])
+		}
+	}
+	// a range ending after an emoji counts both of its units
+	emoji := dialects.DiagnosticsToLSP([]dialects.Diagnostic{{Offset: 9, Length: 5}}, input, "")
+	if end := emoji[0].Range.End; end.Line != 1 || end.Character != 3 {
+		t.Errorf("expected the range to end at line 1, character 3, got %+v", end)
+	}
+	data, err := json.Marshal(converted[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"range":{"start":{"line":1,"character":6},"end":{"line":1,"character":6}},"severity":1,"source":"settings","message":"expected value"}` {
+		t.Errorf("unexpected JSON %s", data)
+	}
+}
@@ -0,0 +1,146 @@
+package dialects
+
+// memoKey identifies an attempt to find a part at a position in the input, whatever the parent it's found under, as
+// a cached part is moved under the parent it's reused for, though not across NoSkip parts, which change what it skips
+type memoKey struct {
+	partName string
+	pos      int
+	noSkip   bool
+}
+
+// memoEntry provides the result of an attempt to find a part, where nil parts record that the part wasn't found
+type memoEntry struct {
+	parts     []*Part
+	end       state
+	deferred  []deferredCall
+	streamed  []*Part
+	tokens    []Token
+	completed []*Part
+	recovered []*ParseError
+	warnings  []Diagnostic
+	covered   []coverMark
+}
+
+// recallPart returns the memoized result of finding the part at the current position as children of the parent, with
+// ok reporting whether there was one, restoring the state to the end of the part and replaying any handler calls and
+// parts it queued
+func recallPart(partName string, parser Parser, parent *Part) (parts []*Part, ok bool) {
+	entry, ok := parser.memo[memoKey{partName: partName, pos: *parser.currentPosPointer, noSkip: parser.noSkip}]
+	if !ok {
+		return nil, false
+	}
+	return adoptEntry(entry, parser, parent), true
+}
+
+// memoizePart records the result of finding the part starting from the state
+func memoizePart(partName string, parts []*Part, start state, parser Parser) {
+	// parts that consume nothing are cheap to find again, and reusing them could put one part in a sequence twice
+	if parts != nil && *parser.currentPosPointer == start.pos {
+		return
+	}
+	parser.memo[memoKey{partName: partName, pos: start.pos, noSkip: parser.noSkip}] = newMemoEntry(parts, start, parser)
+}
+
+// newMemoEntry returns an entry for the parts found from the state to the current one, along with the handler calls,
+// parts, tokens, completed parts, recovered errors, warnings, and coverage queued along the way
+func newMemoEntry(parts []*Part, start state, parser Parser) *memoEntry {
+	entry := &memoEntry{parts: parts}
+	if parts != nil {
+		entry.end = saveState(parser)
+		entry.deferred = append([]deferredCall{}, (*parser.deferred)[start.deferred:]...)
+		if parser.stream != nil && start.streamed >= parser.stream.flushed {
+			entry.streamed = append([]*Part{}, parser.stream.queued[start.streamed-parser.stream.flushed:]...)
+		}
+		if parser.tokens != nil {
+			entry.tokens = append([]Token{}, parser.tokens.items[start.tokens:]...)
+		}
+		if parser.completed != nil {
+			entry.completed = append([]*Part{}, parser.completed.items[start.completed:]...)
+		}
+		if parser.recovered != nil {
+			entry.recovered = append([]*ParseError{}, parser.recovered.items[start.recovered:]...)
+		}
+		if parser.warnings != nil {
+			entry.warnings = append([]Diagnostic{}, parser.warnings.items[start.warnings:]...)
+		}
+		if parser.coverage != nil {
+			entry.covered = append([]coverMark{}, parser.coverage.items[start.covered:]...)
+		}
+	}
+	return entry
+}
+
+// replayEntry restores the state to the end of the entry's parts, replaying any handler calls, parts, tokens,
+// completed parts, recovered errors, warnings, and coverage they queued
+func replayEntry(entry *memoEntry, parser Parser) {
+	*parser.currentPosPointer = entry.end.pos
+	parser.cursor.column = entry.end.column
+	parser.cursor.runes = entry.end.runes
+	*parser.deferred = append(*parser.deferred, entry.deferred...)
+	streamParts(entry.streamed, parser)
+	parser.tokens.replay(entry.tokens, parser)
+	parser.completed.replay(entry.completed, parser)
+	parser.recovered.replay(entry.recovered, parser)
+	parser.warnings.replay(entry.warnings, parser)
+	parser.coverage.replay(entry.covered, parser)
+}
+
+// growPart returns the left-recursive part found at the current position by growing a seed: the part is found again
+// and again while each attempt ends farther along than the last, where each recursive attempt at the same position
+// reuses the part the previous attempt found, and the first fails; handler calls are queued while growing, and only
+// those of the winning attempt are run, failing the part if one of them rejects its part
+func growPart(partName string, parser Parser, parent *Part) []*Part {
+	// each attempt nests the part under a new parent, which adopts the seed found by the attempt before
+	key := memoKey{partName: partName, pos: *parser.currentPosPointer, noSkip: parser.noSkip}
+	if seed, ok := parser.seeds[key]; ok {
+		return adoptEntry(seed, parser, parent)
+	}
+	start := saveState(parser)
+	seed := &memoEntry{}
+	parser.seeds[key] = seed
+	// hold back streamed parts until the attempts that don't grow the seed are discarded
+	beginChoice(parser)
+	growing := parser
+	growing.growing = true
+	for {
+		parts := findPart(partName, growing, parent)
+		if parts == nil || (seed.parts != nil && *parser.currentPosPointer <= seed.end.pos) {
+			break
+		}
+		*seed = *newMemoEntry(parts, start, parser)
+		restoreState(start, parser)
+	}
+	delete(parser.seeds, key)
+	restoreState(start, parser)
+	parts := adoptEntry(seed, parser, parent)
+	// run the winning attempt's handler calls, unless they stay queued for the parse to commit, for a lookahead to
+	// discard, or for an enclosing left-recursive part to settle
+	if parts != nil && !parser.dialect.DeferHandlers && !parser.lookahead && !parser.growing {
+		calls := append([]deferredCall{}, (*parser.deferred)[start.deferred:]...)
+		*parser.deferred = (*parser.deferred)[:start.deferred]
+		for _, call := range calls {
+			if !runHandler(call.partDefinition, call.part, call.line, parser) {
+				restoreState(start, parser)
+				parts = nil
+				break
+			}
+		}
+	}
+	endChoice(parser)
+	return parts
+}
+
+// adoptEntry returns the parts of the entry as children of the parent, replaying the entry's state, since the entry
+// may have been found under another parent, such as that of an earlier attempt to grow a seed
+func adoptEntry(entry *memoEntry, parser Parser, parent *Part) []*Part {
+	if entry.parts == nil {
+		return nil
+	}
+	replayEntry(entry, parser)
+	for _, part := range entry.parts {
+		part.Parent = parent
+		part.Path = childPath(parent, parser)
+		reparent(part, parser)
+	}
+	return entry.parts
+}
@@ -0,0 +1,205 @@
+package dialects_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/AdamJonR/dialects"
+)
+
+// prefixHeavy returns a grammar whose alternatives share the long prefix of a nested expression
+func prefixHeavy() grammar {
+	g := newGrammar("expr", map[string]dialects.PartDefinition{
+		"expr":  {Constituents: [][]string{{"open", "expr", "close", "a"}, {"open", "expr", "close", "b"}, {"open", "expr", "close"}, {"x"}}, Handler: record},
+		"open":  {Regex: `\(`},
+		"close": {Regex: `\)`},
+		"a":     {Regex: `a`},
+		"b":     {Regex: `b`},
+		"x":     {Regex: `x`},
+	})
+	g.dialect.DeferHandlers = true
+	return g
+}
+
+// nested returns x wrapped in depth pairs of parentheses
+func nested(depth int) string {
+	return strings.Repeat("(", depth) + "x" + strings.Repeat(")", depth)
+}
+
+func TestMemoizeMatchesUnmemoized(t *testing.T) {
+	input := nested(8)
+	expected, err, _ := dialects.ParseWithOptions(prefixHeavy(), input, dialects.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, err, _ := dialects.ParseWithOptions(prefixHeavy(), input, dialects.Options{Memoize: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the deferred handler calls of cached parts are replayed, so each expr is recorded once
+	if output != expected || strings.Count(output, "expr") != 9 {
+		t.Errorf("expected %q, got %q", expected, output)
+	}
+}
+
+func TestMemoizeKeepsSharedPartsInPlace(t *testing.T) {
+	g := newGrammar("a", map[string]dialects.PartDefinition{
+		"a":  {Constituents: [][]string{{"ws", "b"}}},
+		"b":  {Constituents: [][]string{{"ws", "x"}}},
+		"ws": {Regex: `[ ]*`},
+		"x":  {Regex: `x`},
+	})
+	root, err, _ := dialects.ParseToTreeWithOptions(g, "x", dialects.Options{Memoize: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := root.Constituents[1]
+	if root.Constituents[0] == b.Constituents[0] {
+		t.Fatal("a and b share the same ws part")
+	}
+	if ws := root.Constituents[0]; ws.Parent != root || strings.Join(ws.Path, "/") != "a" {
+		t.Errorf("ws of a has parent %v and path %v", ws.Parent.Name, ws.Path)
+	}
+}
+
+func TestMemoizeAcrossParents(t *testing.T) {
+	g := newGrammar("root", map[string]dialects.PartDefinition{
+		"root": {Constituents: [][]string{{"a", "bang"}, {"b"}}},
+		"a":    {Constituents: [][]string{{"word"}}},
+		"b":    {Constituents: [][]string{{"word"}}},
+		"word": {Regex: `[a-z]+`, Handler: record},
+		"bang": {Literal: "!"},
+	})
+	// the word found under a is reused under b rather than found again, so its handler is only called once
+	compiled, err := dialects.Compile(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for memoize, calls := range map[bool]string{false: "word,word", true: "word"} {
+		output, err, _ := compiled.ParseWithOptions("ab", dialects.Options{Memoize: memoize})
+		if err != nil || output != calls {
+			t.Errorf("memoize %v: expected %q, got %q and %v", memoize, calls, output, err)
+		}
+	}
+	root, err, _ := compiled.ParseToTreeWithOptions("ab", dialects.Options{Memoize: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := root.Constituents[0]
+	if word := b.Constituents[0]; word.Parent != b || strings.Join(word.Path, "/") != "root/b" {
+		t.Errorf("expected the word to be moved under b, got parent %s and path %v", word.Parent.Name, word.Path)
+	}
+}
+
+// subtraction returns a left-recursive grammar of subtractions whose handler records the value of each
+func subtraction() grammar {
+	g := newGrammar("expr", map[string]dialects.PartDefinition{
+		"expr": {Constituents: [][]string{{"expr", "minus", "num"}, {"num"}}, AllowLeftRecursion: true, Handler: func(part *dialects.Part, model interface{}) bool {
+			names := model.(*[]string)
+			*names = append(*names, part.Value)
+			return true
+		}},
+		"minus": {Literal: "-"},
+		"num":   {Regex: `[0-9]+`},
+	})
+	g.dialect.DeferHandlers = true
+	return g
+}
+
+func TestLeftRecursion(t *testing.T) {
+	for _, memoize := range []bool{false, true} {
+		root, err, _ := dialects.ParseToTreeWithOptions(subtraction(), "5-3-1", dialects.Options{Memoize: memoize})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(root.Constituents) != 3 || root.Constituents[2].Value != "1" {
+			t.Fatalf("expected 5-3 minus 1, got %d constituents", len(root.Constituents))
+		}
+		left := root.Constituents[0]
+		if left.Name != "expr" || left.Value != "5-3" || left.Parent != root || strings.Join(left.Path, "/") != "expr" {
+			t.Errorf("expected 5-3 under the root, got %s %q with path %v", left.Name, left.Value, left.Path)
+		}
+		innermost := left.Constituents[0]
+		if innermost.Value != "5" || innermost.Parent != left || strings.Join(innermost.Constituents[0].Path, "/") != "expr/expr/expr" {
+			t.Errorf("expected 5 under 5-3, got %q with path %v", innermost.Value, innermost.Constituents[0].Path)
+		}
+	}
+}
+
+func TestLeftRecursionHandlers(t *testing.T) {
+	output, err, _ := dialects.Parse(subtraction(), "5-3-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output != "5,5-3,5-3-1" {
+		t.Errorf("expected each subtraction to be handled once from the innermost out, got %q", output)
+	}
+}
+
+func TestLeftRecursionEagerHandlers(t *testing.T) {
+	g := subtraction()
+	g.dialect.DeferHandlers = false
+	for _, memoize := range []bool{false, true} {
+		output, err, _ := dialects.ParseWithOptions(g, "5-3-1", dialects.Options{Memoize: memoize})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the attempts that fail to grow the part never reach the model
+		if output != "5,5-3,5-3-1" {
+			t.Errorf("expected each committed subtraction to be handled once from the innermost out, got %q", output)
+		}
+	}
+	// a handler rejecting a part of the winning attempt fails the part
+	g.dialect.PartDefinitions["expr"] = dialects.PartDefinition{Constituents: g.dialect.PartDefinitions["expr"].Constituents, AllowLeftRecursion: true, Handler: func(part *dialects.Part, model interface{}) bool {
+		return part.Value != "5-3"
+	}}
+	if _, err, _ := dialects.Parse(g, "5-3-1"); err == nil {
+		t.Error("expected the rejected subtraction to fail the parse")
+	}
+}
+
+func TestIndirectLeftRecursion(t *testing.T) {
+	g := newGrammar("postfix", map[string]dialects.PartDefinition{
+		"postfix": {Constituents: [][]string{{"call"}, {"member"}, {"name"}}, AllowLeftRecursion: true},
+		"call":    {Constituents: [][]string{{"postfix", "parens"}}},
+		"member":  {Constituents: [][]string{{"postfix", "dot", "name"}}},
+		"parens":  {Literal: "()"},
+		"dot":     {Literal: "."},
+		"name":    {Regex: `[a-z]+`},
+	})
+	root, err, _ := dialects.ParseToTree(g, "a.b().c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var chain []string
+	for part := root; part.Name == "postfix"; part = part.Constituents[0].Constituents[0] {
+		chain = append(chain, part.Constituents[0].Name+" "+part.Value)
+		if part.Constituents[0].Name == "name" {
+			break
+		}
+	}
+	if strings.Join(chain, ", ") != "member a.b().c, call a.b(), member a.b, name a" {
+		t.Errorf("expected the chain to nest from the left, got %v", chain)
+	}
+}
+
+func BenchmarkMemoizePrefixHeavy(b *testing.B) {
+	input := nested(8)
+	for _, memoize := range []bool{false, true} {
+		name := "unmemoized"
+		if memoize {
+			name = "memoized"
+		}
+		b.Run(name, func(b *testing.B) {
+			compiled, err := dialects.Compile(prefixHeavy())
+			if err != nil {
+				b.Fatal(err)
+			}
+			for i := 0; i < b.N; i++ {
+				if _, err, _ := compiled.ParseWithOptions(input, dialects.Options{Memoize: memoize}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package dialects
+
+import "io"
+
+// DefaultMaxDepth provides the nesting depth limit used when Options.MaxDepth is zero
+const DefaultMaxDepth = 10000
+
+// Options provides the settings that can vary from one parse to the next, where the zero value gives the defaults
+type Options struct {
+	// MaxDepth limits how deeply parts can be nested, using DefaultMaxDepth when zero and no limit when negative
+	MaxDepth int
+	// MaxAttempts limits the number of attempts to find parts, aborting the parse with a LimitError once it's
+	// exceeded, which gives a reproducible limit on the work a parse can do, with no limit when zero
+	MaxAttempts int
+	// Memoize caches the result of each attempt to find a part at a position under the same parent (packrat
+	// parsing), so alternatives sharing long prefixes don't re-parse them; cached parts are reused without calling
+	// their handlers again unless the dialect defers handlers, in which case the deferred calls are replayed
+	Memoize bool
+	// NoTrace skips building the trace log, which most callers discard, so the log returned is empty
+	NoTrace bool
+	// TraceWriter receives the trace as it's written rather than it being returned, so the log returned is empty; an
+	// error writing to it stops the trace but not the parse, and is reported as a TraceError in Result.TraceErr, which
+	// the functions returning output also return as their error, while those returning a tree don't
+	TraceWriter io.Writer
+	// MaxTraceSize limits the bytes of the trace log, after which its lines are left out, noting where it was
+	// truncated and how much was left out, using DefaultMaxTraceSize when zero and no limit when negative
+	MaxTraceSize int
+	// TraceIndent holds the text the trace log is indented by for each level of depth, using DefaultTraceIndent when
+	// empty
+	TraceIndent string
+	// TraceMaxDepth limits how deeply the trace log is indented, marking lines nested deeper with their depth
+	// instead, with no limit when zero
+	TraceMaxDepth int
+	// StableTrace writes the trace log in a format kept stable for golden tests, with a line giving its
+	// StableTraceVersion followed by a line for each event holding its depth, kind, part name, start position, and
+	// line, separated by tabs, rather than the human-readable format, which can change from one release to the next
+	StableTrace bool
+	// TraceFunc receives a structured event for each step of the parse, whether or not the trace log is built
+	TraceFunc func(TraceEvent)
+	// TraceFilter limits the trace log and events to the sequences of the parts, and the invalid regex parts, for
+	// which it returns true
+	TraceFilter func(partName string) bool
+	// KeepIgnored keeps Ignored parts in the Constituents of their parents, still marked by their Ignore field, for
+	// callers such as formatters that need every part of the input
+	KeepIgnored bool
+	// RunePositions counts the runes of each match as it's consumed, setting the rune offsets of parts and the rune
+	// offset and column of parse errors alongside the byte offsets
+	RunePositions bool
+	// TabWidth sets the columns between tab stops for the VisualColumn of parse errors and diagnostics, and has
+	// snippets expand tabs to spaces so the caret lines up however the terminal displays tabs, while zero counts a tab
+	// as one column and leaves the tabs of snippets as they are
+	TabWidth int
+	// Coverage records which parts and which alternatives of their constituent sequences the parse matched, adding
+	// them to what it recorded for earlier parses, so it can report the parts of a grammar that inputs never reach
+	Coverage *Coverage
+	// Stats counts the attempts to find each part, their matches and failures, the input the matches consumed, and
+	// the alternatives of the part abandoned, adding them to what it counted for earlier parses
+	Stats *Stats
+	// Timing measures the wall time of each attempt to find a part for Stats, and the time since the parse started
+	// of each TraceEvent, which reads the clock on every attempt, so the clock is never read without it
+	Timing bool
+	// SourceMap receives the spans of the output written for parts by a SourceMapper, replacing those of any earlier
+	// parse, so positions in the output can be traced back to the input
+	SourceMap *SourceMap
+	// Partial receives what the parse found before it failed, the outermost parts completed by the attempt that
+	// reached farthest along with the farthest failure, and is reset when the parse succeeds
+	Partial *PartialResult
+	// Diagnostics receives the warnings of the parse and the errors it failed with or recovered from, in order of
+	// position, replacing those of any earlier parse, so tooling can show every problem rather than the first
+	Diagnostics *[]Diagnostic
+	// ReportAmbiguity tries the later alternatives of each traced part after one matches, sending a TraceAmbiguity
+	// event for each that would have matched the same text, which usually means the grammar has a redundant or
+	// misordered alternative; the extra attempts are discarded like lookaheads, so their handlers never run
+	ReportAmbiguity bool
+	// Debug is called synchronously at each decision point of the parse, as a part is entered, a constituent
+	// sequence is tried, a leaf part matches, and a part is found or backtracked from, with a view of the parser's
+	// state, so a debugger can step through the parse, and returning false stops the parse with a ParseError
+	Debug func(step DebugStep, state ParserState) bool
+	// OnPart maps part names to callbacks that receive each part with the name once backtracking can no longer
+	// discard it, so results can be streamed out as the parse goes; parts passed to callbacks from within a
+	// repetition aren't kept in the tree, so memory stays bounded for line-oriented grammars
+	OnPart map[string]func(p *Part)
+	// NoRecover lets a panic in a callback of the dialect, such as a Handler, ValidateMatch, FormatMatch, Match, or
+	// GenerateOutput, crash the parse rather than being returned as a ParseError wrapping a PanicError, for
+	// development, where the debugger or the crash should stop at the panic
+	NoRecover bool
+}
+
+// ParseWithOptions parses the input like Parse, using the options for this parse
+func ParseWithOptions(dialectable Dialectable, input string, options Options) (string, error, string) {
+	compiled, err := Compile(dialectable)
+	if err != nil {
+		return "", err, ""
+	}
+	return compiled.ParseWithOptions(input, options)
+}
+
+// ParseToTreeWithOptions parses the input like ParseToTree, using the options for this parse
+func ParseToTreeWithOptions(dialectable Dialectable, input string, options Options) (*Part, error, string) {
+	compiled, err := Compile(dialectable)
+	if err != nil {
+		return nil, err, ""
+	}
+	return compiled.ParseToTreeWithOptions(input, options)
+}
+
+// maxDepth returns the nesting depth limit, or zero if there's no limit
+func (options Options) maxDepth() int {
+	switch {
+	case options.MaxDepth == 0:
+		return DefaultMaxDepth
+	case options.MaxDepth < 0:
+		return 0
+	}
+	return options.MaxDepth
+}
+
+// maxTraceSize returns the limit on the bytes of the trace log, or zero if there's no limit
+func (options Options) maxTraceSize() int {
+	switch {
+	case options.MaxTraceSize == 0:
+		return DefaultMaxTraceSize
+	case options.MaxTraceSize < 0:
+		return 0
+	}
+	return options.MaxTraceSize
+}
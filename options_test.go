@@ -0,0 +1,214 @@
+package dialects_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/AdamJonR/dialects"
+)
+
+// parens returns a recursive grammar of x wrapped in any number of parentheses
+func parens() grammar {
+	return newGrammar("expr", map[string]dialects.PartDefinition{
+		"expr":  {Constituents: [][]string{{"open", "expr", "close"}, {"x"}}},
+		"open":  {Regex: `\(`},
+		"close": {Regex: `\)`},
+		"x":     {Regex: `x`},
+	})
+}
+
+func TestDefaultMaxDepth(t *testing.T) {
+	input := strings.Repeat("(", 10000) + "x" + strings.Repeat(")", 10000)
+	_, err, _ := dialects.ParseToTree(parens(), input)
+	var parseError *dialects.ParseError
+	if !errors.As(err, &parseError) {
+		t.Fatalf("expected a ParseError, got %v", err)
+	}
+	// the open paren of the 10,000th nested expr, at offset 9999, is the first part nested beyond the limit
+	if !strings.Contains(parseError.Message, "maximum nesting depth (10000) exceeded") || parseError.Offset != 9999 {
+		t.Errorf("unexpected error at offset %d: %s", parseError.Offset, parseError.Message)
+	}
+}
+
+func TestMaxDepthOption(t *testing.T) {
+	input := strings.Repeat("(", 100) + "x" + strings.Repeat(")", 100)
+	if _, err, _ := dialects.ParseToTreeWithOptions(parens(), input, dialects.Options{MaxDepth: 50}); err == nil {
+		t.Error("expected a depth limit of 50 to fail")
+	}
+	if _, err, _ := dialects.ParseToTreeWithOptions(parens(), input, dialects.Options{MaxDepth: 200}); err != nil {
+		t.Errorf("expected a depth limit of 200 to succeed, got %v", err)
+	}
+	if _, err, _ := dialects.ParseWithOptions(parens(), input, dialects.Options{MaxDepth: -1}); err != nil {
+		t.Errorf("expected no depth limit to succeed, got %v", err)
+	}
+}
+
+func TestMaxAttempts(t *testing.T) {
+	// without memoization, the nested alternatives take exponential time, which a small budget catches
+	_, err, _ := dialects.ParseWithOptions(prefixHeavy(), nested(12), dialects.Options{MaxAttempts: 10000})
+	var limitError *dialects.LimitError
+	if !errors.As(err, &limitError) {
+		t.Fatalf("expected a LimitError, got %v", err)
+	}
+	var parseError *dialects.ParseError
+	if errors.As(err, &parseError) {
+		t.Error("expected the LimitError to be distinct from a ParseError")
+	}
+	if limitError.Attempts != 10000 || limitError.PartName != "open" || limitError.PartAttempts < 2500 || !strings.Contains(limitError.Message, "parse complexity limit exceeded (10000 attempts") {
+		t.Errorf("expected the limit to be exceeded mostly at open, got %v with %d attempts at %s", err, limitError.PartAttempts, limitError.PartName)
+	}
+	// the same input is far cheaper with memoization, and the limit is reproducible from one parse to the next
+	if _, err, _ := dialects.ParseWithOptions(prefixHeavy(), nested(12), dialects.Options{MaxAttempts: 10000, Memoize: true}); err != nil {
+		t.Errorf("expected the memoized parse to stay within the budget, got %v", err)
+	}
+	_, again, _ := dialects.ParseWithOptions(prefixHeavy(), nested(12), dialects.Options{MaxAttempts: 10000})
+	if again.Error() != err.Error() {
+		t.Errorf("expected the same error again, got %v", again)
+	}
+}
+
+func TestNoTrace(t *testing.T) {
+	expected, err, trace := dialects.Parse(wordList(), "ab cd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(trace, "found") {
+		t.Errorf("expected a trace by default, got %q", trace)
+	}
+	output, err, trace := dialects.ParseWithOptions(wordList(), "ab cd", dialects.Options{NoTrace: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output != expected || trace != "" {
+		t.Errorf("expected output %q with no trace, got %q and %q", expected, output, trace)
+	}
+}
+
+// failingWriter returns an error from every write, counting the writes
+type failingWriter struct {
+	writes int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return 0, errors.New("disk full")
+}
+
+func TestTraceWriter(t *testing.T) {
+	_, _, expected := dialects.Parse(wordList(), "ab cd")
+	var trace strings.Builder
+	output, err, log := dialects.ParseWithOptions(wordList(), "ab cd", dialects.Options{TraceWriter: &trace})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output != "item,item" || log != "" {
+		t.Errorf("expected output item,item with no log returned, got %q and %q", output, log)
+	}
+	if trace.String() != expected {
+		t.Errorf("expected the streamed trace %q, got %q", expected, trace.String())
+	}
+}
+
+func TestTraceWriterError(t *testing.T) {
+	writer := &failingWriter{}
+	output, err, _ := dialects.ParseWithOptions(wordList(), "ab cd", dialects.Options{TraceWriter: writer})
+	var traceErr *dialects.TraceError
+	if !errors.As(err, &traceErr) || !strings.Contains(err.Error(), "disk full") {
+		t.Fatalf("expected a TraceError, got %v", err)
+	}
+	// the parse carries on and the writer isn't tried again
+	if output != "item,item" || writer.writes != 1 {
+		t.Errorf("expected output item,item after 1 write, got %q after %d", output, writer.writes)
+	}
+	// the Result reports the trace's error apart from the parse, which succeeded
+	compiled, err := dialects.Compile(wordList())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := compiled.ParseResult("ab cd", dialects.Options{TraceWriter: &failingWriter{}})
+	if result.Status != dialects.StatusSucceeded || result.Err != nil || result.Output != "item,item" || !errors.As(result.TraceErr, &traceErr) {
+		t.Errorf("expected a successful parse with a TraceError, got %+v", result)
+	}
+	// the tree of a successful parse comes without an error, leaving the trace's error to the Result
+	root, err, _ := compiled.ParseToTreeWithOptions("ab cd", dialects.Options{TraceWriter: &failingWriter{}})
+	if err != nil || root == nil || len(root.Constituents) != 2 {
+		t.Errorf("expected the tree of a successful parse, got %v and %v", root, err)
+	}
+	result = compiled.ParseToTreeResult("ab cd", dialects.Options{TraceWriter: &failingWriter{}})
+	if result.Status != dialects.StatusSucceeded || result.Err != nil || result.Root == nil || result.Consumed != 5 || !errors.As(result.TraceErr, &traceErr) {
+		t.Errorf("expected a successful parse with its tree and a TraceError, got %+v", result)
+	}
+}
+
+func BenchmarkTrace(b *testing.B) {
+	input := strings.Repeat(`abcd 1234 "ef" + `, 1<<12)
+	compiled, err := dialects.Compile(alternatives())
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, bench := range []struct {
+		name    string
+		options dialects.Options
+	}{{"traced", dialects.Options{}}, {"untraced", dialects.Options{NoTrace: true}}} {
+		b.Run(bench.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(input)))
+			for i := 0; i < b.N; i++ {
+				if _, err, _ := compiled.ParseWithOptions(input, bench.options); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// leafValues returns the concatenated Values of the regex parts beneath the part
+func leafValues(part *dialects.Part) string {
+	if len(part.Constituents) == 0 {
+		return part.Value
+	}
+	var values strings.Builder
+	for _, constituent := range part.Constituents {
+		values.WriteString(leafValues(constituent))
+	}
+	return values.String()
+}
+
+func TestKeepIgnored(t *testing.T) {
+	input := "ab  cd\tef\n"
+	root, err, _ := dialects.ParseToTree(wordList(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if leafValues(root) != "abcdef" {
+		t.Errorf("expected the whitespace to be dropped, got %q", leafValues(root))
+	}
+	root, err, _ = dialects.ParseToTreeWithOptions(wordList(), input, dialects.Options{KeepIgnored: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if leafValues(root) != input {
+		t.Errorf("expected the leaves to round trip %q, got %q", input, leafValues(root))
+	}
+	// the kept whitespace is still marked
+	if ws := root.Constituents[0].Constituents[1]; ws.Name != "ws" || !ws.Ignore {
+		t.Errorf("expected an Ignored ws part, got %+v", ws)
+	}
+}
+
+func TestRunePositions(t *testing.T) {
+	root, err, _ := dialects.ParseToTreeWithOptions(letters(), "maß\ngröße straße", dialects.Options{RunePositions: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, expected := range [][2]int{{0, 3}, {4, 9}, {10, 16}} {
+		word := root.Constituents[i].Constituents[0]
+		if word.StartRune != expected[0] || word.EndRune != expected[1] {
+			t.Errorf("expected %s to span runes [%d:%d], got [%d:%d]", word.Value, expected[0], expected[1], word.StartRune, word.EndRune)
+		}
+	}
+	if root.EndRune != 16 || root.EndPos != 20 {
+		t.Errorf("expected the root to end at rune 16 (byte 20), got %d (%d)", root.EndRune, root.EndPos)
+	}
+}
@@ -0,0 +1,70 @@
+package dialects
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError reports that a callback of the dialect, such as a part's Handler or the grammar's GenerateOutput,
+// panicked, holding the Callback's name, the Value it panicked with, and the Stack of the goroutine where it panicked
+type PanicError struct {
+	Callback string
+	Value    interface{}
+	Stack    []byte
+}
+
+// Error returns the callback and the value it panicked with, as in "Handler panicked: assignment to entry in nil map"
+func (e *PanicError) Error() string {
+	return e.Callback + " panicked: " + fmt.Sprint(e.Value)
+}
+
+// Unwrap returns the value the callback panicked with if it's an error, such as a runtime error
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// callback provides the name of the callback the parser is running, along with the part, offset, and line it's
+// running for, so a panic in it can be reported there
+type callback struct {
+	name     string
+	partName string
+	pos      int
+	line     int
+}
+
+// enterCallback notes that the parser is about to run the callback for the part at the offset and line
+func enterCallback(name string, partName string, pos int, line int, parser Parser) {
+	*parser.calling = callback{name: name, partName: partName, pos: pos, line: line}
+}
+
+// leaveCallback notes that the callback the parser was running has returned
+func leaveCallback(parser Parser) {
+	*parser.calling = callback{}
+}
+
+// recoverCallback is deferred by the parse to turn a panic in a callback into a ParseError at the callback's part
+// wrapping a PanicError, leaving any other panic, which would be a bug in the parser, to carry on
+func recoverCallback(parser Parser, root **Part, err *error) {
+	calling := *parser.calling
+	if calling.name == "" {
+		return
+	}
+	value := recover()
+	if value == nil {
+		return
+	}
+	panicError := &PanicError{Callback: calling.name, Value: value, Stack: debug.Stack()}
+	parseError := newParseError(parser, calling.pos, calling.line, calling.partName, panicError.Error())
+	parseError.Err = panicError
+	parser.log.finish()
+	failSemantically(parser)
+	*root, *err = nil, parseError
+}
+
+// recoverOutput is deferred while generating output to turn a panic in the generator into a PanicError
+func recoverOutput(name string, err *error) {
+	if value := recover(); value != nil {
+		*err = &PanicError{Callback: name, Value: value, Stack: debug.Stack()}
+	}
+}
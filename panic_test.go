@@ -0,0 +1,83 @@
+package dialects_test
+
+import (
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/AdamJonR/dialects"
+)
+
+// panickingOutput provides a grammar whose output can't be generated
+type panickingOutput struct {
+	grammar
+}
+
+func (panickingOutput) GenerateOutput(model interface{}) (string, error) {
+	var counts map[string]int
+	counts["words"]++
+	return "", nil
+}
+
+func TestCallbackPanics(t *testing.T) {
+	word := func(partDefinition dialects.PartDefinition) grammar {
+		partDefinition.Regex = `[a-z]+`
+		return newGrammar("root", map[string]dialects.PartDefinition{
+			"root":    {Constituents: [][]string{{"word%newline+"}}},
+			"word":    partDefinition,
+			"newline": {Literal: "\n"},
+		})
+	}
+	deferred := word(dialects.PartDefinition{Handler: func(part *dialects.Part, model interface{}) bool {
+		return model.(map[string]int)[part.Value] > 0
+	}})
+	deferred.dialect.DeferHandlers = true
+	tests := []struct {
+		name     string
+		grammar  dialects.Dialectable
+		expected string
+	}{
+		{"Handler", word(dialects.PartDefinition{Handler: func(part *dialects.Part, model interface{}) bool {
+			// only the word on the second line panics
+			var counts map[string]int
+			if part.Value == "cd" {
+				counts[part.Value]++
+			}
+			return true
+		}}), "dialects error: Handler panicked: assignment to entry in nil map at line 2, column 1 (offset 3)"},
+		{"deferred Handler", deferred, "dialects error: Handler panicked: interface conversion"},
+		{"HandlerE", word(dialects.PartDefinition{HandlerE: func(part *dialects.Part, model interface{}) error {
+			panic("unexpected " + part.Value)
+		}}), "dialects error: HandlerE panicked: unexpected ab at line 1, column 1 (offset 0)"},
+		{"ValidateMatch", word(dialects.PartDefinition{ValidateMatch: func(matches []string) (bool, string) {
+			return matches[1] != "", ""
+		}}), "dialects error: ValidateMatch panicked: runtime error: index out of range [1] with length 1 at line 1, column 1 (offset 0)"},
+		{"FormatMatch", word(dialects.PartDefinition{FormatMatch: func(matches []string) string {
+			return matches[0][5:]
+		}}), "dialects error: FormatMatch panicked: runtime error: slice bounds out of range"},
+		{"GenerateOutput", panickingOutput{wordList()}, "GenerateOutput panicked: assignment to entry in nil map"},
+	}
+	for _, test := range tests {
+		_, err, _ := dialects.Parse(test.grammar, "ab\ncd")
+		if err == nil || !strings.HasPrefix(err.Error(), test.expected) {
+			t.Errorf("%s: expected %q, got %v", test.name, test.expected, err)
+			continue
+		}
+		var panicError *dialects.PanicError
+		if !errors.As(err, &panicError) || !strings.Contains(string(panicError.Stack), "panic_test.go") {
+			t.Errorf("%s: expected a PanicError with the stack of the panic, got %#v", test.name, err)
+		}
+		var runtimeError runtime.Error
+		if test.name != "HandlerE" && !errors.As(err, &runtimeError) {
+			t.Errorf("%s: expected the runtime error to be wrapped, got %#v", test.name, err)
+		}
+	}
+	// the panic can be left to crash the parse
+	defer func() {
+		if value := recover(); value == nil {
+			t.Error("expected the handler to panic")
+		}
+	}()
+	dialects.ParseWithOptions(tests[0].grammar, "ab\ncd", dialects.Options{NoRecover: true})
+}
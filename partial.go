@@ -0,0 +1,72 @@
+package dialects
+
+import "errors"
+
+// PartialResult provides what a failed parse found before it failed, for editor features that should keep working
+// while the input is being edited: the outermost parts completed by the attempt that reached farthest, in order of
+// position, along with the position of the farthest failure and the parts attempted there
+type PartialResult struct {
+	Parts     []*Part
+	Offset    int
+	Line      int
+	Column    int
+	PartNames []string
+	Expected  []string
+}
+
+// recordCompleted adds the parts found to those completed, unless within a lookahead, leaving out Ignored parts
+// unless they're kept
+func recordCompleted(parts []*Part, parser Parser) {
+	if parser.completed == nil || parser.lookahead {
+		return
+	}
+	for _, part := range parts {
+		if !part.Ignore || parser.keepIgnored {
+			parser.completed.items = append(parser.completed.items, part)
+		}
+	}
+}
+
+// newPartialResult returns the partial result of the parse that failed with the error
+func newPartialResult(err error, parser Parser) *PartialResult {
+	partial := &PartialResult{}
+	// the constituents of completed parts are within them, so only the parts outside the others are kept
+	completed := parser.completed.farthestItems()
+	found := make(map[*Part]bool, len(completed))
+	for _, part := range completed {
+		found[part] = true
+	}
+	for _, part := range completed {
+		if !found[part.Parent] {
+			partial.Parts = append(partial.Parts, part)
+		}
+	}
+	var parseError *ParseError
+	if len(parser.failure.partNames) > 0 {
+		parseError = expectedError(parser)
+		partial.PartNames = append([]string{}, parser.failure.partNames...)
+		partial.Expected = parseError.Expected
+	} else if !errors.As(err, &parseError) {
+		var limitError *LimitError
+		if !errors.As(err, &limitError) {
+			return partial
+		}
+		parseError = limitError.ParseError
+	}
+	partial.Offset, partial.Line, partial.Column = parseError.Offset, parseError.Line, parseError.Column
+	return partial
+}
+
+// recordPartial sets Options.Partial to what the parse found if it failed with the error, or resets it if it didn't,
+// with positions in the original input
+func recordPartial(err error, parser Parser, mapping *preprocessing, options Options) {
+	if options.Partial == nil {
+		return
+	}
+	if err == nil {
+		*options.Partial = PartialResult{}
+		return
+	}
+	*options.Partial = *newPartialResult(err, parser)
+	mapping.mapPartial(options.Partial, options.RunePositions)
+}
@@ -0,0 +1,39 @@
+package dialects_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/AdamJonR/dialects"
+)
+
+func TestPartial(t *testing.T) {
+	g := statements()
+	g.dialect.PartDefinitions["root"] = dialects.PartDefinition{Constituents: [][]string{{"statement+", "end"}}}
+	g.dialect.PartDefinitions["end"] = dialects.PartDefinition{EOF: true}
+	partial := &dialects.PartialResult{}
+	input := "let a = 1;\nlet b = 2 + 3;\nlet c = ;"
+	if _, err, _ := dialects.ParseToTreeWithOptions(g, input, dialects.Options{Partial: partial}); err == nil {
+		t.Fatal("expected the parse to fail")
+	}
+	// the statements that parsed are kept, followed by the parts of the one that didn't
+	var names []string
+	for _, part := range partial.Parts {
+		names = append(names, part.Name+":"+part.Value)
+	}
+	expected := "statement:let a = 1; statement:let b = 2 + 3; let:let name:c equals:="
+	if strings.Join(names, " ") != expected {
+		t.Errorf("expected parts %q, got %q", expected, strings.Join(names, " "))
+	}
+	if partial.Parts[1].StartLine != 2 || len(partial.Parts[1].Constituents) != 5 || partial.Parts[1].Parent == nil {
+		t.Errorf("expected the second statement to keep its position and constituents, got %v", partial.Parts[1])
+	}
+	offset := strings.Index(input, " ;") + 1
+	if partial.Offset != offset || partial.Line != 3 || partial.Column != 9 || strings.Join(partial.PartNames, ",") != "value" || strings.Join(partial.Expected, ",") != "expression" {
+		t.Errorf("expected the failure at offset %d, line 3, column 9, expecting a value, got %+v", offset, partial)
+	}
+	// a parse that succeeds resets the result
+	if _, err, _ := dialects.ParseWithOptions(g, "let a = 1;", dialects.Options{Partial: partial}); err != nil || partial.Parts != nil || partial.Offset != 0 {
+		t.Errorf("expected the result to be reset, got %+v and %v", partial, err)
+	}
+}
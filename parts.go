@@ -0,0 +1,102 @@
+package dialects
+
+import (
+	"strconv"
+	"strings"
+)
+
+// WhitespacePart returns the definition of an Ignored part matching spaces, tabs, and line endings
+func WhitespacePart() PartDefinition {
+	return PartDefinition{Description: "whitespace", Regex: `[ \t\r\n]+`, Ignore: true}
+}
+
+// IdentifierPart returns the definition of a part matching an identifier of letters, digits, and underscores that
+// doesn't start with a digit
+func IdentifierPart() PartDefinition {
+	return PartDefinition{Description: "identifier", Regex: `[A-Za-z_][A-Za-z0-9_]*`}
+}
+
+// IntegerPart returns the definition of a part matching a decimal integer with an optional sign, rejecting integers
+// that don't fit in an int64
+func IntegerPart() PartDefinition {
+	return PartDefinition{
+		Description: "integer",
+		Regex:       `[-+]?[0-9]+`,
+		ValidateMatch: func(matches []string) (bool, string) {
+			if _, err := strconv.ParseInt(matches[0], 10, 64); err != nil {
+				return false, "out of range"
+			}
+			return true, ""
+		},
+	}
+}
+
+// FloatPart returns the definition of a part matching a decimal number with an optional sign that has a fraction, an
+// exponent, or both, rejecting numbers that don't fit in a float64
+func FloatPart() PartDefinition {
+	return PartDefinition{
+		Description: "number",
+		Regex:       `[-+]?(?:[0-9]+\.[0-9]*(?:[eE][-+]?[0-9]+)?|\.[0-9]+(?:[eE][-+]?[0-9]+)?|[0-9]+[eE][-+]?[0-9]+)`,
+		ValidateMatch: func(matches []string) (bool, string) {
+			if _, err := strconv.ParseFloat(matches[0], 64); err != nil {
+				return false, "out of range"
+			}
+			return true, ""
+		},
+	}
+}
+
+// QuotedStringPart returns the definition of a part matching a double-quoted string on one line, whose Value holds its
+// contents with Go escape sequences such as \n, \", and \u00e9 replaced, rejecting strings that aren't closed or have
+// invalid escape sequences
+func QuotedStringPart() PartDefinition {
+	return PartDefinition{
+		Description: "string",
+		// the closing quote is optional so an unterminated string can be reported as such
+		Regex: `"((?:[^"\\\n]|\\.)*)("?)`,
+		ValidateMatch: func(matches []string) (bool, string) {
+			if matches[2] == "" {
+				return false, "unterminated string"
+			}
+			if _, err := strconv.Unquote(matches[0]); err != nil {
+				return false, "invalid escape sequence"
+			}
+			return true, ""
+		},
+		FormatMatch: func(matches []string) string {
+			contents, err := strconv.Unquote(matches[0])
+			if err != nil {
+				return matches[1]
+			}
+			return contents
+		},
+	}
+}
+
+// singleQuoteUnescaper replaces the escape sequences of single-quoted strings
+var singleQuoteUnescaper = strings.NewReplacer(`\'`, `'`, `\\`, `\`)
+
+// SingleQuotedStringPart returns the definition of a part matching a single-quoted string on one line, whose Value
+// holds its contents with \' and \\ replaced by the characters they escape, leaving any other backslashes as written,
+// rejecting strings that aren't closed
+func SingleQuotedStringPart() PartDefinition {
+	return PartDefinition{
+		Description: "string",
+		// the closing quote is optional so an unterminated string can be reported as such
+		Regex: `'((?:[^'\\\n]|\\.)*)('?)`,
+		ValidateMatch: func(matches []string) (bool, string) {
+			if matches[2] == "" {
+				return false, "unterminated string"
+			}
+			return true, ""
+		},
+		FormatMatch: func(matches []string) string {
+			return singleQuoteUnescaper.Replace(matches[1])
+		},
+	}
+}
+
+// BooleanPart returns the definition of a part matching true or false, but not the start of a longer word
+func BooleanPart() PartDefinition {
+	return PartDefinition{Description: "boolean", Regex: `(?:true|false)\b`}
+}
@@ -0,0 +1,60 @@
+package dialects_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/AdamJonR/dialects"
+)
+
+func TestParts(t *testing.T) {
+	tests := []struct {
+		name     string
+		part     dialects.PartDefinition
+		input    string
+		expected string
+		err      string
+	}{
+		{"whitespace", dialects.WhitespacePart(), " \t\r\n", " \t\r\n", ""},
+		{"whitespace missing", dialects.WhitespacePart(), "x", "", "expected whitespace"},
+		{"identifier", dialects.IdentifierPart(), "_total2", "_total2", ""},
+		{"identifier starting with a digit", dialects.IdentifierPart(), "2total", "", "expected identifier"},
+		{"integer", dialects.IntegerPart(), "-42", "-42", ""},
+		{"integer with plus", dialects.IntegerPart(), "+7", "+7", ""},
+		{"integer out of range", dialects.IntegerPart(), "9223372036854775808", "", "invalid value: out of range"},
+		{"float", dialects.FloatPart(), "3.25", "3.25", ""},
+		{"float without digits after the point", dialects.FloatPart(), "3.", "3.", ""},
+		{"float without digits before the point", dialects.FloatPart(), "-.5", "-.5", ""},
+		{"float with exponent", dialects.FloatPart(), "1e-3", "1e-3", ""},
+		{"float with fraction and exponent", dialects.FloatPart(), "6.02E23", "6.02E23", ""},
+		{"float without fraction or exponent", dialects.FloatPart(), "3", "", "expected number"},
+		{"float out of range", dialects.FloatPart(), "1e400", "", "invalid value: out of range"},
+		{"quoted string", dialects.QuotedStringPart(), `"a \"b\"\tcé"`, "a \"b\"\tcé", ""},
+		{"empty quoted string", dialects.QuotedStringPart(), `""`, "", ""},
+		{"quoted string with invalid escape", dialects.QuotedStringPart(), `"a\q"`, "", "invalid value: invalid escape sequence"},
+		{"unterminated quoted string", dialects.QuotedStringPart(), `"abc`, "", "invalid value: unterminated string"},
+		{"quoted string ending in an escaped quote", dialects.QuotedStringPart(), `"abc\"`, "", "invalid value: unterminated string"},
+		{"quoted string across lines", dialects.QuotedStringPart(), "\"ab\nc\"", "", "invalid value: unterminated string"},
+		{"single-quoted string", dialects.SingleQuotedStringPart(), `'it\'s a \\ \n'`, `it's a \ \n`, ""},
+		{"unterminated single-quoted string", dialects.SingleQuotedStringPart(), `'abc\'`, "", "invalid value: unterminated string"},
+		{"true", dialects.BooleanPart(), "true", "true", ""},
+		{"false", dialects.BooleanPart(), "false", "false", ""},
+		{"boolean starting a word", dialects.BooleanPart(), "trueish", "", "expected boolean"},
+	}
+	for _, test := range tests {
+		root, err, _ := dialects.ParseToTree(newGrammar("value", map[string]dialects.PartDefinition{"value": test.part}), test.input)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%s: expected %q, got %v", test.name, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if root.Value != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.name, test.expected, root.Value)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package dialects
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Preprocessor defines the optional interface for grammars that normalize their input before it's parsed, such as
+// by collapsing CRLF line endings or replacing smart quotes, returning the OffsetMap from the preprocessed input back
+// to the original input, or nil if the preprocessing didn't move any text
+type Preprocessor interface {
+	Preprocess(input string) (string, *OffsetMap, error)
+}
+
+// OffsetMap provides the original offsets of the text of a preprocessed input, so the positions of parts and parse
+// errors can be reported relative to the input as it was given, and the zero value maps every offset to itself
+type OffsetMap struct {
+	points []offsetPoint
+	// next holds the map the offsets this one returns are passed through, when the input was preprocessed in steps
+	next *OffsetMap
+}
+
+// offsetPoint provides an offset of the preprocessed input and the offset of the original input its text came from
+type offsetPoint struct {
+	preprocessed int
+	original     int
+}
+
+// Add records that the text of the preprocessed input from the offset came from the original offset, up to the next
+// offset added, which must come no earlier than this one
+func (offsets *OffsetMap) Add(preprocessed int, original int) {
+	offsets.points = append(offsets.points, offsetPoint{preprocessed: preprocessed, original: original})
+}
+
+// Original returns the offset of the original input that the offset of the preprocessed input came from, where an
+// offset within replaced text maps into the text it replaced, and a nil map maps every offset to itself
+func (offsets *OffsetMap) Original(offset int) int {
+	if offsets == nil {
+		return offset
+	}
+	return offsets.next.Original(offsets.original(offset))
+}
+
+// original returns the offset that the offset came from according to the points of this map alone
+func (offsets *OffsetMap) original(offset int) int {
+	// find the last point at or before the offset, where a later point at the same offset wins so removed text
+	// maps to the text after it
+	next := sort.Search(len(offsets.points), func(i int) bool { return offsets.points[i].preprocessed > offset })
+	if next == 0 {
+		return offset
+	}
+	point := offsets.points[next-1]
+	original := point.original + offset - point.preprocessed
+	if next < len(offsets.points) && original > offsets.points[next].original {
+		return offsets.points[next].original
+	}
+	return original
+}
+
+// followedBy returns the map passing the offsets the map returns through the next map, where a nil map maps every
+// offset to itself
+func (offsets *OffsetMap) followedBy(next *OffsetMap) *OffsetMap {
+	if offsets == nil {
+		return next
+	}
+	if next == nil {
+		return offsets
+	}
+	return &OffsetMap{points: offsets.points, next: offsets.next.followedBy(next)}
+}
+
+// Replace returns the input with each old string replaced by its new string, given as pairs like strings.NewReplacer,
+// trying the pairs in order at each position, along with the OffsetMap back to the input, or nil if nothing was
+// replaced
+func Replace(input string, oldnew ...string) (string, *OffsetMap) {
+	if len(oldnew)%2 == 1 {
+		panic("dialects.Replace: odd argument count")
+	}
+	var offsets *OffsetMap
+	var replaced strings.Builder
+	last := 0
+	for pos := 0; pos < len(input); {
+		old, replacement := "", ""
+		for i := 0; i < len(oldnew); i += 2 {
+			if oldnew[i] != "" && strings.HasPrefix(input[pos:], oldnew[i]) {
+				old, replacement = oldnew[i], oldnew[i+1]
+				break
+			}
+		}
+		if old == "" {
+			pos++
+			continue
+		}
+		if offsets == nil {
+			offsets = &OffsetMap{}
+			replaced.Grow(len(input))
+		}
+		replaced.WriteString(input[last:pos])
+		// the new text maps into the old text, and the text after it resumes after the old text
+		offsets.Add(replaced.Len(), pos)
+		replaced.WriteString(replacement)
+		pos += len(old)
+		offsets.Add(replaced.Len(), pos)
+		last = pos
+	}
+	if offsets == nil {
+		return input, nil
+	}
+	replaced.WriteString(input[last:])
+	return replaced.String(), offsets
+}
+
+// preprocessing provides the original input of a parse and the map from the offsets of the preprocessed input
+type preprocessing struct {
+	original string
+	offsets  *OffsetMap
+	// lineStarts holds the offset of the start of each line of the original input, and lineRunes holds the number
+	// of runes before each
+	lineStarts []int
+	lineRunes  []int
+}
+
+// preprocess returns the input without its UTF-8 byte order mark, with its CRLF line endings collapsed if the dialect
+// sets NormalizeLineEndings, then preprocessed by the dialect if it's a Preprocessor, along with the preprocessing to
+// map positions back to the input, which is nil if the offsets are unchanged, returning an EncodingError if the input
+// isn't UTF-8
+func (compiled *CompiledDialect) preprocess(input string) (string, *preprocessing, error) {
+	preprocessed, encoding, err := checkEncoding(input, compiled.dialect)
+	if err != nil {
+		return input, nil, err
+	}
+	var lineEndings, offsets *OffsetMap
+	if compiled.dialect.NormalizeLineEndings {
+		preprocessed, lineEndings = Replace(preprocessed, "\r\n", "\n")
+	}
+	if preprocessor, ok := compiled.dialectable.(Preprocessor); ok {
+		if preprocessed, offsets, err = preprocessor.Preprocess(preprocessed); err != nil {
+			return preprocessed, nil, err
+		}
+	}
+	// the dialect's offsets lead back to the normalized input, whose offsets lead back to the input without its byte
+	// order mark, whose offsets lead back to the input
+	offsets = offsets.followedBy(lineEndings).followedBy(encoding)
+	if offsets == nil {
+		return preprocessed, nil, nil
+	}
+	return preprocessed, newPreprocessing(input, offsets), nil
+}
+
+// newPreprocessing returns the preprocessing that maps positions back to the original input with the offsets
+func newPreprocessing(original string, offsets *OffsetMap) *preprocessing {
+	mapping := &preprocessing{original: original, offsets: offsets, lineStarts: lineStarts(original)}
+	mapping.lineRunes = make([]int, len(mapping.lineStarts))
+	for i := 1; i < len(mapping.lineStarts); i++ {
+		mapping.lineRunes[i] = mapping.lineRunes[i-1] + utf8.RuneCountInString(original[mapping.lineStarts[i-1]:mapping.lineStarts[i]])
+	}
+	return mapping
+}
+
+// within returns the preprocessing that maps positions of the text, as preprocessed by the mapping, which may be nil,
+// back to the host input the text starts at the offset of, where host maps positions of the host to themselves
+func (mapping *preprocessing) within(host *preprocessing, offset int) *preprocessing {
+	offsets := &OffsetMap{}
+	offsets.Add(0, offset)
+	if mapping != nil {
+		offsets = mapping.offsets.followedBy(offsets)
+	}
+	return &preprocessing{original: host.original, offsets: offsets, lineStarts: host.lineStarts, lineRunes: host.lineRunes}
+}
+
+// position returns the original offset of the preprocessed offset, along with its 1-based line, byte column, and rune
+// column, and its offset in runes
+func (mapping *preprocessing) position(offset int) (original int, line int, column int, runeColumn int, runeOffset int) {
+	original = mapping.offsets.Original(offset)
+	// clamp the offset in case the map was built by hand
+	if original < 0 {
+		original = 0
+	} else if original > len(mapping.original) {
+		original = len(mapping.original)
+	}
+	line = sort.SearchInts(mapping.lineStarts, original+1)
+	lineStart := mapping.lineStarts[line-1]
+	runeColumn = utf8.RuneCountInString(mapping.original[lineStart:original]) + 1
+	runeOffset = mapping.lineRunes[line-1] + runeColumn - 1
+	column = original - lineStart + 1
+	if splitsCRLF(mapping.original, original) {
+		column, runeColumn = column-1, runeColumn-1
+	}
+	return original, line, column, runeColumn, runeOffset
+}
+
+// mapTree moves the positions of the part and its descendants back to the original input
+func (mapping *preprocessing) mapTree(root *Part, runePositions bool) {
+	if mapping == nil {
+		return
+	}
+	Walk(root, func(p *Part, depth int) bool {
+		var startRune, endRune int
+		p.StartPos, p.StartLine, _, p.StartCol, startRune = mapping.position(p.StartPos)
+		p.EndPos, p.EndLine, _, p.EndCol, endRune = mapping.position(p.EndPos)
+		if runePositions {
+			p.StartRune, p.EndRune = startRune, endRune
+		}
+		// the source is the text of the original input, which preprocessing may have changed
+		if p.Found {
+			p.Source = mapping.original[p.StartPos:p.EndPos]
+		}
+		return true
+	})
+}
+
+// mapError moves the position of the parse error, if the error is one, back to the original input, taking its
+// snippet from the original input unless snippets are omitted, and returns the error
+func (mapping *preprocessing) mapError(err error, omitSnippets bool) error {
+	if mapping == nil {
+		return err
+	}
+	var parseError *ParseError
+	snippet, encoding := !omitSnippets, false
+	switch typed := err.(type) {
+	case *ParseError:
+		parseError = typed
+	case *LimitError:
+		parseError = typed.ParseError
+	case *EncodingError:
+		parseError = typed.ParseError
+		// input in another encoding would be garbled, so it's left out, as it is before mapping
+		snippet, encoding = snippet && typed.Encoding == "", true
+	case *RecoveredError:
+		for _, recovered := range typed.Errors {
+			mapping.mapError(recovered, omitSnippets)
+		}
+		return err
+	default:
+		return err
+	}
+	var runeOffset, runeColumn int
+	if parseError.length > 0 {
+		end, _, _, _, _ := mapping.position(parseError.Offset + parseError.length)
+		parseError.length = end - mapping.offsets.Original(parseError.Offset)
+	}
+	parseError.Offset, parseError.Line, parseError.Column, runeColumn, runeOffset = mapping.position(parseError.Offset)
+	if snippet {
+		parseError.LineText = lineText(mapping.original, parseError.Offset)
+	}
+	if snippet && encoding {
+		// invalid byte sequences are shown as replacement characters, as they are before mapping
+		parseError.LineText = strings.ToValidUTF8(parseError.LineText, "\uFFFD")
+	}
+	if parseError.RuneColumn > 0 {
+		parseError.RuneOffset, parseError.RuneColumn = runeOffset, runeColumn
+	}
+	parseError.VisualColumn = visualColumn(mapping.original, parseError.Offset, parseError.TabWidth)
+	return err
+}
+
+// mapTokens moves the positions of the tokens back to the original input
+func (mapping *preprocessing) mapTokens(tokens []Token) {
+	if mapping == nil {
+		return
+	}
+	for i := range tokens {
+		tokens[i].StartPos, tokens[i].Line, _, _, _ = mapping.position(tokens[i].StartPos)
+		tokens[i].EndPos, _, _, _, _ = mapping.position(tokens[i].EndPos)
+	}
+}
+
+// mapPartial moves the positions of the partial result and its parts back to the original input
+func (mapping *preprocessing) mapPartial(partial *PartialResult, runePositions bool) {
+	if mapping == nil {
+		return
+	}
+	for _, part := range partial.Parts {
+		mapping.mapTree(part, runePositions)
+	}
+	partial.Offset, partial.Line, partial.Column, _, _ = mapping.position(partial.Offset)
+}
@@ -0,0 +1,86 @@
+package dialects_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/AdamJonR/dialects"
+)
+
+// normalized provides a grammar that strips byte order marks and collapses CRLF line endings before parsing
+type normalized struct {
+	grammar
+}
+
+func (normalized) Preprocess(input string) (string, *dialects.OffsetMap, error) {
+	output, offsets := dialects.Replace(input, "\ufeff", "", "\r\n", "\n")
+	return output, offsets, nil
+}
+
+// rejected provides a grammar whose preprocessing fails
+type rejected struct {
+	grammar
+}
+
+var errBinary = errors.New("input is binary")
+
+func (rejected) Preprocess(input string) (string, *dialects.OffsetMap, error) {
+	return "", nil, errBinary
+}
+
+func TestPreprocess(t *testing.T) {
+	// the words don't allow carriage returns, so the input only parses once they're removed
+	input := "\ufeff" + strings.Repeat("ab cd\r\n", 9) + "ab 12\r\n"
+	_, err, _ := dialects.Parse(normalized{wordList()}, input)
+	var parseError *dialects.ParseError
+	if !errors.As(err, &parseError) {
+		t.Fatalf("expected a ParseError, got %v", err)
+	}
+	// the error on line 10 is reported at its offset in the original input, past the BOM and nine carriage returns
+	offset := strings.Index(input, "12")
+	if parseError.Offset != offset || parseError.Line != 10 || parseError.Column != 4 || parseError.LineText != "ab 12" {
+		t.Errorf("expected offset %d at line 10, column 4 of 'ab 12', got offset %d at line %d, column %d of %q", offset, parseError.Offset, parseError.Line, parseError.Column, parseError.LineText)
+	}
+	root, err, _ := dialects.ParseToTreeWithOptions(normalized{wordList()}, input[:offset], dialects.Options{RunePositions: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the first word starts after the BOM, which is one rune
+	first := root.Constituents[0]
+	if first.StartPos != 3 || first.StartLine != 1 || first.StartCol != 2 || first.StartRune != 1 {
+		t.Errorf("expected the first item to start at offset 3, line 1, column 2, rune 1, got %d, %d, %d, %d", first.StartPos, first.StartLine, first.StartCol, first.StartRune)
+	}
+	// the item ending a line ends after its CRLF
+	line := root.Constituents[1]
+	if line.EndPos != 3+7 || line.EndLine != 2 || line.EndCol != 1 {
+		t.Errorf("expected the second item to end at offset 10, line 2, column 1, got %d, %d, %d", line.EndPos, line.EndLine, line.EndCol)
+	}
+	last := root.Constituents[len(root.Constituents)-1]
+	if last.StartPos != offset-3 || last.StartLine != 10 || last.EndPos != offset {
+		t.Errorf("expected the last item to span [%d:%d] on line 10, got [%d:%d] on line %d", offset-3, offset, last.StartPos, last.EndPos, last.StartLine)
+	}
+}
+
+func TestPreprocessError(t *testing.T) {
+	_, err, _ := dialects.Parse(rejected{wordList()}, "ab")
+	if !errors.Is(err, errBinary) {
+		t.Errorf("expected the preprocessing error, got %v", err)
+	}
+}
+
+func TestReplace(t *testing.T) {
+	output, offsets := dialects.Replace("a\r\nb\u201cc\u201d", "\r\n", "\n", "\u201c", `"`, "\u201d", `"`)
+	if output != "a\nb\"c\"" {
+		t.Fatalf("expected the replaced text, got %q", output)
+	}
+	// offsets within replacements map to the start of the text they replaced, and the rest shift with them
+	for offset, original := range []int{0, 1, 3, 4, 7, 8, 11} {
+		if got := offsets.Original(offset); got != original {
+			t.Errorf("expected offset %d to map to %d, got %d", offset, original, got)
+		}
+	}
+	if output, offsets := dialects.Replace("ab", "\r\n", "\n"); output != "ab" || offsets != nil {
+		t.Errorf("expected the input and a nil map when nothing is replaced, got %q and %v", output, offsets)
+	}
+}
@@ -0,0 +1,48 @@
+package dialects
+
+import "strings"
+
+// Print returns the source text of the part and its descendants, written from the Sources of the leaves the parser
+// found and the Values of those a transform added, in order, so a tree parsed with Options.KeepIgnored prints exactly
+// the input it was parsed from and a tree that was transformed prints deterministically, with each part the parser
+// didn't find printed with the PrintHint of its definition when it has one
+func Print(dialectable Dialectable, root *Part) string {
+	var source strings.Builder
+	printPart(root, dialectable.NewDialect(), &source)
+	return source.String()
+}
+
+// printPart writes the text of the part to the source, using its PrintHint if it was synthesized
+func printPart(part *Part, dialect *Dialect, source *strings.Builder) {
+	if part == nil {
+		return
+	}
+	// parts found by the parser print as written
+	hint := dialect.PartDefinitions[part.Name].PrintHint
+	if part.Found || hint == "" {
+		printContent(part, dialect, source)
+		return
+	}
+	before, after, hasContent := strings.Cut(hint, PrintHintContent)
+	source.WriteString(before)
+	if hasContent {
+		printContent(part, dialect, source)
+		source.WriteString(after)
+	}
+}
+
+// printContent writes the text of a leaf, which is its Source if the parser found it and its Value otherwise, or the
+// text of each of a part's constituents, to the source
+func printContent(part *Part, dialect *Dialect, source *strings.Builder) {
+	if len(part.Constituents) == 0 {
+		if part.Found {
+			source.WriteString(part.Source)
+		} else {
+			source.WriteString(part.Value)
+		}
+		return
+	}
+	for _, constituent := range part.Constituents {
+		printPart(constituent, dialect, source)
+	}
+}
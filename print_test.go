@@ -0,0 +1,97 @@
+package dialects_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/AdamJonR/dialects"
+)
+
+// statements returns a grammar of let statements with comments and free spacing, with hints for printing
+// synthesized statements
+func statements() grammar {
+	g := newGrammar("root", map[string]dialects.PartDefinition{
+		"root":      {Constituents: [][]string{{"statement+"}}},
+		"statement": {Constituents: [][]string{{"let", "name", "equals", "value", "semicolon"}}, PrintHint: "{}\n"},
+		"let":       {Keyword: "let", PrintHint: "{} "},
+		"name":      {Regex: `[a-z]+`},
+		"equals":    {Literal: "=", PrintHint: " = "},
+		"value":     dialects.ExpressionPart("number", []dialects.OpLevel{{Operators: []string{"+"}}}),
+		"number":    {Regex: `[0-9]+`},
+		"semicolon": {Literal: ";"},
+	})
+	g.dialect.SkipPattern = `[ \t\n]+`
+	g.dialect.LineComment = "#"
+	return g
+}
+
+func TestPrint(t *testing.T) {
+	input := "# totals\nlet a = 1 + 2;\n  let b=3 ;  # trailing\n"
+	root, err, _ := dialects.ParseToTreeWithOptions(statements(), input, dialects.Options{KeepIgnored: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if source := dialects.Print(statements(), root); source != input {
+		t.Errorf("expected the input to round-trip, got %q", source)
+	}
+	// a synthesized statement is printed with the hints of its parts
+	values := []string{"let", "c", "=", "4", ";"}
+	statement := &dialects.Part{Name: "statement"}
+	for i, name := range []string{"let", "name", "equals", "value", "semicolon"} {
+		statement.Constituents = append(statement.Constituents, &dialects.Part{Name: name, Value: values[i]})
+	}
+	root.Constituents = append(root.Constituents, statement)
+	if source := dialects.Print(statements(), root); source != input+"let c = 4;\n" {
+		t.Errorf("expected the synthesized statement to be printed with its hints, got %q", source)
+	}
+	// without the ignored parts, only the parts matched are printed
+	root, err, _ = dialects.ParseToTree(statements(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if source := dialects.Print(statements(), root); source != "leta=1+2;letb=3;" {
+		t.Errorf("expected the parts without spacing or comments, got %q", source)
+	}
+}
+
+func TestPrintFormatMatch(t *testing.T) {
+	g := statements()
+	input := "let s = \"a\\\"b\" ;\nlet t='c';\n"
+	g.dialect.PartDefinitions["value"] = dialects.PartDefinition{Constituents: [][]string{{"double"}, {"single"}}}
+	g.dialect.PartDefinitions["double"] = dialects.QuotedStringPart()
+	g.dialect.PartDefinitions["single"] = dialects.SingleQuotedStringPart()
+	root, err, _ := dialects.ParseToTreeWithOptions(g, input, dialects.Options{KeepIgnored: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the strings are printed as written rather than from their unescaped Values
+	if double := root.First("double"); double == nil || double.Value != `a"b` {
+		t.Fatalf("expected the unescaped value %q, got %+v", `a"b`, double)
+	}
+	if source := dialects.Print(g, root); source != input {
+		t.Errorf("expected the input to round-trip, got %q", source)
+	}
+	// a tree decoded from JSON prints the same, with no hints applied to the parts the parser found
+	data, err := json.Marshal(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded dialects.Part
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if source := dialects.Print(g, &decoded); source != input {
+		t.Errorf("expected the decoded tree to round-trip, got %q", source)
+	}
+	// a synthesized part keeps its hints through JSON
+	decoded.Constituents = append(decoded.Constituents, &dialects.Part{Name: "equals", Value: "="})
+	if data, err = json.Marshal(&decoded); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if source := dialects.Print(g, &decoded); source != input+" = " {
+		t.Errorf("expected the synthesized part to print with its hint, got %q", source)
+	}
+}
@@ -0,0 +1,61 @@
+package dialects
+
+import (
+	"io"
+	"os"
+	"strings"
+)
+
+// ParseReader parses the input read from the reader like Parse, reading the whole input into memory before parsing it
+// and holding it there in a single buffer that the parts' Values slice, returning the reader's error if it fails; the
+// input isn't read incrementally as the parse needs it, since the parse can backtrack to any earlier position and a
+// regex could match past the end of a partial buffer
+func ParseReader(dialectable Dialectable, r io.Reader) (string, error, string) {
+	compiled, err := Compile(dialectable)
+	if err != nil {
+		return "", err, ""
+	}
+	return compiled.ParseReader(r, Options{})
+}
+
+// ParseReader parses the input read from the reader like ParseWithOptions, reading the whole input into memory before
+// parsing it and holding it there in a single buffer that the parts' Values slice, returning the reader's error if it
+// fails
+func (compiled *CompiledDialect) ParseReader(r io.Reader, options Options) (string, error, string) {
+	input, err := readInput(r)
+	if err != nil {
+		return "", err, ""
+	}
+	return compiled.ParseWithOptions(input, options)
+}
+
+// ParseToTreeReader parses the input read from the reader like ParseToTreeWithOptions, reading the whole input into
+// memory before parsing it and holding it there in a single buffer that the parts' Values slice, returning the
+// reader's error if it fails
+func (compiled *CompiledDialect) ParseToTreeReader(r io.Reader, options Options) (*Part, error, string) {
+	input, err := readInput(r)
+	if err != nil {
+		return nil, err, ""
+	}
+	return compiled.ParseToTreeWithOptions(input, options)
+}
+
+// readInput returns all of the input from the reader, read in full before the parse starts rather than as the parse
+// needs it, growing the buffer to the input's size first when the reader knows it, so reading doesn't copy the input
+// as the buffer grows, and the string shares the buffer
+func readInput(r io.Reader) (string, error) {
+	var buffer strings.Builder
+	switch sized := r.(type) {
+	case interface{ Len() int }:
+		// readers like bytes.Reader and strings.Reader report what's left to read
+		buffer.Grow(sized.Len())
+	case *os.File:
+		if info, err := sized.Stat(); err == nil && info.Mode().IsRegular() {
+			buffer.Grow(int(info.Size()))
+		}
+	}
+	if _, err := io.Copy(&buffer, r); err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
@@ -0,0 +1,50 @@
+package dialects_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/AdamJonR/dialects"
+)
+
+func TestParseReader(t *testing.T) {
+	input := strings.Repeat("ab cd\n", 1000)
+	expected, err, _ := dialects.Parse(wordList(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a reader returning a byte at a time is read to the end before parsing
+	output, err, _ := dialects.ParseReader(wordList(), iotest.OneByteReader(strings.NewReader(input)))
+	if err != nil || output != expected {
+		t.Errorf("expected the same output as Parse, got %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "input.txt")
+	if err := os.WriteFile(path, []byte(input), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	compiled, err := dialects.Compile(wordList())
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err, _ := compiled.ParseToTreeReader(file, dialects.Options{})
+	if err != nil || len(root.Constituents) != 2000 || root.Constituents[1999].Value != "cd\n" {
+		t.Errorf("expected 2000 items from the file, got %v", err)
+	}
+}
+
+func TestParseReaderError(t *testing.T) {
+	readErr := errors.New("connection reset")
+	_, err, _ := dialects.ParseReader(wordList(), iotest.ErrReader(readErr))
+	if !errors.Is(err, readErr) {
+		t.Errorf("expected the reader's error, got %v", err)
+	}
+}
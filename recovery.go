@@ -0,0 +1,178 @@
+package dialects
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrorPartName provides the name of the parts holding the text skipped to recover from a syntax error in a part with
+// RecoverAt, which take the place of the part in the tree
+const ErrorPartName = "$error"
+
+// RecoveredError reports the syntax errors that a parse recovered from, in order of position, followed by the error
+// that made the parse fail if it still did, and is returned by ParseToTree along with the tree of what parsed
+type RecoveredError struct {
+	Errors []error
+}
+
+// Error returns the message of each error on its own line
+func (e *RecoveredError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Unwrap returns the errors, so errors.As finds the first ParseError
+func (e *RecoveredError) Unwrap() []error {
+	return e.Errors
+}
+
+// attemptRecovering returns the parts found like attemptPart, though if the part has RecoverAt and fails after
+// matching some of its input, it recovers by skipping to one of the RecoverAt parts, returning an ErrorPart
+func attemptRecovering(partName string, parser Parser, parent *Part) []*Part {
+	recoverAt := parser.dialect.PartDefinitions[partName].RecoverAt
+	if parser.recovered == nil || parser.lookahead || len(recoverAt) == 0 {
+		return attemptPart(partName, parser, parent)
+	}
+	start := saveState(parser)
+	partStart := skippedTo(parser, parent)
+	// track the part's failures apart from the others, so it's known how far it got, and what it expected there or
+	// which of its handlers failed
+	outer := *parser.failure
+	parser.failure.offset, parser.failure.line, parser.failure.partNames, parser.failure.messages, parser.failure.texts, parser.failure.semantic = 0, 0, nil, nil, nil, nil
+	parts := attemptPart(partName, parser, parent)
+	semantic := parser.failure.semantic
+	progressed := len(parser.failure.partNames) > 0 && parser.failure.offset > partStart
+	if parts != nil || (!progressed && semantic == nil) || parser.failure.abort != nil {
+		mergeFailure(outer, parser)
+		if outer.semantic != nil {
+			parser.failure.semantic = outer.semantic
+		}
+		return parts
+	}
+	// a handler's error explains the failure better than the grammar can
+	diagnostic := semantic
+	if diagnostic == nil {
+		diagnostic = expectedError(parser)
+	}
+	restoreFailure(outer, parser)
+	parser.failure.semantic = outer.semantic
+	restoreState(start, parser)
+	return recoverPart(recoverAt, diagnostic, parser, parent)
+}
+
+// skippedTo returns the position the part would start at after skipping text, without skipping it
+func skippedTo(parser Parser, parent *Part) int {
+	if !skipping(parser) || parser.noSkip || parent == nil {
+		return *parser.currentPosPointer
+	}
+	start := saveState(parser)
+	skipText(parser, parent)
+	pos := *parser.currentPosPointer
+	restoreState(start, parser)
+	return pos
+}
+
+// recoverPart records the syntax error and skips from it to the end of the first RecoverAt part found after it, or
+// to the end of the input, returning an ErrorPart for the text from the start of the part
+func recoverPart(recoverAt []string, diagnostic *ParseError, parser Parser, parent *Part) []*Part {
+	var parts []*Part
+	if skipping(parser) && !parser.noSkip && parent != nil {
+		parts = skipText(parser, parent)
+	}
+	part := &Part{
+		Name:      ErrorPartName,
+		Parent:    parent,
+		Found:     true,
+		Path:      childPath(parent, parser),
+		StartPos:  *parser.currentPosPointer,
+		StartLine: currentLine(parser),
+		StartCol:  parser.cursor.column,
+		StartRune: parser.cursor.runes,
+	}
+	// the failed attempts to find the RecoverAt parts aren't failures of the parse
+	outer := *parser.failure
+	advance(parser.input[*parser.currentPosPointer:diagnostic.Offset], parser)
+	for synchronized := false; !synchronized; {
+		for _, name := range recoverAt {
+			if synchronized = len(findOne(name, parser, part)) > 0; synchronized {
+				break
+			}
+		}
+		if *parser.currentPosPointer >= len(parser.input) {
+			break
+		}
+		if !synchronized {
+			_, width := utf8.DecodeRuneInString(parser.input[*parser.currentPosPointer:])
+			advance(parser.input[*parser.currentPosPointer:*parser.currentPosPointer+width], parser)
+		}
+	}
+	restoreFailure(outer, parser)
+	part.EndPos = *parser.currentPosPointer
+	part.EndLine, part.EndCol = currentLine(parser), parser.cursor.column
+	part.EndRune = parser.cursor.runes
+	part.Value = parser.input[part.StartPos:part.EndPos]
+	part.Source = part.Value
+	parser.recovered.items = append(parser.recovered.items, diagnostic)
+	return append(parts, part)
+}
+
+// restoreFailure sets the failure tracker back to the snapshot, keeping any semantic error or abort since
+func restoreFailure(snapshot failure, parser Parser) {
+	semantic, abort := parser.failure.semantic, parser.failure.abort
+	*parser.failure = snapshot
+	parser.failure.semantic, parser.failure.abort = semantic, abort
+}
+
+// mergeFailure combines the failures recorded since the snapshot with those before it, keeping the farthest
+func mergeFailure(snapshot failure, parser Parser) {
+	since := *parser.failure
+	restoreFailure(snapshot, parser)
+	switch {
+	case len(since.partNames) < 1 || (len(snapshot.partNames) > 0 && since.offset < snapshot.offset):
+	case len(snapshot.partNames) < 1 || since.offset > snapshot.offset:
+		parser.failure.offset, parser.failure.line = since.offset, since.line
+		parser.failure.partNames, parser.failure.messages, parser.failure.texts = since.partNames, since.messages, since.texts
+	default:
+		// both got as far, so the parts that failed there are those of both, each named once
+		for _, name := range since.partNames {
+			if !containsName(parser.failure.partNames, name) {
+				parser.failure.partNames = append(parser.failure.partNames, name)
+			}
+		}
+		parser.failure.messages = append(parser.failure.messages, since.messages...)
+		for _, text := range since.texts {
+			if !containsName(parser.failure.texts, text) {
+				parser.failure.texts = append(parser.failure.texts, text)
+			}
+		}
+	}
+}
+
+// recoveredError returns the error for the syntax errors the parse recovered from followed by the error it failed
+// with, if any, or just the error if it didn't recover from any
+func recoveredError(err error, parser Parser) error {
+	if parser.recovered.length() == 0 {
+		return err
+	}
+	recovered := &RecoveredError{}
+	for _, diagnostic := range parser.recovered.items {
+		recovered.Errors = append(recovered.Errors, diagnostic)
+	}
+	if err != nil {
+		recovered.Errors = append(recovered.Errors, err)
+	}
+	return recovered
+}
+
+// containsName reports whether the names include the name
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
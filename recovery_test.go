@@ -0,0 +1,74 @@
+package dialects_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/AdamJonR/dialects"
+)
+
+// settings returns a grammar of key = value lines that recovers from a broken line at its newline
+func settings() grammar {
+	g := newGrammar("root", map[string]dialects.PartDefinition{
+		"root":    {Constituents: [][]string{{"setting*"}}},
+		"setting": {Constituents: [][]string{{"key", "equals", "value", "newline"}}, RecoverAt: []string{"newline"}},
+		"key":     {Regex: `[a-z]+`},
+		"equals":  {Literal: "="},
+		"value":   {Regex: `[0-9]+`},
+		"newline": {Literal: "\n"},
+	})
+	g.dialect.SkipPattern = `[ ]+`
+	return g
+}
+
+func TestRecoverAt(t *testing.T) {
+	root, err, _ := dialects.ParseToTree(settings(), "a = 1\nb = x\nc = 3\nd 4\ne = 5\n")
+	var recovered *dialects.RecoveredError
+	if !errors.As(err, &recovered) || len(recovered.Errors) != 2 {
+		t.Fatalf("expected 2 recovered errors, got %v", err)
+	}
+	// each error is reported with its own line
+	var parseErrors []*dialects.ParseError
+	for _, recoveredErr := range recovered.Errors {
+		var parseError *dialects.ParseError
+		if !errors.As(recoveredErr, &parseError) {
+			t.Fatalf("expected a ParseError, got %v", recoveredErr)
+		}
+		parseErrors = append(parseErrors, parseError)
+	}
+	if parseErrors[0].Line != 2 || parseErrors[0].Column != 5 || parseErrors[0].Message != "expected value" {
+		t.Errorf("expected a value at line 2, column 5, got %v", parseErrors[0])
+	}
+	if parseErrors[1].Line != 4 || parseErrors[1].Column != 3 || parseErrors[1].Message != "expected '='" {
+		t.Errorf("expected '=' at line 4, column 3, got %v", parseErrors[1])
+	}
+	// the tree holds the settings that parsed, with the broken lines in their place
+	var names []string
+	for _, part := range root.Constituents {
+		names = append(names, part.Name+":"+strings.TrimSuffix(part.Value, "\n"))
+	}
+	expected := "setting:a = 1 $error:b = x setting:c = 3 $error:d 4 setting:e = 5"
+	if strings.Join(names, " ") != expected {
+		t.Errorf("expected parts %q, got %q", expected, strings.Join(names, " "))
+	}
+	if broken := root.Constituents[1]; broken.StartPos != 6 || broken.EndPos != 12 || broken.StartLine != 2 {
+		t.Errorf("expected the broken line to span [6:12] on line 2, got %v", broken)
+	}
+}
+
+func TestRecoverAtFailure(t *testing.T) {
+	// a line that doesn't start a setting ends the repetition rather than being recovered from, failing the parse
+	root, err, _ := dialects.ParseToTree(settings(), "a = 1\nb = x\n% = 3\n")
+	var recovered *dialects.RecoveredError
+	if root != nil || !errors.As(err, &recovered) || len(recovered.Errors) != 2 {
+		t.Fatalf("expected the recovered error and the one the parse failed with, got %v", err)
+	}
+	var parseError *dialects.ParseError
+	if !errors.As(recovered.Errors[1], &parseError) || parseError.Line != 3 {
+		t.Errorf("expected the parse to fail on line 3, got %v", recovered.Errors[1])
+	}
+	if _, err, _ := dialects.ParseToTree(settings(), "a = 1\n"); err != nil {
+		t.Errorf("expected no errors without broken lines, got %v", err)
+	}
+}
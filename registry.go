@@ -0,0 +1,127 @@
+package dialects
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Registry provides dialects compiled once and looked up by name and version, which is safe for concurrent use, where
+// the zero value has no dialects registered
+type Registry struct {
+	mutex sync.RWMutex
+	// versions holds the dialects registered for each name, in order of version
+	versions map[string][]*CompiledDialect
+}
+
+// defaultRegistry provides the registry used by the package's Register, Lookup, and ParseNamed functions
+var defaultRegistry = &Registry{}
+
+// Register compiles the dialect and registers it under the name for its Version, replacing any dialect already
+// registered for both, returning the error from Compile if it doesn't compile
+func (registry *Registry) Register(name string, dialectable Dialectable) error {
+	compiled, err := Compile(dialectable)
+	if err != nil {
+		return err
+	}
+	registry.mutex.Lock()
+	if registry.versions == nil {
+		registry.versions = make(map[string][]*CompiledDialect)
+	}
+	// copy the versions, since lookups read them after unlocking
+	versions := registry.versions[name]
+	i := sort.Search(len(versions), func(i int) bool { return versions[i].dialect.Version >= compiled.dialect.Version })
+	updated := append(append([]*CompiledDialect{}, versions[:i]...), compiled)
+	if i < len(versions) && versions[i].dialect.Version == compiled.dialect.Version {
+		i++
+	}
+	registry.versions[name] = append(updated, versions[i:]...)
+	registry.mutex.Unlock()
+	return nil
+}
+
+// Lookup returns the highest version of the dialect registered under the name, with ok reporting whether there was one
+func (registry *Registry) Lookup(name string) (compiled *CompiledDialect, ok bool) {
+	registry.mutex.RLock()
+	versions := registry.versions[name]
+	registry.mutex.RUnlock()
+	if len(versions) == 0 {
+		return nil, false
+	}
+	return versions[len(versions)-1], true
+}
+
+// LookupVersion returns the dialect registered under the name for the version, or else the highest version with the
+// same major version (its whole number part) that's newer, treating those as compatible, with ok reporting whether
+// there was one
+func (registry *Registry) LookupVersion(name string, version float64) (compiled *CompiledDialect, ok bool) {
+	registry.mutex.RLock()
+	versions := registry.versions[name]
+	registry.mutex.RUnlock()
+	for i := len(versions) - 1; i >= 0; i-- {
+		candidate := versions[i].dialect.Version
+		if candidate == version {
+			return versions[i], true
+		}
+		if candidate > version && math.Floor(candidate) == math.Floor(version) && !ok {
+			compiled, ok = versions[i], true
+		}
+	}
+	return compiled, ok
+}
+
+// ParseNamed parses the input with the highest version of the dialect registered under the name, returning an error
+// naming the registered dialects if there's none
+func (registry *Registry) ParseNamed(name string, input string) (string, error, string) {
+	compiled, ok := registry.Lookup(name)
+	if !ok {
+		return "", registry.lookupError(name), ""
+	}
+	return compiled.Parse(input)
+}
+
+// Names returns the names dialects are registered under, in order
+func (registry *Registry) Names() []string {
+	registry.mutex.RLock()
+	names := make([]string, 0, len(registry.versions))
+	for name := range registry.versions {
+		names = append(names, name)
+	}
+	registry.mutex.RUnlock()
+	sort.Strings(names)
+	return names
+}
+
+// lookupError returns the error for finding no dialect registered under the name, listing the names registered
+func (registry *Registry) lookupError(name string) error {
+	names := registry.Names()
+	registered := "none"
+	if len(names) > 0 {
+		registered = strings.Join(names, ", ")
+	}
+	return &DialectError{Message: "no dialect is registered as " + strconv.Quote(name) + " (registered: " + registered + ")"}
+}
+
+// Register compiles the dialect and registers it under the name for its Version in the default registry
+func Register(name string, dialectable Dialectable) error {
+	return defaultRegistry.Register(name, dialectable)
+}
+
+// Lookup returns the highest version of the dialect registered under the name in the default registry
+func Lookup(name string) (compiled *CompiledDialect, ok bool) {
+	return defaultRegistry.Lookup(name)
+}
+
+// LookupVersion returns the dialect registered under the name for the version, or the highest compatible version, in
+// the default registry
+func LookupVersion(name string, version float64) (compiled *CompiledDialect, ok bool) {
+	return defaultRegistry.LookupVersion(name, version)
+}
+
+// ParseNamed parses the input with the highest version of the dialect registered under the name in the default
+// registry
+func ParseNamed(name string, input string) (string, error, string) {
+	return defaultRegistry.ParseNamed(name, input)
+}
@@ -0,0 +1,72 @@
+package dialects_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/AdamJonR/dialects"
+)
+
+// versioned returns the word list grammar with the version
+func versioned(version float64) grammar {
+	g := wordList()
+	g.dialect.Version = version
+	return g
+}
+
+func TestRegistry(t *testing.T) {
+	registry := &dialects.Registry{}
+	var wg sync.WaitGroup
+	for _, version := range []float64{1.0, 1.5, 1.2, 2.0} {
+		wg.Add(1)
+		go func(version float64) {
+			defer wg.Done()
+			if err := registry.Register("words", versioned(version)); err != nil {
+				t.Error(err)
+			}
+		}(version)
+	}
+	wg.Wait()
+	if compiled, ok := registry.Lookup("words"); !ok || compiled.Dialect().Version != 2.0 {
+		t.Errorf("expected the highest version, got %v", compiled)
+	}
+	tests := []struct {
+		version  float64
+		expected float64
+		ok       bool
+	}{
+		{1.2, 1.2, true},
+		{1.1, 1.5, true},
+		{1.6, 0, false},
+		{2.0, 2.0, true},
+		{3.0, 0, false},
+	}
+	for _, test := range tests {
+		compiled, ok := registry.LookupVersion("words", test.version)
+		if ok != test.ok || (ok && compiled.Dialect().Version != test.expected) {
+			t.Errorf("version %v: expected %v (%v), got %v", test.version, test.expected, test.ok, compiled)
+		}
+	}
+	if output, err, _ := registry.ParseNamed("words", "ab cd"); err != nil || output != "item,item" {
+		t.Errorf("expected two items, got %q and %v", output, err)
+	}
+	if err := registry.Register("broken", newGrammar("root", map[string]dialects.PartDefinition{"root": {Regex: `[`}})); err == nil {
+		t.Error("expected the broken dialect to be rejected")
+	}
+	if _, err, _ := registry.ParseNamed("wrods", "ab"); err == nil || !strings.Contains(err.Error(), `no dialect is registered as "wrods" (registered: words)`) {
+		t.Errorf("expected the registered names to be listed, got %v", err)
+	}
+}
+
+func TestDefaultRegistry(t *testing.T) {
+	if err := dialects.Register("default words", wordList()); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := dialects.Lookup("default words"); !ok {
+		t.Error("expected the dialect to be registered")
+	}
+	if output, err, _ := dialects.ParseNamed("default words", "ab"); err != nil || output != "item" {
+		t.Errorf("expected one item, got %q and %v", output, err)
+	}
+}
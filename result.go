@@ -0,0 +1,123 @@
+package dialects
+
+import "context"
+
+// Status reports how a parse ended, telling apart input the grammar couldn't match from input the dialect's callbacks
+// rejected and output that couldn't be generated
+type Status int
+
+const (
+	// StatusSucceeded reports that the input parsed and its output was generated
+	StatusSucceeded Status = iota
+	// StatusFailedSyntax reports that the grammar couldn't match the input, including failures the parse recovered
+	// from, parses that were stopped, as by a limit or a canceled context, and input that isn't UTF-8 or that the
+	// dialect's Preprocessor rejected
+	StatusFailedSyntax
+	// StatusFailedSemantic reports that the grammar matched the input but a callback of the dialect rejected it, as
+	// when a HandlerE returns an error, a deferred Handler returns false, a callback panics, or the version the input
+	// declares isn't compatible
+	StatusFailedSemantic
+	// StatusFailedGenerate reports that the input parsed but generating its output returned an error or panicked
+	StatusFailedGenerate
+	// StatusInvalidDialect reports that the dialect failed to compile, so the input wasn't parsed
+	StatusInvalidDialect
+)
+
+// String returns the name of the status, as in "failed-syntax"
+func (status Status) String() string {
+	switch status {
+	case StatusSucceeded:
+		return "succeeded"
+	case StatusFailedSyntax:
+		return "failed-syntax"
+	case StatusFailedSemantic:
+		return "failed-semantic"
+	case StatusFailedGenerate:
+		return "failed-generate"
+	case StatusInvalidDialect:
+		return "invalid-dialect"
+	}
+	return "unknown"
+}
+
+// Result holds everything a parse produced: the Status it ended with, the Output generated for the input, the Err it
+// failed with, the Diagnostics it reported, the number of bytes of the input it Consumed, and the Log of its trace,
+// which, unlike the log Parse returns, is kept when the parse fails, along with the TraceErr for a trace that couldn't
+// be written to Options.TraceWriter, which doesn't fail the parse, and the Root of the parse tree for a parse that
+// returns one in place of output
+type Result struct {
+	Status      Status
+	Output      string
+	Err         error
+	Diagnostics []Diagnostic
+	Consumed    int
+	Log         string
+	TraceErr    error
+	Root        *Part
+}
+
+// ParseResult parses the input like Parse, returning a Result in place of Parse's output, error, and log
+func ParseResult(dialectable Dialectable, input string) Result {
+	compiled, err := Compile(dialectable)
+	if err != nil {
+		return Result{Status: StatusInvalidDialect, Err: err}
+	}
+	return compiled.ParseResult(input, Options{})
+}
+
+// ParseResult parses the input with the compiled dialect and the options for this parse like ParseWithOptions,
+// returning a Result, whose Diagnostics are also written to Options.Diagnostics if it's set
+func (compiled *CompiledDialect) ParseResult(input string, options Options) Result {
+	if options.Diagnostics == nil {
+		options.Diagnostics = &[]Diagnostic{}
+	}
+	return compiled.parse(context.Background(), input, options, false)
+}
+
+// ParseToTreeResult parses the input like ParseToTree, returning a Result holding the Root of the parse tree in place of
+// ParseToTree's tree, error, and log
+func ParseToTreeResult(dialectable Dialectable, input string) Result {
+	compiled, err := Compile(dialectable)
+	if err != nil {
+		return Result{Status: StatusInvalidDialect, Err: err}
+	}
+	return compiled.ParseToTreeResult(input, Options{})
+}
+
+// ParseToTreeResult parses the input with the compiled dialect and the options for this parse like
+// ParseToTreeWithOptions, returning a Result holding the Root of the parse tree, whose Diagnostics are also written to
+// Options.Diagnostics if it's set
+func (compiled *CompiledDialect) ParseToTreeResult(input string, options Options) Result {
+	if options.Diagnostics == nil {
+		options.Diagnostics = &[]Diagnostic{}
+	}
+	return compiled.parseTreeResult(context.Background(), compiled.dialect.RootName, input, options)
+}
+
+// values returns the output, consumed length, error, and log of the result the way the parse functions that predate
+// Result return them, with no log once the parse itself has failed, and the TraceErr in place of the error of a parse
+// that succeeded
+func (result Result) values() (string, int, error, string) {
+	if result.Status == StatusFailedSyntax || result.Status == StatusFailedSemantic {
+		return "", 0, result.Err, ""
+	}
+	if result.Err == nil {
+		return result.Output, result.Consumed, result.TraceErr, result.Log
+	}
+	return result.Output, result.Consumed, result.Err, result.Log
+}
+
+// tree returns the tree, error, and log of the result the way ParseToTree returns them, with the tree of a parse that
+// recovered from syntax errors kept along with them, but no log once the parse has failed, and no error for a parse
+// that succeeded, leaving the TraceErr to the Result
+func (result Result) tree() (*Part, error, string) {
+	if result.Err != nil {
+		return result.Root, result.Err, ""
+	}
+	return result.Root, nil, result.Log
+}
+
+// failSemantically notes that the parse is failing because a callback of the dialect rejected the input
+func failSemantically(parser Parser) {
+	*parser.status = StatusFailedSemantic
+}
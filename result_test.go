@@ -0,0 +1,67 @@
+package dialects_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/AdamJonR/dialects"
+)
+
+// failingOutput provides a grammar whose output generation returns an error
+type failingOutput struct {
+	grammar
+}
+
+func (failingOutput) GenerateOutput(model interface{}) (string, error) {
+	return "partial", errors.New("no output")
+}
+
+func TestParseResult(t *testing.T) {
+	rejecting := wordList()
+	rejecting.dialect.PartDefinitions["word"] = dialects.PartDefinition{Regex: `[a-z]+`, HandlerE: func(part *dialects.Part, model interface{}) error {
+		if part.Value == "cd" {
+			return errors.New("cd isn't allowed")
+		}
+		return nil
+	}}
+	tests := []struct {
+		name        string
+		grammar     dialects.Dialectable
+		input       string
+		status      dialects.Status
+		output      string
+		consumed    int
+		err         string
+		diagnostics int
+	}{
+		{"succeeded", wordList(), "ab cd", dialects.StatusSucceeded, "item,item", 5, "", 0},
+		{"syntax", wordList(), "ab 12", dialects.StatusFailedSyntax, "", 0, "dialects error: expected word at line 1, column 4 (offset 3)", 1},
+		{"semantic", rejecting, "ab cd", dialects.StatusFailedSemantic, "", 0, "dialects error: cd isn't allowed at line 1, column 4 (offset 3)", 1},
+		{"generate", failingOutput{wordList()}, "ab cd", dialects.StatusFailedGenerate, "partial", 5, "no output", 0},
+		{"invalid dialect", newGrammar("missing", nil), "ab", dialects.StatusInvalidDialect, "", 0, "dialects error: part (missing) is the root part but is not defined", 0},
+	}
+	for _, test := range tests {
+		result := dialects.ParseResult(test.grammar, test.input)
+		if result.Status != test.status || result.Output != test.output || result.Consumed != test.consumed {
+			t.Errorf("%s: expected %v with %q consuming %d, got %v with %q consuming %d", test.name, test.status, test.output, test.consumed, result.Status, result.Output, result.Consumed)
+		}
+		if (result.Err == nil && test.err != "") || (result.Err != nil && !strings.HasPrefix(result.Err.Error(), test.err)) {
+			t.Errorf("%s: expected error %q, got %v", test.name, test.err, result.Err)
+		}
+		if len(result.Diagnostics) != test.diagnostics {
+			t.Errorf("%s: expected %d diagnostics, got %v", test.name, test.diagnostics, result.Diagnostics)
+		}
+		// the trace is kept whether or not the parse failed
+		if test.status != dialects.StatusInvalidDialect && result.Log == "" {
+			t.Errorf("%s: expected the trace", test.name)
+		}
+		// Parse returns the same output and error
+		if output, err, _ := dialects.Parse(test.grammar, test.input); output != result.Output || (err == nil) != (result.Err == nil) {
+			t.Errorf("%s: expected Parse to return %q and %v, got %q and %v", test.name, result.Output, result.Err, output, err)
+		}
+	}
+	if status := dialects.StatusFailedSemantic.String(); status != "failed-semantic" {
+		t.Errorf("expected failed-semantic, got %s", status)
+	}
+}
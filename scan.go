@@ -0,0 +1,91 @@
+package dialects
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Region provides a region of a host text, such as a template, that's delimited by the markers passed to ScanAll,
+// along with the result of parsing its text with the dialect, where the positions of its parts, errors, and
+// diagnostics are those of the host text
+type Region struct {
+	// Offset and Length locate the text between the markers
+	Offset int
+	Length int
+	Output string
+	Root   *Part
+	// Err holds the error the region's parse failed with, or recovered from, in which case Root holds the tree
+	Err         error
+	Diagnostics []Diagnostic
+}
+
+// ScanAll parses each region of the host input between the start and end markers with the dialect, like ScanAll on
+// the compiled dialect, returning an error only if the dialect doesn't compile
+func ScanAll(dialectable Dialectable, input string, start string, end string) ([]Region, error) {
+	compiled, err := Compile(dialectable)
+	if err != nil {
+		return nil, err
+	}
+	return compiled.ScanAll(input, start, end, Options{}), nil
+}
+
+// ScanAll finds each region of the host input between the start and end markers, in order, and parses its text with
+// the compiled dialect and the options, using a fresh model for each, where a region that fails to parse gets its
+// error and diagnostics without stopping the scan, and a region whose end marker is missing runs to the end of the
+// input and fails with an error at its start marker; the Diagnostics set by the options are ignored in favor of
+// those of each region, and there are no regions if either marker is empty
+func (compiled *CompiledDialect) ScanAll(input string, start string, end string, options Options) []Region {
+	if start == "" || end == "" {
+		return nil
+	}
+	host := newPreprocessing(input, nil)
+	var regions []Region
+	for pos := 0; ; {
+		opening := strings.Index(input[pos:], start)
+		if opening < 0 {
+			return regions
+		}
+		offset := pos + opening + len(start)
+		length := strings.Index(input[offset:], end)
+		if length < 0 {
+			return append(regions, compiled.unterminatedRegion(host, offset-len(start), offset, end, options.TabWidth))
+		}
+		regions = append(regions, compiled.scanRegion(host, offset, length, options))
+		pos = offset + length + len(end)
+	}
+}
+
+// scanRegion parses the text of the host input at the offset for the length like ParseWithOptions, keeping the tree
+// along with the output, and moving their positions into the host input
+func (compiled *CompiledDialect) scanRegion(host *preprocessing, offset int, length int, options Options) Region {
+	region := Region{Offset: offset, Length: length}
+	options.Diagnostics = &region.Diagnostics
+	parser, root, err := compiled.run(context.Background(), host.original[offset:offset+length], host, offset, options, nil)
+	region.Root, region.Err = root, err
+	if parser == nil || err != nil {
+		return region
+	}
+	region.Output, region.Err = compiled.generateOutput(parser.model, root, host.original, options)
+	return region
+}
+
+// unterminatedRegion returns the region from the offset of the host input to the end of the input, which fails with
+// an error at the offset of its start marker for the missing end marker, with its visual column for the tab width
+func (compiled *CompiledDialect) unterminatedRegion(host *preprocessing, marker int, offset int, end string, tabWidth int) Region {
+	line := sort.SearchInts(host.lineStarts, marker+1)
+	parseError := &ParseError{
+		Offset:       marker,
+		Line:         line,
+		Column:       marker - host.lineStarts[line-1] + 1,
+		Message:      "unterminated region starting on line " + strconv.Itoa(line) + ", expected " + strconv.Quote(end),
+		VisualColumn: visualColumn(host.original, marker, tabWidth),
+		TabWidth:     tabWidth,
+	}
+	if !compiled.dialect.OmitSnippets {
+		parseError.LineText = lineText(host.original, marker)
+	}
+	diagnostic, _ := errorDiagnostic(parseError)
+	return Region{Offset: offset, Length: len(host.original) - offset, Err: parseError, Diagnostics: []Diagnostic{diagnostic}}
+}
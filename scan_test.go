@@ -0,0 +1,78 @@
+package dialects_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/AdamJonR/dialects"
+)
+
+func TestScanAll(t *testing.T) {
+	host := "<p>{{ab cd}}</p>\n<b>{{ef 12}}</b> {{gh"
+	regions, err := dialects.ScanAll(wordList(), host, "{{", "}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(regions) != 3 {
+		t.Fatalf("expected 3 regions, got %d", len(regions))
+	}
+	first := regions[0]
+	if first.Err != nil || first.Output != "item,item" || host[first.Offset:first.Offset+first.Length] != "ab cd" {
+		t.Errorf("expected the first region to parse, got %+v", first)
+	}
+	// the parts are positioned in the host
+	if item := first.Root.Constituents[1]; item.StartPos != 8 || item.StartLine != 1 || item.StartCol != 9 {
+		t.Errorf("expected the second item at offset 8, line 1, column 9, got %d, %d, %d", item.StartPos, item.StartLine, item.StartCol)
+	}
+	// a region that fails doesn't stop the scan, and its error is positioned in the host
+	var parseError *dialects.ParseError
+	if !errors.As(regions[1].Err, &parseError) || parseError.Offset != strings.Index(host, "12") || parseError.Line != 2 || parseError.Column != 9 || parseError.LineText != "<b>{{ef 12}}</b> {{gh" {
+		t.Errorf("expected an error at the 12 on line 2, got %v", regions[1].Err)
+	}
+	if len(regions[1].Diagnostics) != 1 || regions[1].Diagnostics[0].Line != 2 || regions[1].Diagnostics[0].Offset != parseError.Offset {
+		t.Errorf("expected a diagnostic for the error, got %v", regions[1].Diagnostics)
+	}
+	last := regions[2]
+	if !errors.As(last.Err, &parseError) || parseError.Offset != strings.LastIndex(host, "{{") || host[last.Offset:] != "gh" || last.Length != 2 {
+		t.Errorf("expected the last region to be unterminated, got %+v", last)
+	}
+	if len(last.Diagnostics) != 1 || last.Diagnostics[0].Message != `unterminated region starting on line 2, expected "}}"` {
+		t.Errorf("expected a diagnostic for the unterminated region, got %v", last.Diagnostics)
+	}
+}
+
+func TestScanAllPreprocessed(t *testing.T) {
+	// the region's CRLF is collapsed before parsing, and positions map back through it into the host
+	host := "<p>\r\n{{ab\r\ncd}}"
+	regions, err := dialects.ScanAll(normalized{wordList()}, host, "{{", "}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(regions) != 1 || regions[0].Err != nil {
+		t.Fatalf("expected one region to parse, got %+v", regions)
+	}
+	if item := regions[0].Root.Constituents[1]; item.StartPos != strings.Index(host, "cd") || item.StartLine != 3 || item.StartCol != 1 {
+		t.Errorf("expected the second item at line 3, column 1, got %d, %d, %d", item.StartPos, item.StartLine, item.StartCol)
+	}
+}
+
+func TestScanAllEncoding(t *testing.T) {
+	host := "<p>{{ab}}</p>\n<b>{{cd \xffef}}</b> {{\xff\xfea\x00}}"
+	regions, err := dialects.ScanAll(wordList(), host, "{{", "}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(regions) != 3 || regions[0].Err != nil {
+		t.Fatalf("expected 3 regions, the first parsing, got %+v", regions)
+	}
+	// the invalid byte is positioned in the host rather than the region
+	var encodingError *dialects.EncodingError
+	if !errors.As(regions[1].Err, &encodingError) || encodingError.Offset != strings.Index(host, "\xff") || encodingError.Line != 2 || encodingError.Column != 9 || encodingError.LineText != "<b>{{cd \uFFFDef}}</b> {{\uFFFDa\x00}}" {
+		t.Errorf("expected an EncodingError at line 2, column 9, got %#v", regions[1].Err)
+	}
+	// the byte order mark of another encoding is reported at the start of the region, without the garbled text
+	if !errors.As(regions[2].Err, &encodingError) || encodingError.Encoding != "UTF-16LE" || encodingError.Offset != strings.LastIndex(host, "{{")+2 || encodingError.Line != 2 || encodingError.LineText != "" {
+		t.Errorf("expected a UTF-16LE EncodingError at the start of the region, got %#v", regions[2].Err)
+	}
+}
@@ -0,0 +1,93 @@
+package dialects
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// SourceMapper defines the optional interface for grammars that record which parts produced which text of their
+// output, such as to report errors in generated code against the input, which Parse prefers to TreeAware and
+// GenerateOutput, passing the root Part and input like TreeAware along with the builder that the output is written to
+type SourceMapper interface {
+	GenerateMappedOutput(model interface{}, root *Part, input string, output *OutputBuilder) error
+}
+
+// OutputBuilder builds the output of a SourceMapper, tracking the line and column of the output as it's written so
+// the text written for a part can be mapped back to it
+type OutputBuilder struct {
+	output   strings.Builder
+	line     int
+	column   int
+	mappings []Mapping
+}
+
+// SourceMap provides the spans of the output written for parts, in order of position, as recorded by
+// Options.SourceMap for the latest parse
+type SourceMap struct {
+	Mappings []Mapping
+}
+
+// Mapping provides a span of the output written for a part, where StartPos and EndPos are byte offsets in the output,
+// and StartLine, StartCol, EndLine, and EndCol hold their 1-based lines and rune columns, as for a Part
+type Mapping struct {
+	StartPos  int
+	EndPos    int
+	StartLine int
+	StartCol  int
+	EndLine   int
+	EndCol    int
+	Part      *Part
+}
+
+// newOutputBuilder returns an empty OutputBuilder at the first line and column
+func newOutputBuilder() *OutputBuilder {
+	return &OutputBuilder{line: 1, column: 1}
+}
+
+// Write appends text to the output that doesn't come from any part
+func (builder *OutputBuilder) Write(text string) {
+	builder.output.WriteString(text)
+	builder.line = builder.line + strings.Count(text, "\n")
+	if lastNewline := strings.LastIndexByte(text, '\n'); lastNewline >= 0 {
+		builder.column = utf8.RuneCountInString(text[lastNewline+1:]) + 1
+	} else {
+		builder.column = builder.column + utf8.RuneCountInString(text)
+	}
+}
+
+// WriteMapped appends text to the output that was generated from the part, recording the span it takes up, or
+// appends it like Write if the part is nil or the text is empty
+func (builder *OutputBuilder) WriteMapped(text string, from *Part) {
+	if from == nil || text == "" {
+		builder.Write(text)
+		return
+	}
+	mapping := Mapping{StartPos: builder.output.Len(), StartLine: builder.line, StartCol: builder.column, Part: from}
+	builder.Write(text)
+	mapping.EndPos, mapping.EndLine, mapping.EndCol = builder.output.Len(), builder.line, builder.column
+	builder.mappings = append(builder.mappings, mapping)
+}
+
+// String returns the output written so far
+func (builder *OutputBuilder) String() string {
+	return builder.output.String()
+}
+
+// Find returns the part whose output spans the 1-based line and rune column of the output, or nil if the text there
+// wasn't written for a part
+func (sourceMap *SourceMap) Find(line int, column int) *Part {
+	// find the first span ending after the position, which holds it unless it starts after it
+	i := sort.Search(len(sourceMap.Mappings), func(i int) bool {
+		mapping := sourceMap.Mappings[i]
+		return mapping.EndLine > line || (mapping.EndLine == line && mapping.EndCol > column)
+	})
+	if i == len(sourceMap.Mappings) {
+		return nil
+	}
+	mapping := sourceMap.Mappings[i]
+	if mapping.StartLine > line || (mapping.StartLine == line && mapping.StartCol > column) {
+		return nil
+	}
+	return mapping.Part
+}
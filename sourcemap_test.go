@@ -0,0 +1,47 @@
+package dialects_test
+
+import (
+	"testing"
+
+	"github.com/AdamJonR/dialects"
+)
+
+// emitter provides a grammar that compiles words to calls, one per line after a header, mapping each call to its word
+type emitter struct {
+	grammar
+}
+
+func (emitter) GenerateMappedOutput(model interface{}, root *dialects.Part, input string, output *dialects.OutputBuilder) error {
+	output.Write("// generated\n")
+	for _, word := range root.FindAll("word") {
+		output.WriteMapped("emit("+word.Value+");", word)
+		output.Write("\n")
+	}
+	return nil
+}
+
+func TestSourceMap(t *testing.T) {
+	sourceMap := &dialects.SourceMap{}
+	output, err, _ := dialects.ParseWithOptions(emitter{wordList()}, "ab cd\nef", dialects.Options{SourceMap: sourceMap})
+	if err != nil || output != "// generated\nemit(ab);\nemit(cd);\nemit(ef);\n" {
+		t.Fatalf("expected a call for each word, got %q and %v", output, err)
+	}
+	if len(sourceMap.Mappings) != 3 {
+		t.Fatalf("expected 3 mappings, got %d", len(sourceMap.Mappings))
+	}
+	// output line 3 was written for the second word
+	if part := sourceMap.Find(3, 6); part == nil || part.Value != "cd" || part.StartLine != 1 || part.StartCol != 4 {
+		t.Errorf("expected output line 3 to map to cd at line 1, column 4, got %v", part)
+	}
+	mapping := sourceMap.Mappings[2]
+	if mapping.StartPos != 33 || mapping.StartLine != 4 || mapping.StartCol != 1 || mapping.EndPos != 42 || mapping.EndCol != 10 || mapping.Part.Value != "ef" {
+		t.Errorf("expected ef's call to span [33:42] on line 4, got %+v", mapping)
+	}
+	// the header and line endings weren't written for a part
+	if part := sourceMap.Find(1, 1); part != nil {
+		t.Errorf("expected the header to be unmapped, got %v", part)
+	}
+	if part := sourceMap.Find(3, 10); part != nil {
+		t.Errorf("expected the line ending to be unmapped, got %v", part)
+	}
+}
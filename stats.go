@@ -0,0 +1,100 @@
+package dialects
+
+import (
+	"sync"
+	"time"
+)
+
+// PartStats provides the counts for a part over the parses given a Stats, where Consumed counts the bytes of input
+// its matches consumed, including any text skipped before them, and Backtracks counts the alternatives of its
+// constituent sequences that failed, so the next was tried
+type PartStats struct {
+	Attempts   int
+	Matches    int
+	Failures   int
+	Consumed   int
+	Backtracks int
+	// Time holds the wall time of the attempts, including the time finding their constituents, and MaxTime the
+	// longest of them, which are only measured with Options.Timing
+	Time    time.Duration
+	MaxTime time.Duration
+}
+
+// Stats provides the counts for each part over the parses given it through Options.Stats, which may run concurrently,
+// where the zero value has counted nothing
+type Stats struct {
+	mutex sync.Mutex
+	parts map[string]*PartStats
+}
+
+// part returns the counts for the part, adding them if it has none
+func (stats *Stats) part(partName string) *PartStats {
+	if stats.parts == nil {
+		stats.parts = make(map[string]*PartStats)
+	}
+	partStats, ok := stats.parts[partName]
+	if !ok {
+		partStats = &PartStats{}
+		stats.parts[partName] = partStats
+	}
+	return partStats
+}
+
+// countAttempt counts the attempt to find the part from the start offset, which began at the time if timing and found
+// the parts if any
+func countAttempt(partName string, parts []*Part, start int, began time.Time, parser Parser) {
+	partStats := parser.stats.part(partName)
+	partStats.Attempts++
+	if parser.timed {
+		elapsed := time.Since(began)
+		partStats.Time += elapsed
+		if elapsed > partStats.MaxTime {
+			partStats.MaxTime = elapsed
+		}
+	}
+	if parts == nil {
+		partStats.Failures++
+		return
+	}
+	partStats.Matches++
+	partStats.Consumed += *parser.currentPosPointer - start
+}
+
+// countBacktrack counts an alternative of the part that failed, if counting
+func countBacktrack(partName string, parser Parser) {
+	if parser.stats != nil {
+		parser.stats.part(partName).Backtracks++
+	}
+}
+
+// add adds the counts of a parse to the stats, if there are any
+func (stats *Stats) add(parse *Stats) {
+	if stats == nil || parse == nil {
+		return
+	}
+	stats.mutex.Lock()
+	for name, counts := range parse.parts {
+		partStats := stats.part(name)
+		partStats.Attempts += counts.Attempts
+		partStats.Matches += counts.Matches
+		partStats.Failures += counts.Failures
+		partStats.Consumed += counts.Consumed
+		partStats.Backtracks += counts.Backtracks
+		partStats.Time += counts.Time
+		if counts.MaxTime > partStats.MaxTime {
+			partStats.MaxTime = counts.MaxTime
+		}
+	}
+	stats.mutex.Unlock()
+}
+
+// Parts returns a copy of the counts for each part attempted, by part name
+func (stats *Stats) Parts() map[string]PartStats {
+	stats.mutex.Lock()
+	parts := make(map[string]PartStats, len(stats.parts))
+	for name, partStats := range stats.parts {
+		parts[name] = *partStats
+	}
+	stats.mutex.Unlock()
+	return parts
+}
@@ -0,0 +1,67 @@
+package dialects_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AdamJonR/dialects"
+)
+
+func TestStats(t *testing.T) {
+	stats := &dialects.Stats{}
+	for i := 0; i < 2; i++ {
+		if _, err, _ := dialects.ParseWithOptions(alternatives(), "ab 1", dialects.Options{Stats: stats}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	parts := stats.Parts()
+	// each parse tries an item at ab, at 1, and at the end, where every alternative fails but the number at 1
+	expected := map[string]dialects.PartStats{
+		"root":   {Attempts: 2, Matches: 2, Consumed: 8},
+		"item":   {Attempts: 6, Matches: 4, Failures: 2, Consumed: 8, Backtracks: 14},
+		"number": {Attempts: 6, Matches: 2, Failures: 4, Consumed: 2},
+		"word":   {Attempts: 4, Matches: 2, Failures: 2, Consumed: 4},
+	}
+	for name, counts := range expected {
+		if parts[name] != counts {
+			t.Errorf("expected %s to have %+v, got %+v", name, counts, parts[name])
+		}
+	}
+	if _, ok := parts["unused"]; ok || len(parts) != 7 {
+		t.Errorf("expected stats for the 7 parts attempted, got %v", parts)
+	}
+}
+
+func TestStatsTiming(t *testing.T) {
+	// a slow Match part stands in for an expensive regex
+	g := newGrammar("root", map[string]dialects.PartDefinition{
+		"root": {Constituents: [][]string{{"slow", "word"}}},
+		"slow": {Match: func(input string, pos int) (int, string, bool) {
+			time.Sleep(5 * time.Millisecond)
+			return 0, "", true
+		}},
+		"word": {Regex: `[a-z]+`},
+	})
+	stats := &dialects.Stats{}
+	var elapsed []time.Duration
+	options := dialects.Options{Stats: stats, Timing: true, TraceFunc: func(event dialects.TraceEvent) { elapsed = append(elapsed, event.Elapsed) }}
+	if _, err, _ := dialects.ParseWithOptions(g, "ab", options); err != nil {
+		t.Fatal(err)
+	}
+	parts := stats.Parts()
+	if slow := parts["slow"]; slow.Time < 5*time.Millisecond || slow.MaxTime != slow.Time {
+		t.Errorf("expected the slow part to take at least 5ms, got %+v", slow)
+	}
+	// a part's time includes its constituents'
+	if root := parts["root"]; root.Time < parts["slow"].Time+parts["word"].Time {
+		t.Errorf("expected the root's time to include its constituents', got %+v", root)
+	}
+	if len(elapsed) != 2 || elapsed[1] < 5*time.Millisecond || elapsed[1] < elapsed[0] {
+		t.Errorf("expected the events to be timed from the start of the parse, got %v", elapsed)
+	}
+	// the clock isn't read without Timing
+	untimed := &dialects.Stats{}
+	if _, err, _ := dialects.ParseWithOptions(g, "ab", dialects.Options{Stats: untimed}); err != nil || untimed.Parts()["slow"].Time != 0 {
+		t.Errorf("expected no time without Timing, got %+v", untimed.Parts()["slow"])
+	}
+}
@@ -0,0 +1,102 @@
+package dialects
+
+// stream queues the parts found for the Options.OnPart callbacks until backtracking can no longer discard them
+type stream struct {
+	callbacks map[string]func(p *Part)
+	queued    []*Part
+	// flushed counts the parts already passed to their callbacks, so positions in the queue can be absolute
+	flushed int
+	// choices holds the absolute queue positions at the live choice points (alternatives still to try, optional
+	// parts, and repetitions), where parsing resumes if what follows fails
+	choices []int
+}
+
+// streamLength returns the number of parts streamed so far, counting both flushed and queued parts
+func streamLength(parser Parser) int {
+	if parser.stream == nil {
+		return 0
+	}
+	return parser.stream.flushed + len(parser.stream.queued)
+}
+
+// streamParts queues the parts that have callbacks, flushing them right away if no choice point could discard them
+func streamParts(parts []*Part, parser Parser) {
+	if parser.stream == nil {
+		return
+	}
+	for _, part := range parts {
+		if _, ok := parser.stream.callbacks[part.Name]; ok {
+			parser.stream.queued = append(parser.stream.queued, part)
+		}
+	}
+	flushStream(parser)
+}
+
+// truncateStream drops the parts queued after the absolute position, which backtracking has discarded
+func truncateStream(length int, parser Parser) {
+	if parser.stream == nil {
+		return
+	}
+	// backtracking to before flushed parts only happens on the way to failing the whole parse
+	length = length - parser.stream.flushed
+	if length < 0 {
+		length = 0
+	}
+	for i := length; i < len(parser.stream.queued); i++ {
+		parser.stream.queued[i] = nil
+	}
+	parser.stream.queued = parser.stream.queued[:length]
+}
+
+// beginChoice notes a choice point, holding back the parts queued after it until it ends
+func beginChoice(parser Parser) {
+	if parser.stream != nil {
+		parser.stream.choices = append(parser.stream.choices, streamLength(parser))
+	}
+}
+
+// endChoice ends the latest choice point, flushing any parts it was holding back
+func endChoice(parser Parser) {
+	if parser.stream != nil {
+		parser.stream.choices = parser.stream.choices[:len(parser.stream.choices)-1]
+		flushStream(parser)
+	}
+}
+
+// flushStream passes the queued parts found before every live choice point to their callbacks, releasing them
+func flushStream(parser Parser) {
+	s := parser.stream
+	limit := len(s.queued)
+	if len(s.choices) > 0 && s.choices[0]-s.flushed < limit {
+		limit = s.choices[0] - s.flushed
+	}
+	for i := 0; i < limit; i++ {
+		s.callbacks[s.queued[i].Name](s.queued[i])
+		s.queued[i] = nil
+	}
+	if limit == len(s.queued) {
+		s.queued = s.queued[:0]
+	} else {
+		s.queued = s.queued[limit:]
+	}
+	s.flushed = s.flushed + limit
+}
+
+// dropStreamed returns the parts without those already passed to their callbacks, so repetitions don't hold on to
+// every part streamed
+func dropStreamed(parts []*Part, parser Parser) []*Part {
+	// parts are only known to have been flushed once nothing is queued
+	if parser.stream == nil || len(parser.stream.queued) > 0 {
+		return parts
+	}
+	kept := parts[:0]
+	for _, part := range parts {
+		if _, ok := parser.stream.callbacks[part.Name]; !ok {
+			kept = append(kept, part)
+		}
+	}
+	for i := len(kept); i < len(parts); i++ {
+		parts[i] = nil
+	}
+	return kept
+}
@@ -0,0 +1,111 @@
+package dialects_test
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/AdamJonR/dialects"
+)
+
+// lines returns a grammar of lines of words, where the handler of each line records its number in the events if set
+func lines(events *[]string) grammar {
+	g := newGrammar("root", map[string]dialects.PartDefinition{
+		"root":    {Constituents: [][]string{{"line*"}}},
+		"line":    {Constituents: [][]string{{"word+", "newline"}}},
+		"word":    {Regex: `[a-z]+ ?`},
+		"newline": {Regex: `\n`},
+	})
+	if events != nil {
+		line := g.dialect.PartDefinitions["line"]
+		line.Handler = func(part *dialects.Part, model interface{}) bool {
+			*events = append(*events, "handled "+strconv.Itoa(part.StartLine))
+			return true
+		}
+		g.dialect.PartDefinitions["line"] = line
+	}
+	return g
+}
+
+func TestOnPartStreamsLines(t *testing.T) {
+	var events []string
+	options := dialects.Options{OnPart: map[string]func(p *dialects.Part){
+		"line": func(p *dialects.Part) {
+			events = append(events, "streamed "+strconv.Itoa(p.StartLine)+" "+strconv.Itoa(len(p.Constituents)))
+		},
+	}}
+	root, err, _ := dialects.ParseToTreeWithOptions(lines(&events), "ab cd\nef\ngh ij kl\n", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// each line is streamed, with its constituents, before the next one is parsed
+	expected := "handled 1,streamed 1 3,handled 2,streamed 2 2,handled 3,streamed 3 4"
+	if strings.Join(events, ",") != expected {
+		t.Errorf("expected %q, got %q", expected, strings.Join(events, ","))
+	}
+	// the streamed lines aren't kept in the tree
+	if len(root.Constituents) != 0 || root.Value != "ab cd\nef\ngh ij kl\n" {
+		t.Errorf("expected an empty root spanning the input, got %d constituents and %q", len(root.Constituents), root.Value)
+	}
+}
+
+func TestOnPartOnlyStreamsCommittedParts(t *testing.T) {
+	var streamed []string
+	options := dialects.Options{OnPart: map[string]func(p *dialects.Part){
+		"name": func(p *dialects.Part) {
+			streamed = append(streamed, p.Parent.Name+":"+p.Value)
+		},
+	}}
+	// the first alternative finds the name inside an assignment and then fails on the missing equals
+	if _, err, _ := dialects.ParseWithOptions(ghostEntries(false), "abc:", options); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(streamed, ",") != "decl:abc" {
+		t.Errorf("expected only the name in the committed alternative, got %q", streamed)
+	}
+}
+
+func TestOnPartWithMemoize(t *testing.T) {
+	count := 0
+	options := dialects.Options{Memoize: true, OnPart: map[string]func(p *dialects.Part){
+		"x": func(p *dialects.Part) { count++ },
+	}}
+	// the cached x is found again by each alternative of every expr, but it's only streamed once
+	if _, err, _ := dialects.ParseWithOptions(prefixHeavy(), nested(6), options); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("expected x to be streamed once, got %d", count)
+	}
+}
+
+func TestOnPartMemoryStaysBounded(t *testing.T) {
+	input := strings.Repeat("abc def gh\n", 200000)
+	// sample the live heap early and late in the parse, which would grow with every line kept in the tree
+	var early, late runtime.MemStats
+	count := 0
+	options := dialects.Options{NoTrace: true, OnPart: map[string]func(p *dialects.Part){
+		"line": func(p *dialects.Part) {
+			count++
+			switch count {
+			case 1000:
+				runtime.GC()
+				runtime.ReadMemStats(&early)
+			case 199000:
+				runtime.GC()
+				runtime.ReadMemStats(&late)
+			}
+		},
+	}}
+	g := lines(nil)
+	if _, err, _ := dialects.ParseWithOptions(g, input, options); err != nil {
+		t.Fatal(err)
+	}
+	if count != 200000 {
+		t.Fatalf("expected 200000 lines to be streamed, got %d", count)
+	}
+	if growth := int64(late.HeapAlloc) - int64(early.HeapAlloc); growth > 1<<20 {
+		t.Errorf("expected the heap to stay about the same size while streaming, but it grew by %d bytes", growth)
+	}
+}
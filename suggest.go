@@ -0,0 +1,90 @@
+package dialects
+
+import "unicode/utf8"
+
+const (
+	// maxSuggestionDistance provides the most edits a word can be from a literal or keyword for it to be suggested
+	maxSuggestionDistance = 2
+	// minSuggestionLength provides the fewest runes a literal or keyword must have to be suggested, as short ones
+	// are within a couple of edits of too many words
+	minSuggestionLength = 4
+)
+
+// recordExpectedText notes the text of the literal or keyword part that just failed at the current position, so
+// the word there can be checked for a misspelling of it if that's where the parse fails
+func recordExpectedText(text string, parser Parser) {
+	if parser.lookahead || parser.failure.offset != *parser.currentPosPointer || containsName(parser.failure.texts, text) {
+		return
+	}
+	parser.failure.texts = append(parser.failure.texts, text)
+}
+
+// suggestText returns the expected literal or keyword text closest to the word at the farthest failure, along with
+// the length of the word, or an empty string if none is close enough to be a likely misspelling
+func suggestText(parser Parser) (suggestion string, length int) {
+	if len(parser.failure.texts) == 0 {
+		return "", 0
+	}
+	word := []rune(parser.input[parser.failure.offset : parser.failure.offset+wordLength(parser, parser.failure.offset)])
+	if len(word) == 0 {
+		return "", 0
+	}
+	best := maxSuggestionDistance + 1
+	for _, text := range parser.failure.texts {
+		candidate := []rune(text)
+		if len(candidate) < minSuggestionLength {
+			continue
+		}
+		// the first of the closest texts wins, so the suggestion follows the order of the grammar
+		if distance := editDistance(word, candidate); distance > 0 && distance < best {
+			suggestion, best = text, distance
+		}
+	}
+	if suggestion == "" {
+		return "", 0
+	}
+	return suggestion, len(string(word))
+}
+
+// wordLength returns the length of the run of characters continuing a word, as set by KeywordContinuation, from the
+// offset of the input
+func wordLength(parser Parser, offset int) int {
+	end := offset
+	for end < len(parser.input) && parser.continuation.MatchString(parser.input[end:]) {
+		_, width := utf8.DecodeRuneInString(parser.input[end:])
+		end += width
+	}
+	return end - offset
+}
+
+// editDistance returns the optimal string alignment distance between the words, which counts the insertions,
+// deletions, substitutions, and transpositions of adjacent runes needed to turn one into the other
+func editDistance(a []rune, b []rune) int {
+	// keep the last three rows of the table, as a transposition looks back two rows
+	before, previous, current := make([]int, len(b)+1), make([]int, len(b)+1), make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			// take the cheapest of a deletion, an insertion, or a substitution, or a transposition of the last two
+			current[j] = previous[j-1] + cost
+			if previous[j]+1 < current[j] {
+				current[j] = previous[j] + 1
+			}
+			if current[j-1]+1 < current[j] {
+				current[j] = current[j-1] + 1
+			}
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] && before[j-2]+1 < current[j] {
+				current[j] = before[j-2] + 1
+			}
+		}
+		before, previous, current = previous, current, before
+	}
+	return previous[len(b)]
+}
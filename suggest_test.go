@@ -0,0 +1,61 @@
+package dialects_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/AdamJonR/dialects"
+)
+
+// keywordStatements returns a grammar of statements starting with keywords, described as a statement in errors
+func keywordStatements() grammar {
+	g := newGrammar("root", map[string]dialects.PartDefinition{
+		"root":      {Constituents: [][]string{{"statement*"}}},
+		"statement": {Constituents: [][]string{{"return", "name", "newline"}, {"while", "name", "newline"}, {"if", "name", "newline"}}, Description: "statement"},
+		"return":    {Keyword: "return"},
+		"while":     {Keyword: "while"},
+		"if":        {Keyword: "if"},
+		"name":      {Regex: `[a-z]+`},
+		"newline":   {Literal: "\n"},
+	})
+	g.dialect.SkipPattern = `[ ]+`
+	return g
+}
+
+func TestSuggestion(t *testing.T) {
+	var diagnostics []dialects.Diagnostic
+	_, err, _ := dialects.ParseWithOptions(keywordStatements(), "while x\nretrun x\n", dialects.Options{Diagnostics: &diagnostics})
+	var parseError *dialects.ParseError
+	if !errors.As(err, &parseError) {
+		t.Fatalf("expected a ParseError, got %v", err)
+	}
+	// the keywords of the summarized statement are still checked, with the transposition counting as one edit
+	if parseError.Message != "expected statement, did you mean 'return'?" || parseError.Suggestion != "return" || parseError.Line != 2 {
+		t.Errorf("expected a suggestion of 'return' on line 2, got %q (%q)", parseError.Message, parseError.Suggestion)
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Suggestion != "return" || diagnostics[0].Offset != 8 || diagnostics[0].Length != 6 {
+		t.Errorf("expected the suggestion to replace the misspelled word, got %+v", diagnostics)
+	}
+	for _, test := range []struct {
+		input      string
+		suggestion string
+	}{
+		{"whle x\n", "while"},
+		{"returns x\n", "return"},
+		{"whilst x\n", "while"},
+		// too many edits
+		{"banana x\n", ""},
+		// too short a keyword to suggest
+		{"fi x\n", ""},
+		// no word at the failure
+		{"while x y\n", ""},
+	} {
+		_, err, _ := dialects.Parse(keywordStatements(), test.input)
+		if !errors.As(err, &parseError) {
+			t.Fatalf("expected a ParseError for %q, got %v", test.input, err)
+		}
+		if parseError.Suggestion != test.suggestion {
+			t.Errorf("expected the suggestion %q for %q, got %q", test.suggestion, test.input, parseError.Suggestion)
+		}
+	}
+}
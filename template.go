@@ -0,0 +1,52 @@
+package dialects
+
+import (
+	"sort"
+	"strings"
+)
+
+// PartTemplate provides a part definition whose constituents, RecoverAt parts, and expression operand can refer to
+// parameters, as in "$arg", so similar parts can be defined once and instantiated with the parts that differ
+type PartTemplate struct {
+	name       string
+	parameters []string
+	definition PartDefinition
+}
+
+// DefineTemplate returns the template named for the definition, whose references to "$" followed by one of the
+// parameters are replaced by the arguments passed to Instantiate
+func DefineTemplate(name string, parameters []string, definition PartDefinition) *PartTemplate {
+	return &PartTemplate{name: name, parameters: append([]string{}, parameters...), definition: definition}
+}
+
+// Instantiate returns a copy of the template's definition with its references to the parameters replaced by the
+// names of the parts given as their arguments, as in {"arg": "condition"} for "$arg", where an argument missing or
+// given for a parameter the template doesn't have is reported by ValidateDialect
+func (template *PartTemplate) Instantiate(arguments map[string]string) PartDefinition {
+	var problems []string
+	for _, parameter := range template.parameters {
+		if _, ok := arguments[parameter]; !ok {
+			problems = append(problems, "instantiates template ("+template.name+") without an argument for parameter ("+parameter+")")
+		}
+	}
+	var unknown []string
+	for parameter := range arguments {
+		if !containsName(template.parameters, parameter) {
+			unknown = append(unknown, parameter)
+		}
+	}
+	sort.Strings(unknown)
+	for _, parameter := range unknown {
+		problems = append(problems, "instantiates template ("+template.name+") with an argument for unknown parameter ("+parameter+")")
+	}
+	partDefinition := renameParts(template.definition, func(name string) string {
+		if parameter, ok := strings.CutPrefix(name, "$"); ok && containsName(template.parameters, parameter) {
+			if argument, ok := arguments[parameter]; ok {
+				return argument
+			}
+		}
+		return name
+	})
+	partDefinition.templateProblems = problems
+	return partDefinition
+}
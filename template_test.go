@@ -0,0 +1,57 @@
+package dialects_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/AdamJonR/dialects"
+)
+
+// call provides a template for a keyword followed by an argument in parentheses
+var call = dialects.DefineTemplate("call", []string{"kw", "arg"}, dialects.PartDefinition{
+	Constituents: [][]string{{"$kw", "lparen", "$arg", "rparen"}},
+	Handler:      record,
+})
+
+func TestInstantiate(t *testing.T) {
+	g := newGrammar("statements", map[string]dialects.PartDefinition{
+		"statements": {Constituents: [][]string{{"statement+"}}},
+		"statement":  {Constituents: [][]string{{"ifCall"}, {"printCall"}}},
+		"ifCall":     call.Instantiate(map[string]string{"kw": "if", "arg": "word"}),
+		"printCall":  call.Instantiate(map[string]string{"kw": "print", "arg": "number"}),
+		"if":         {Keyword: "if"},
+		"print":      {Keyword: "print"},
+		"lparen":     {Literal: "("},
+		"rparen":     {Literal: ")"},
+		"word":       {Regex: `[a-z]+`},
+		"number":     {Regex: `[0-9]+`},
+	})
+	output, err, _ := dialects.Parse(g, "if(x)print(12)")
+	if err != nil || output != "ifCall,printCall" {
+		t.Errorf("expected both instances to parse, got %q and %v", output, err)
+	}
+	// an argument for one instance doesn't leak into another
+	if constituents := g.dialect.PartDefinitions["ifCall"].Constituents[0]; strings.Join(constituents, ", ") != "if, lparen, word, rparen" {
+		t.Errorf("expected the parameters to be replaced, got %v", constituents)
+	}
+}
+
+func TestInstantiateArguments(t *testing.T) {
+	g := newGrammar("root", map[string]dialects.PartDefinition{
+		"root":   call.Instantiate(map[string]string{"kw": "word", "args": "word"}),
+		"lparen": {Literal: "("},
+		"rparen": {Literal: ")"},
+		"word":   {Regex: `[a-z]+`},
+	})
+	messages := validationMessages(t, g)
+	expected := []string{
+		`part (root) references undefined part ($arg) in constituent "$arg"`,
+		"part (root) instantiates template (call) without an argument for parameter (arg)",
+		"part (root) instantiates template (call) with an argument for unknown parameter (args)",
+	}
+	for _, message := range expected {
+		if !strings.Contains(strings.Join(messages, "\n"), "dialects error: "+message) {
+			t.Errorf("expected %q, got %q", message, messages)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package dialects
+
+import "context"
+
+// Token provides a leaf part matched by a parse, such as a regex, literal, or keyword part, or skipped text and
+// comments, which are Ignored, as a flat list for syntax highlighting
+type Token struct {
+	PartName string
+	StartPos int
+	EndPos   int
+	Line     int
+	Value    string
+	Ignore   bool
+}
+
+// trail collects the items found by a parse, such as tokens, dropping those that backtracking discards, while keeping
+// the items of the attempt that reached farthest, by the end of its last item, in case the parse fails
+type trail[T any] struct {
+	items []T
+	end   func(item T) int
+	// the farthest items are the first shared items followed by the chunks of rest, saved from last to first as
+	// backtracking drops them, so saving them copies each item at most once
+	shared   int
+	rest     [][]T
+	farthest int
+}
+
+// Tokenize parses the input like Parse, returning the tokens matched, or if the parse fails, the tokens matched by
+// the attempt that reached farthest, so input that's only partly written can still be highlighted up to its error,
+// where there are no tokens if the dialect can't be compiled or the input can't be preprocessed
+func Tokenize(dialectable Dialectable, input string) []Token {
+	compiled, err := Compile(dialectable)
+	if err != nil {
+		return nil
+	}
+	return compiled.Tokenize(input)
+}
+
+// Tokenize parses the input with the compiled dialect like the Tokenize function, using a fresh model
+func (compiled *CompiledDialect) Tokenize(input string) []Token {
+	input, mapping, err := compiled.preprocess(input)
+	if err != nil {
+		return nil
+	}
+	parser := newParser(context.Background(), compiled, input, Options{NoTrace: true})
+	parser.tokens = &trail[Token]{end: func(token Token) int { return token.EndPos }}
+	_, err = parseRoot(parser)
+	tokens := parser.tokens.items
+	if err != nil {
+		tokens = parser.tokens.farthestItems()
+	}
+	mapping.mapTokens(tokens)
+	return tokens
+}
+
+// recordToken adds a token for text about to be consumed at the current position, unless it's within a lookahead
+func recordToken(partName string, ignore bool, match string, parser Parser) {
+	if parser.tokens == nil || parser.lookahead || match == "" {
+		return
+	}
+	pos := *parser.currentPosPointer
+	parser.tokens.items = append(parser.tokens.items, Token{PartName: partName, StartPos: pos, EndPos: pos + len(match), Line: currentLine(parser), Value: match, Ignore: ignore})
+}
+
+// length returns the number of items collected so far, or zero for a nil trail
+func (t *trail[T]) length() int {
+	if t == nil {
+		return 0
+	}
+	return len(t.items)
+}
+
+// truncate drops the items collected after the length, which backtracking has discarded, first keeping them among
+// the farthest items if they reach farther than any before
+func (t *trail[T]) truncate(length int) {
+	if t == nil || length >= len(t.items) {
+		return
+	}
+	t.saveFarthest()
+	if length < t.shared {
+		t.rest = append(t.rest, append([]T{}, t.items[length:t.shared]...))
+		t.shared = length
+	}
+	t.items = t.items[:length]
+}
+
+// saveFarthest makes the current items the farthest if they reach farther than the farthest so far
+func (t *trail[T]) saveFarthest() {
+	if len(t.items) == 0 || t.end(t.items[len(t.items)-1]) <= t.farthest {
+		return
+	}
+	t.shared = len(t.items)
+	t.rest = nil
+	t.farthest = t.end(t.items[len(t.items)-1])
+}
+
+// farthestItems returns the items of the attempt that reached farthest
+func (t *trail[T]) farthestItems() []T {
+	t.saveFarthest()
+	items := append([]T{}, t.items[:t.shared]...)
+	for i := len(t.rest) - 1; i >= 0; i-- {
+		items = append(items, t.rest[i]...)
+	}
+	return items
+}
+
+// replay adds the items recorded by a memoized attempt, unless within a lookahead
+func (t *trail[T]) replay(items []T, parser Parser) {
+	if t != nil && !parser.lookahead {
+		t.items = append(t.items, items...)
+	}
+}
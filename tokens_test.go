@@ -0,0 +1,55 @@
+package dialects_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/AdamJonR/dialects"
+)
+
+// tokenNames returns the part names and values of the tokens that aren't Ignored
+func tokenNames(tokens []dialects.Token) []string {
+	var names []string
+	for _, token := range tokens {
+		if !token.Ignore {
+			names = append(names, token.PartName+":"+token.Value)
+		}
+	}
+	return names
+}
+
+func TestTokenize(t *testing.T) {
+	tokens := dialects.Tokenize(statements(), "# totals\nlet a = 1 + 2;\nlet b = 3;")
+	expected := []string{"let:let", "name:a", "equals:=", "number:1", "$operator:+", "number:2", "semicolon:;", "let:let", "name:b", "equals:=", "number:3", "semicolon:;"}
+	if names := tokenNames(tokens); strings.Join(names, " ") != strings.Join(expected, " ") {
+		t.Errorf("expected tokens %v, got %v", expected, names)
+	}
+	// the first alternative's token is discarded when it fails, leaving the second alternative's
+	alternatives := newGrammar("root", map[string]dialects.PartDefinition{
+		"root": {Constituents: [][]string{{"a", "b"}, {"a", "c"}}},
+		"a":    {Literal: "a"},
+		"b":    {Literal: "b"},
+		"c":    {Literal: "c"},
+	})
+	if names := tokenNames(dialects.Tokenize(alternatives, "ac")); strings.Join(names, " ") != "a:a c:c" {
+		t.Errorf("expected the tokens of the second alternative, got %v", names)
+	}
+	// the comment is an Ignored token, with its position
+	if comment := tokens[0]; comment.PartName != dialects.CommentPartName || comment.StartPos != 0 || comment.EndPos != 8 || !comment.Ignore {
+		t.Errorf("expected the comment first, got %+v", comment)
+	}
+	last := tokens[len(tokens)-1]
+	if last.StartPos != 33 || last.EndPos != 34 || last.Line != 3 {
+		t.Errorf("expected the last semicolon at [33:34] on line 3, got %+v", last)
+	}
+}
+
+func TestTokenizeFailure(t *testing.T) {
+	// the second statement is half written, but its tokens before the error are kept, while the alternatives of the
+	// value that were abandoned aren't
+	tokens := dialects.Tokenize(statements(), "let a = 1 + 2;\nlet b = ;")
+	expected := []string{"let:let", "name:a", "equals:=", "number:1", "$operator:+", "number:2", "semicolon:;", "let:let", "name:b", "equals:="}
+	if names := tokenNames(tokens); strings.Join(names, " ") != strings.Join(expected, " ") {
+		t.Errorf("expected tokens %v, got %v", expected, names)
+	}
+}
@@ -0,0 +1,234 @@
+package dialects
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TraceKind identifies what happened in a TraceEvent
+type TraceKind int
+
+const (
+	// TraceSequence reports the start of an attempt to match a constituent sequence of a part
+	TraceSequence TraceKind = iota
+	// TraceMatch reports that the whole constituent sequence matched
+	TraceMatch
+	// TraceMiss reports that a required constituent of the sequence wasn't found, so the sequence failed
+	TraceMiss
+	// TraceInvalid reports that a regex part matched but its ValidateMatch rejected the match
+	TraceInvalid
+	// TraceAmbiguity reports that a later alternative of a part would have matched the same text as the one that
+	// matched, with Options.ReportAmbiguity
+	TraceAmbiguity
+	// TraceWarning reports that a regex part matched but its ValidateMatchSeverity warned about the match
+	TraceWarning
+)
+
+// String returns the name of the kind of event
+func (kind TraceKind) String() string {
+	switch kind {
+	case TraceSequence:
+		return "sequence"
+	case TraceMatch:
+		return "match"
+	case TraceMiss:
+		return "miss"
+	case TraceInvalid:
+		return "invalid"
+	case TraceAmbiguity:
+		return "ambiguity"
+	case TraceWarning:
+		return "warning"
+	}
+	return "TraceKind(" + strconv.Itoa(int(kind)) + ")"
+}
+
+// TraceEvent provides the details of one step of a parse for tools that display or analyze parse attempts
+type TraceEvent struct {
+	Kind TraceKind
+	// PartName holds the part whose constituent sequence was attempted, or the regex part that was invalid
+	PartName string
+	// Sequence holds the constituent sequence attempted, or the later alternative that also matched for
+	// TraceAmbiguity, and is nil for TraceInvalid and TraceWarning
+	Sequence []string
+	// Constituent holds the missing constituent, with its modifier, for TraceMiss
+	Constituent string
+	// Message holds the message from ValidateMatch for TraceInvalid, if any, or TraceWarning, for TraceMiss, how many
+	// of a bounded repetition were expected and found, or for TraceAmbiguity, which alternatives both match
+	Message string
+	// StartPos holds the offset where the sequence or invalid part started
+	StartPos int
+	// EndPos holds the offset where both alternatives end for TraceAmbiguity
+	EndPos int
+	// Line holds the line of input being parsed when the event happened
+	Line int
+	// Depth holds the number of traced constituent sequences enclosing the event
+	Depth int
+	// Elapsed holds the wall time since the parse started, which is only measured with Options.Timing
+	Elapsed time.Duration
+}
+
+// tracer sends the trace events of a parse to its consumers, keeping track of how deeply sequences are nested
+type tracer struct {
+	consumers []func(TraceEvent)
+	filter    func(partName string) bool
+	depth     int
+}
+
+// tracing returns whether anything consumes the trace events of the part, so events needn't be built otherwise
+func tracing(partName string, parser Parser) bool {
+	return len(parser.tracer.consumers) > 0 && (parser.tracer.filter == nil || parser.tracer.filter(partName))
+}
+
+// trace sends the event to the consumers after filling in the current line and depth
+func trace(event TraceEvent, parser Parser) {
+	event.Line = currentLine(parser)
+	event.Depth = parser.tracer.depth
+	if parser.timed {
+		event.Elapsed = time.Since(parser.started)
+	}
+	for _, consumer := range parser.tracer.consumers {
+		consumer(event)
+	}
+}
+
+// Log provides the human-readable trace of a parse, written as indented lines built from the trace events
+type Log struct {
+	buffer   *bytes.Buffer
+	writer   io.Writer
+	writeErr error
+	// unit holds the text indenting each level of depth, and indent holds it repeated for the deepest line so far
+	unit     string
+	indent   string
+	maxDepth int
+	// size counts the bytes written, up to maxSize, and omitted those of the lines left out after it was reached
+	size    int
+	maxSize int
+	omitted int
+}
+
+// event writes the line of the log for the trace event
+func (log *Log) event(event TraceEvent) {
+	switch event.Kind {
+	case TraceSequence:
+		log.line(event.Depth, strings.Join(event.Sequence, ", "))
+	case TraceMatch:
+		log.line(event.Depth, "found")
+	case TraceMiss:
+		message := "missing " + event.Constituent
+		if event.Message != "" {
+			message = event.Message
+		}
+		log.line(event.Depth, message+" on line "+strconv.Itoa(event.Line))
+	case TraceInvalid:
+		message := "invalid " + event.PartName + " starting on line " + strconv.Itoa(event.Line)
+		if event.Message != "" {
+			// log custom error message
+			message = message + ": " + event.Message
+		}
+		log.line(event.Depth, message)
+	case TraceWarning:
+		log.line(event.Depth, "warning for "+event.PartName+" starting on line "+strconv.Itoa(event.Line)+": "+event.Message)
+	case TraceAmbiguity:
+		log.line(event.Depth, "part "+event.PartName+": "+event.Message+" [line "+strconv.Itoa(event.Line)+", "+strconv.Itoa(event.EndPos-event.StartPos)+" chars]")
+	}
+}
+
+// StableTraceVersion provides the version of the trace format written with Options.StableTrace, which is given on
+// the trace's first line and only changes when the format does
+const StableTraceVersion = 1
+
+// stableHeader returns the first line of the stable trace format, which gives its version
+func stableHeader() string {
+	return "# dialects trace v" + strconv.Itoa(StableTraceVersion) + "\n"
+}
+
+// stableEvent writes the line of the stable trace format for the event, which holds its depth, kind, part name,
+// start position, and line, separated by tabs
+func (log *Log) stableEvent(event TraceEvent) {
+	log.write(strconv.Itoa(event.Depth) + "\t" + event.Kind.String() + "\t" + event.PartName + "\t" + strconv.Itoa(event.StartPos) + "\t" + strconv.Itoa(event.Line) + "\n")
+}
+
+// DefaultTraceIndent provides the text the trace log is indented by for each level of depth when
+// Options.TraceIndent is empty
+const DefaultTraceIndent = "  "
+
+// line writes the message to the log indented for the depth, growing the indent as needed so any depth is safe, and
+// marking the depth of a line deeper than the maximum indented instead
+func (log *Log) line(depth int, message string) {
+	if log.maxDepth > 0 && depth > log.maxDepth {
+		message = "\u2026 depth " + strconv.Itoa(depth) + ": " + message
+		depth = log.maxDepth
+	}
+	width := depth * len(log.unit)
+	if len(log.indent) < width {
+		// double the indent so it's only regenerated a few times however deep the parse goes
+		log.indent = strings.Repeat(log.unit, 2*depth)
+	}
+	log.write(log.indent[:width] + message + "\n")
+}
+
+// DefaultMaxTraceSize provides the limit on the bytes of the trace log used when Options.MaxTraceSize is zero
+const DefaultMaxTraceSize = 4 << 20
+
+// write writes the line to the log unless the log has reached its maximum size, noting where the log was truncated
+// and counting the bytes of the lines left out after that
+func (log *Log) write(line string) {
+	if log.maxSize > 0 && (log.omitted > 0 || log.size+len(line) > log.maxSize) {
+		if log.omitted == 0 {
+			log.emit("\u2026 trace truncated after " + strconv.Itoa(log.size) + " bytes\n")
+		}
+		log.omitted += len(line)
+		return
+	}
+	log.size += len(line)
+	log.emit(line)
+}
+
+// finish ends the log, noting how many bytes were left out if it was truncated
+func (log *Log) finish() {
+	if log.writer != nil && log.omitted > 0 {
+		log.emit("\u2026 " + strconv.Itoa(log.omitted) + " bytes of trace omitted\n")
+	}
+}
+
+// emit writes the text to the log, giving up on the trace after the first write error so it can be reported once at
+// the end of the parse
+func (log *Log) emit(line string) {
+	if log.writeErr != nil {
+		return
+	}
+	if _, err := io.WriteString(log.writer, line); err != nil {
+		log.writeErr = err
+	}
+}
+
+// err returns a TraceError for the first write error, if any
+func (log *Log) err() error {
+	if log.writeErr == nil {
+		return nil
+	}
+	return &TraceError{Err: log.writeErr}
+}
+
+// String returns the trace written during the parse, or an empty string if tracing was off or streamed to a writer
+func (log *Log) String() string {
+	if log.buffer == nil {
+		return ""
+	}
+	return log.buffer.String()
+}
+
+// TraceParts returns a TraceFilter that traces only the named parts
+func TraceParts(partNames ...string) func(partName string) bool {
+	traced := make(map[string]bool, len(partNames))
+	for _, partName := range partNames {
+		traced[partName] = true
+	}
+	return func(partName string) bool {
+		return traced[partName]
+	}
+}
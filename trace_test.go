@@ -0,0 +1,222 @@
+package dialects_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/AdamJonR/dialects"
+)
+
+// traceEvents returns the events of parsing the input, formatted as kind:part:constituent@pos/line/depth
+func traceEvents(t *testing.T, g grammar, input string, options dialects.Options) []string {
+	t.Helper()
+	var events []string
+	options.TraceFunc = func(event dialects.TraceEvent) {
+		events = append(events, event.Kind.String()+":"+event.PartName+":"+event.Constituent+"@"+strconv.Itoa(event.StartPos)+"/"+strconv.Itoa(event.Line)+"/"+strconv.Itoa(event.Depth))
+	}
+	if _, err, _ := dialects.ParseWithOptions(g, input, options); err != nil {
+		t.Fatal(err)
+	}
+	return events
+}
+
+func TestTraceFunc(t *testing.T) {
+	events := traceEvents(t, alternatives(), "ab 1", dialects.Options{})
+	expected := []string{
+		"sequence:root:@0/1/0",
+		// the word is found after the number, string and symbol alternatives miss
+		"sequence:item:@0/1/1", "miss:item:number@0/1/1",
+		"sequence:item:@0/1/1", "miss:item:string@0/1/1",
+		"sequence:item:@0/1/1", "miss:item:symbol@0/1/1",
+		"sequence:item:@0/1/1", "match:item:@0/1/1",
+		"sequence:item:@3/1/1", "match:item:@3/1/1",
+		// the repetition ends when every alternative misses at the end of the input
+		"sequence:item:@4/1/1", "miss:item:number@4/1/1",
+		"sequence:item:@4/1/1", "miss:item:string@4/1/1",
+		"sequence:item:@4/1/1", "miss:item:symbol@4/1/1",
+		"sequence:item:@4/1/1", "miss:item:word@4/1/1",
+		"match:root:@0/1/0",
+	}
+	if strings.Join(events, " ") != strings.Join(expected, " ") {
+		t.Errorf("expected events\n%q\ngot\n%q", expected, events)
+	}
+}
+
+func TestTraceFuncInvalid(t *testing.T) {
+	g := newGrammar("root", map[string]dialects.PartDefinition{
+		"root": {Constituents: [][]string{{"byte"}, {"word"}}},
+		"byte": {Regex: `[0-9]+`, ValidateMatch: func(matches []string) (bool, string) {
+			n, _ := strconv.Atoi(matches[0])
+			return n < 256, "out of range"
+		}},
+		"word": {Regex: `[0-9a-z]+`},
+	})
+	var invalid []dialects.TraceEvent
+	options := dialects.Options{NoTrace: true, TraceFunc: func(event dialects.TraceEvent) {
+		if event.Kind == dialects.TraceInvalid {
+			invalid = append(invalid, event)
+		}
+	}}
+	// events are still sent with the log turned off
+	if _, err, _ := dialects.ParseWithOptions(g, "300", options); err != nil {
+		t.Fatal(err)
+	}
+	if len(invalid) != 1 || invalid[0].PartName != "byte" || invalid[0].Message != "out of range" || invalid[0].Depth != 1 {
+		t.Errorf("expected one invalid byte event, got %+v", invalid)
+	}
+}
+
+func TestTraceLogMatchesEvents(t *testing.T) {
+	_, _, log := dialects.Parse(alternatives(), "ab 1")
+	lines := strings.Split(strings.TrimSuffix(log, "\n"), "\n")
+	events := traceEvents(t, alternatives(), "ab 1", dialects.Options{})
+	if len(lines) != len(events) {
+		t.Fatalf("expected a log line for each of the %d events, got %d lines", len(events), len(lines))
+	}
+	if lines[0] != "item+" || lines[1] != "  number, ws?" || lines[2] != "  missing number on line 1" || lines[len(lines)-1] != "found" {
+		t.Errorf("unexpected log %q", log)
+	}
+}
+
+// arithmetic returns a grammar of sums of numbers and parenthesized expressions, with an example
+func arithmetic() grammar {
+	g := newGrammar("expression", map[string]dialects.PartDefinition{
+		"expression": {Constituents: [][]string{{"term", "sum*"}}},
+		"sum":        {Constituents: [][]string{{"ws?", "plus", "ws?", "term"}}},
+		"term":       {Constituents: [][]string{{"number"}, {"group"}}},
+		"group":      {Constituents: [][]string{{"open", "expression", "close"}}},
+		"number":     {Regex: `[0-9]+`},
+		"plus":       {Regex: `\+`},
+		"open":       {Regex: `\(`},
+		"close":      {Regex: `\)`},
+		"ws":         {Regex: `[ ]+`, Ignore: true},
+	})
+	g.dialect.Examples = map[string]string{"nested": "1 + (2 + 3)"}
+	return g
+}
+
+func TestTraceFilter(t *testing.T) {
+	g := arithmetic()
+	options := dialects.Options{TraceFilter: dialects.TraceParts("expression")}
+	_, _, log := dialects.ParseWithOptions(g, g.dialect.Examples["nested"], options)
+	// the nested expression is indented one level under the outer one, with the levels between left out
+	expected := "term, sum*\n  term, sum*\n  found\nfound\n"
+	if log != expected {
+		t.Errorf("expected log %q, got %q", expected, log)
+	}
+	for _, event := range traceEvents(t, g, g.dialect.Examples["nested"], options) {
+		if !strings.Contains(event, ":expression:") {
+			t.Errorf("unexpected event %s", event)
+		}
+	}
+}
+
+func TestTraceLogIndentsDeepNesting(t *testing.T) {
+	// the indent has to grow several times
+	_, err, log := dialects.Parse(parens(), strings.Repeat("(", 200)+"x"+strings.Repeat(")", 200))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(log, "\n")
+	for depth := 0; depth <= 200; depth++ {
+		// the first alternative of each expr is attempted a level deeper than the last
+		expected := strings.Repeat("  ", depth) + "open, expr, close"
+		if lines[depth] != expected {
+			t.Fatalf("expected line %d to be %q, got %q", depth, expected, lines[depth])
+		}
+	}
+	// the grown indent is shared by the whole parse, so the lines on the way back out are indented too
+	if expected := strings.Repeat("  ", 199) + "found"; !strings.Contains(log, "\n"+expected+"\n") {
+		t.Errorf("expected the log to contain %q", expected)
+	}
+}
+
+func TestTraceIndent(t *testing.T) {
+	_, err, log := dialects.ParseWithOptions(parens(), "((x))", dialects.Options{TraceIndent: "| ", TraceMaxDepth: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// lines nested deeper than the maximum are indented to it and marked with their depth
+	lines := strings.Split(log, "\n")
+	expected := []string{"open, expr, close", "| open, expr, close", "| \u2026 depth 2: open, expr, close"}
+	if strings.Join(lines[:3], "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected the log to start with %q, got %q", expected, lines[:3])
+	}
+}
+
+func TestMaxTraceSize(t *testing.T) {
+	expectedOutput, _, full := dialects.ParseWithOptions(parens(), "((x))", dialects.Options{MaxTraceSize: -1})
+	output, err, log := dialects.ParseWithOptions(parens(), "((x))", dialects.Options{MaxTraceSize: 40})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output != expectedOutput {
+		t.Errorf("expected the output %q to be unaffected by the limit, got %q", expectedOutput, output)
+	}
+	// the lines that fit are kept, followed by a note of where the log was truncated and how much was left out
+	lines := strings.Split(full, "\n")
+	kept, size := "", 0
+	for _, line := range lines {
+		if size+len(line)+1 > 40 {
+			break
+		}
+		kept += line + "\n"
+		size += len(line) + 1
+	}
+	expected := kept + "\u2026 trace truncated after " + strconv.Itoa(size) + " bytes\n\u2026 " + strconv.Itoa(len(full)-size) + " bytes of trace omitted\n"
+	if log != expected {
+		t.Errorf("expected the log %q, got %q", expected, log)
+	}
+}
+
+func TestReportAmbiguity(t *testing.T) {
+	g := newGrammar("values", map[string]dialects.PartDefinition{
+		"values": {Constituents: [][]string{{"value+"}}},
+		"value":  {Constituents: [][]string{{"number", "ws?"}, {"word", "ws?"}, {"digits", "ws?"}}},
+		"number": {Regex: `[0-9]+`, Handler: record},
+		"word":   {Regex: `[a-z]+`, Handler: record},
+		"digits": {Regex: `[0-9]+`, Handler: record},
+		"ws":     {Regex: `[ \n]+`, Ignore: true},
+	})
+	var ambiguities []dialects.TraceEvent
+	options := dialects.Options{ReportAmbiguity: true, TraceFunc: func(event dialects.TraceEvent) {
+		if event.Kind == dialects.TraceAmbiguity {
+			ambiguities = append(ambiguities, event)
+		}
+	}}
+	output, err, log := dialects.ParseWithOptions(g, "ab\n12 ", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ambiguities) != 1 || ambiguities[0].PartName != "value" || ambiguities[0].StartPos != 3 || ambiguities[0].EndPos != 6 || strings.Join(ambiguities[0].Sequence, ", ") != "digits, ws?" {
+		t.Errorf("expected one ambiguity between the number and the digits, got %+v", ambiguities)
+	}
+	if !strings.Contains(log, "part value: alternatives 1 and 3 both match [line 2, 3 chars]\n") {
+		t.Errorf("expected the ambiguity to be logged, got %q", log)
+	}
+	// the digits alternative is only tried for the check, so its handler never runs, and its attempt isn't traced
+	// (leaving just the one where the repetition ends)
+	if output != "word,number" || strings.Count(log, "digits, ws?") != 1 {
+		t.Errorf("expected the check to leave no trace but its report, got output %q and log %q", output, log)
+	}
+	// without the option, nothing is reported
+	ambiguities = nil
+	options.ReportAmbiguity = false
+	if _, err, _ := dialects.ParseWithOptions(g, "ab\n12 ", options); err != nil || len(ambiguities) != 0 {
+		t.Errorf("expected no ambiguity reported, got %v and %+v", err, ambiguities)
+	}
+}
+
+func TestStableTrace(t *testing.T) {
+	_, err, log := dialects.ParseWithOptions(wordList(), "ab\ncd", dialects.Options{StableTrace: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "stable_trace.txt", []byte(log))
+	// the stable format is written by TraceWriter too
+	var written strings.Builder
+	if _, err, _ := dialects.ParseWithOptions(wordList(), "ab\ncd", dialects.Options{StableTrace: true, TraceWriter: &written}); err != nil || written.String() != log {
+		t.Errorf("expected the written trace to match the log, got %q", written.String())
+	}
+}
@@ -0,0 +1,124 @@
+package dialects
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Walk calls visit for the part and each of its descendants in depth-first order, along with their depth beneath the
+// part, skipping the descendants of any part for which visit returns false
+func Walk(root *Part, visit func(p *Part, depth int) bool) {
+	walk(root, 0, visit)
+}
+
+// walk visits the part at the depth and then its constituents
+func walk(part *Part, depth int, visit func(p *Part, depth int) bool) {
+	if part == nil || !visit(part, depth) {
+		return
+	}
+	for _, constituent := range part.Constituents {
+		walk(constituent, depth+1, visit)
+	}
+}
+
+// FindAll returns the descendants of the part with the name, in depth-first order
+func (part *Part) FindAll(name string) []*Part {
+	var found []*Part
+	for _, constituent := range part.constituents() {
+		Walk(constituent, func(p *Part, depth int) bool {
+			if p.Name == name {
+				found = append(found, p)
+			}
+			return true
+		})
+	}
+	return found
+}
+
+// First returns the first descendant of the part with the name in depth-first order, or nil if there isn't one
+func (part *Part) First(name string) *Part {
+	var first *Part
+	for _, constituent := range part.constituents() {
+		Walk(constituent, func(p *Part, depth int) bool {
+			if first == nil && p.Name == name {
+				first = p
+			}
+			return first == nil
+		})
+		if first != nil {
+			break
+		}
+	}
+	return first
+}
+
+// constituents returns the constituents of the part, or nil for a nil part
+func (part *Part) constituents() []*Part {
+	if part == nil {
+		return nil
+	}
+	return part.Constituents
+}
+
+// DefaultMaxValueLength provides the number of runes of a leaf's Value shown by DumpTree when
+// DumpOptions.MaxValueLength is zero
+const DefaultMaxValueLength = 40
+
+// DumpOptions provides the settings for DumpTreeWithOptions, where the zero value gives the defaults used by DumpTree
+type DumpOptions struct {
+	// MaxValueLength limits the runes of a leaf's Value that are shown, using DefaultMaxValueLength when zero and no
+	// limit when negative
+	MaxValueLength int
+	// SkipIgnored leaves Ignored parts and their descendants out rather than marking them as ignored
+	SkipIgnored bool
+}
+
+// DumpTree returns the part and its descendants as an indented tree, one part per line, for debugging grammars and
+// golden tests
+func DumpTree(p *Part) string {
+	return DumpTreeWithOptions(p, DumpOptions{})
+}
+
+// DumpTreeWithOptions returns the part and its descendants as an indented tree like DumpTree, using the options
+func DumpTreeWithOptions(p *Part, options DumpOptions) string {
+	maxValueLength := options.MaxValueLength
+	if maxValueLength == 0 {
+		maxValueLength = DefaultMaxValueLength
+	}
+	var dump strings.Builder
+	Walk(p, func(p *Part, depth int) bool {
+		if p.Ignore && options.SkipIgnored {
+			return false
+		}
+		// write the name and span, followed by the escaped value of leaves
+		dump.WriteString(strings.Repeat("  ", depth) + p.Name + " [" + strconv.Itoa(p.StartPos) + ":" + strconv.Itoa(p.EndPos) + "]")
+		if len(p.Constituents) == 0 {
+			dump.WriteString(" " + quoteValue(p.Value, maxValueLength))
+		}
+		if p.Ignore {
+			dump.WriteString(" (ignored)")
+		}
+		dump.WriteString("\n")
+		return true
+	})
+	return dump.String()
+}
+
+// String returns the part and its descendants as an indented tree, as returned by DumpTree
+func (part *Part) String() string {
+	return DumpTree(part)
+}
+
+// quoteValue returns the value quoted and escaped, truncated to the number of runes unless it's negative
+func quoteValue(value string, maxLength int) string {
+	if maxLength < 0 {
+		return strconv.Quote(value)
+	}
+	for i := range value {
+		if maxLength == 0 {
+			return strconv.Quote(value[:i]) + "..."
+		}
+		maxLength--
+	}
+	return strconv.Quote(value)
+}
@@ -0,0 +1,97 @@
+package dialects_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/AdamJonR/dialects"
+)
+
+// declarations returns a grammar of function and variable declarations
+func declarations() grammar {
+	return newGrammar("program", map[string]dialects.PartDefinition{
+		"program":      {Constituents: [][]string{{"decl+"}}},
+		"decl":         {Constituents: [][]string{{"functionDecl"}, {"varDecl"}}},
+		"functionDecl": {Constituents: [][]string{{"funcKeyword", "identifier", "open", "varDecl*", "close"}}},
+		"varDecl":      {Constituents: [][]string{{"varKeyword", "identifier", "semi"}}},
+		"funcKeyword":  {Regex: `func `},
+		"varKeyword":   {Regex: `var `},
+		"identifier":   {Regex: `[a-z]+`},
+		"semi":         {Regex: `;`},
+		"open":         {Regex: `\{`},
+		"close":        {Regex: `\}`},
+	})
+}
+
+// values returns the Values of the parts
+func values(parts []*dialects.Part) string {
+	var values []string
+	for _, part := range parts {
+		values = append(values, part.Value)
+	}
+	return strings.Join(values, ",")
+}
+
+func TestWalk(t *testing.T) {
+	root, err, _ := dialects.ParseToTree(declarations(), "var a;func f{var b;}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var visited []string
+	dialects.Walk(root, func(p *dialects.Part, depth int) bool {
+		visited = append(visited, strings.Repeat(" ", depth)+p.Name)
+		// prune the function's body
+		return p.Name != "functionDecl"
+	})
+	expected := "program, decl,  varDecl,   varKeyword,   identifier,   semi, decl,  functionDecl"
+	if strings.Join(visited, ",") != expected {
+		t.Errorf("expected %q, got %q", expected, strings.Join(visited, ","))
+	}
+	// walking a nil tree visits nothing
+	dialects.Walk(nil, func(p *dialects.Part, depth int) bool {
+		t.Error("unexpected visit")
+		return true
+	})
+}
+
+func TestFindAllAndFirst(t *testing.T) {
+	root, err, _ := dialects.ParseToTree(declarations(), "var a;func f{var b;var c;}func g{}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found := values(root.FindAll("identifier")); found != "a,f,b,c,g" {
+		t.Errorf("expected identifiers a,f,b,c,g, got %s", found)
+	}
+	function := root.First("functionDecl")
+	if function == nil || function.First("identifier").Value != "f" {
+		t.Fatalf("expected the function f, got %v", function)
+	}
+	if found := values(function.FindAll("identifier")); found != "f,b,c" {
+		t.Errorf("expected the function's identifiers f,b,c, got %s", found)
+	}
+	// parts don't find themselves, and missing parts, nil parts and leaves find nothing
+	var none *dialects.Part
+	if function.First("functionDecl") != nil || root.First("missing") != nil || none.First("decl") != nil ||
+		len(none.FindAll("decl")) != 0 || len(function.First("identifier").FindAll("identifier")) != 0 {
+		t.Error("expected nothing to be found")
+	}
+}
+
+func TestDumpTree(t *testing.T) {
+	g := newGrammar("root", map[string]dialects.PartDefinition{
+		"root":    {Constituents: [][]string{{"item+"}}},
+		"item":    {Constituents: [][]string{{"word", "ws?"}, {"comment", "ws?"}}},
+		"word":    {Regex: `[a-z]+`},
+		"comment": {Regex: `#[^\n]*`},
+		"ws":      {Regex: `[ \n]+`, Ignore: true},
+	})
+	root, err, _ := dialects.ParseToTreeWithOptions(g, "ab #\t\"quoted\" and a very long comment that goes on\ncd", dialects.Options{KeepIgnored: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "dump.txt", []byte(dialects.DumpTree(root)))
+	golden(t, "dump_skip_ignored.txt", []byte(dialects.DumpTreeWithOptions(root, dialects.DumpOptions{MaxValueLength: 8, SkipIgnored: true})))
+	if root.String() != dialects.DumpTree(root) || dialects.DumpTree(nil) != "" {
+		t.Error("expected String to match DumpTree and a nil tree to dump nothing")
+	}
+}
@@ -0,0 +1,118 @@
+package dialects
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// DialectableT defines the interface for DSL grammars whose model has the type T, so handlers and GenerateOutput
+// receive the model without type assertions
+type DialectableT[T any] interface {
+	NewDialect() *Dialect
+	NewModel() T
+	GenerateOutput(model T) (string, error)
+}
+
+// untyped provides the Dialectable for a DialectableT
+type untyped[T any] struct {
+	typed DialectableT[T]
+}
+
+// Untyped returns the Dialectable for the typed grammar, for use with Compile and the other functions taking one
+func Untyped[T any](dialectable DialectableT[T]) Dialectable {
+	return untyped[T]{typed: dialectable}
+}
+
+func (dialectable untyped[T]) NewDialect() *Dialect {
+	return dialectable.typed.NewDialect()
+}
+
+func (dialectable untyped[T]) NewModel() interface{} {
+	return dialectable.typed.NewModel()
+}
+
+func (dialectable untyped[T]) GenerateOutput(model interface{}) (string, error) {
+	return dialectable.typed.GenerateOutput(typedModel[T](model))
+}
+
+// GenerateMappedOutput writes the output to the builder if the typed grammar has a GenerateMappedOutput method taking
+// a T, or else writes the output it generates otherwise
+func (dialectable untyped[T]) GenerateMappedOutput(model interface{}, root *Part, input string, output *OutputBuilder) error {
+	if sourceMapper, ok := dialectable.typed.(interface {
+		GenerateMappedOutput(model T, root *Part, input string, output *OutputBuilder) error
+	}); ok {
+		return sourceMapper.GenerateMappedOutput(typedModel[T](model), root, input, output)
+	}
+	text, err := dialectable.GenerateOutputWithTree(model, root, input)
+	output.Write(text)
+	return err
+}
+
+// GenerateOutputWithTree generates the output with the tree if the typed grammar has a GenerateOutputWithTree method
+// taking a T, or else generates it from the model alone
+func (dialectable untyped[T]) GenerateOutputWithTree(model interface{}, root *Part, input string) (string, error) {
+	if treeAware, ok := dialectable.typed.(interface {
+		GenerateOutputWithTree(model T, root *Part, input string) (string, error)
+	}); ok {
+		return treeAware.GenerateOutputWithTree(typedModel[T](model), root, input)
+	}
+	return dialectable.typed.GenerateOutput(typedModel[T](model))
+}
+
+// Preprocess preprocesses the input if the typed grammar is a Preprocessor, or else returns it unchanged
+func (dialectable untyped[T]) Preprocess(input string) (string, *OffsetMap, error) {
+	if preprocessor, ok := dialectable.typed.(Preprocessor); ok {
+		return preprocessor.Preprocess(input)
+	}
+	return input, nil, nil
+}
+
+// typedModel returns the model as a T, or the zero value of T for a nil model, panicking if the model has another
+// type, so a typed callback used with another dialect's model is reported as the callback panicking rather than
+// handed the zero value
+func typedModel[T any](model interface{}) T {
+	typed, ok := model.(T)
+	if !ok && model != nil {
+		panic(fmt.Sprintf("dialects: expected a model of type %s, got %T", reflect.TypeOf((*T)(nil)).Elem(), model))
+	}
+	return typed
+}
+
+// ParseT parses the input like Parse for a typed grammar
+func ParseT[T any](dialectable DialectableT[T], input string) (string, error, string) {
+	return Parse(Untyped(dialectable), input)
+}
+
+// ParseWithOptionsT parses the input like ParseWithOptions for a typed grammar
+func ParseWithOptionsT[T any](dialectable DialectableT[T], input string, options Options) (string, error, string) {
+	return ParseWithOptions(Untyped(dialectable), input, options)
+}
+
+// HandlerT returns a Handler for PartDefinition that passes the model to the typed handler as a T
+func HandlerT[T any](handler func(part *Part, model T) (ok bool)) func(*Part, interface{}) (ok bool) {
+	return func(part *Part, model interface{}) bool {
+		return handler(part, typedModel[T](model))
+	}
+}
+
+// HandlerET returns a HandlerE for PartDefinition that passes the model to the typed handler as a T
+func HandlerET[T any](handler func(part *Part, model T) error) func(*Part, interface{}) error {
+	return func(part *Part, model interface{}) error {
+		return handler(part, typedModel[T](model))
+	}
+}
+
+// ValidateMatchCtxT returns a ValidateMatchCtx for PartDefinition that passes the model to the typed validator as a T
+func ValidateMatchCtxT[T any](validate func(matches []string, pos int, line int, model T) (bool, string)) func(matches []string, pos int, line int, model interface{}) (bool, string) {
+	return func(matches []string, pos int, line int, model interface{}) (bool, string) {
+		return validate(matches, pos, line, typedModel[T](model))
+	}
+}
+
+// ValidateMatchSeverityT returns a ValidateMatchSeverity for PartDefinition that passes the model to the typed
+// validator as a T
+func ValidateMatchSeverityT[T any](validate func(matches []string, pos int, line int, model T) (Severity, string)) func(matches []string, pos int, line int, model interface{}) (Severity, string) {
+	return func(matches []string, pos int, line int, model interface{}) (Severity, string) {
+		return validate(matches, pos, line, typedModel[T](model))
+	}
+}
@@ -0,0 +1,77 @@
+package dialects_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/AdamJonR/dialects"
+)
+
+// inventory provides the typed model of the stock grammar
+type inventory struct {
+	items []string
+	seen  map[string]bool
+}
+
+// stock provides a typed grammar of unique item names, with no type assertions
+type stock struct{}
+
+func (stock) NewDialect() *dialects.Dialect {
+	return &dialects.Dialect{
+		Title:    "stock",
+		RootName: "items",
+		PartDefinitions: map[string]dialects.PartDefinition{
+			"items": {Constituents: [][]string{{"item+"}}, Handler: dialects.HandlerT(func(part *dialects.Part, model *inventory) bool {
+				return len(model.seen) > 0
+			})},
+			"item": {Regex: `[a-z]+`, ValidateMatchCtx: dialects.ValidateMatchCtxT(func(matches []string, pos int, line int, model *inventory) (bool, string) {
+				return !model.seen[matches[0]], "duplicate item"
+			}), HandlerE: dialects.HandlerET(func(part *dialects.Part, model *inventory) error {
+				if part.Value == "none" {
+					return errors.New("none isn't an item")
+				}
+				model.seen[part.Value] = true
+				return nil
+			})},
+		},
+		SkipPattern: `[ ]+`,
+	}
+}
+
+func (stock) NewModel() *inventory {
+	return &inventory{seen: make(map[string]bool)}
+}
+
+func (stock) GenerateOutput(model *inventory) (string, error) {
+	for name := range model.seen {
+		model.items = append(model.items, name)
+	}
+	return strings.Join(model.items, ","), nil
+}
+
+func TestParseT(t *testing.T) {
+	output, err, _ := dialects.ParseT[*inventory](stock{}, "nuts")
+	if err != nil || output != "nuts" {
+		t.Errorf("expected nuts, got %q and %v", output, err)
+	}
+	if _, err, _ := dialects.ParseWithOptionsT[*inventory](stock{}, "nuts nuts", dialects.Options{NoTrace: true}); err == nil || !strings.Contains(err.Error(), "duplicate item") {
+		t.Errorf("expected the duplicate to be rejected, got %v", err)
+	}
+	compiled, err := dialects.Compile(dialects.Untyped[*inventory](stock{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err, _ := compiled.Parse("nuts none"); err == nil || !strings.Contains(err.Error(), "none isn't an item") {
+		t.Errorf("expected the handler's error, got %v", err)
+	}
+	// a typed handler given the model of another dialect reports the types rather than receiving a nil model
+	g := newGrammar("word", map[string]dialects.PartDefinition{
+		"word": {Regex: `[a-z]+`, Handler: dialects.HandlerT(func(part *dialects.Part, model *inventory) bool {
+			return !model.seen[part.Value]
+		})},
+	})
+	if _, err, _ := dialects.Parse(g, "nuts"); err == nil || !strings.Contains(err.Error(), "Handler panicked: dialects: expected a model of type *dialects_test.inventory, got *[]string") {
+		t.Errorf("expected the model's type to be reported, got %v", err)
+	}
+}
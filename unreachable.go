@@ -0,0 +1,53 @@
+package dialects
+
+// UnreachablePart provides the Name and Description of a part that no parse of the dialect can reach
+type UnreachablePart struct {
+	Name        string
+	Description string
+}
+
+// UnreachableParts returns the parts, in order of name, that can't be reached from the root part or an Exported part
+// through constituents (including lookaheads and separators), expression operands, and RecoverAt parts, which are
+// usually left over from earlier versions of the grammar or only unreachable because a reference to them is misspelled
+func UnreachableParts(d *Dialect) []UnreachablePart {
+	reachable := make(map[string]bool)
+	var pending []string
+	visit := func(name string) {
+		if _, ok := d.PartDefinitions[name]; ok && !reachable[name] {
+			reachable[name] = true
+			pending = append(pending, name)
+		}
+	}
+	visit(d.RootName)
+	for _, name := range sortedPartNames(d) {
+		if d.PartDefinitions[name].Exported {
+			visit(name)
+		}
+	}
+	for len(pending) > 0 {
+		partDefinition := d.PartDefinitions[pending[0]]
+		pending = pending[1:]
+		for _, constituentSeq := range partDefinition.Constituents {
+			for _, constituentID := range constituentSeq {
+				reference := parseConstituent(constituentID)
+				if reference.modifier != "^" {
+					visit(reference.name)
+				}
+				visit(reference.separator)
+			}
+		}
+		if partDefinition.expression != nil {
+			visit(partDefinition.expression.operand)
+		}
+		for _, recoverAt := range partDefinition.RecoverAt {
+			visit(recoverAt)
+		}
+	}
+	var unreachable []UnreachablePart
+	for _, name := range sortedPartNames(d) {
+		if !reachable[name] {
+			unreachable = append(unreachable, UnreachablePart{Name: name, Description: d.PartDefinitions[name].Description})
+		}
+	}
+	return unreachable
+}
@@ -0,0 +1,33 @@
+package dialects_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/AdamJonR/dialects"
+)
+
+func TestUnreachableParts(t *testing.T) {
+	d := &dialects.Dialect{RootName: "statements", PartDefinitions: map[string]dialects.PartDefinition{
+		"statements": {Constituents: [][]string{{"statement%semi*"}}},
+		"statement":  {Constituents: [][]string{{"!reserved", "name", "^", "equals", "expr"}}, RecoverAt: []string{"newline"}},
+		"expr":       dialects.ExpressionPart("operand", []dialects.OpLevel{{Operators: []string{"+"}}}),
+		"operand":    {Regex: `[0-9]+`},
+		"reserved":   {Keyword: "if"},
+		"name":       {Regex: `[a-z]+`},
+		"equals":     {Literal: "="},
+		"semi":       {Literal: ";"},
+		"newline":    {Literal: "\n"},
+		"value":      {Constituents: [][]string{{"quoted"}}, Exported: true},
+		"quoted":     {Regex: `"[^"]*"`},
+		"comment":    {Regex: `#.*`, Description: "comment"},
+		"nmae":       {Regex: `[A-Z]+`, Description: "misspelled name"},
+	}}
+	expected := []dialects.UnreachablePart{{Name: "comment", Description: "comment"}, {Name: "nmae", Description: "misspelled name"}}
+	if unreachable := dialects.UnreachableParts(d); !reflect.DeepEqual(unreachable, expected) {
+		t.Errorf("expected %v, got %v", expected, unreachable)
+	}
+	if unreachable := dialects.UnreachableParts(wordList().NewDialect()); len(unreachable) != 0 {
+		t.Errorf("expected no unreachable parts, got %v", unreachable)
+	}
+}
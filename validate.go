@@ -0,0 +1,297 @@
+package dialects
+
+import (
+	"regexp"
+	"regexp/syntax"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ValidateDialect checks the grammar of the dialect before any parsing happens, returning an error for each problem
+// found: a missing root part, constituents, expression operands, or RecoverAt parts that reference undefined parts (or
+// parts not earlier in the sequence for backreferences), invalid repetition bounds, empty constituent sequences and
+// operators, parts that don't define exactly one of Constituents, a Regex, a Literal, a Keyword, EOF, a Match, or an
+// ExpressionPart, parts that set both CaseInsensitive and CaseSensitive, invalid regexes (including the dialect's own),
+// a BlockComment without both its start and end, a VersionPart that isn't defined, arguments missing from or unknown
+// to the template a part was instantiated from, namespaced part names, as in "query.expression", with an empty
+// namespace or name, and left recursion that no part of its cycle allows
+func ValidateDialect(d *Dialect) []error {
+	var errs []error
+	if _, err := regexp.Compile(keywordContinuation(d)); err != nil {
+		errs = append(errs, &DialectError{Message: "KeywordContinuation is an invalid regex", Err: err})
+	}
+	if _, err := regexp.Compile(d.SkipPattern); err != nil {
+		errs = append(errs, &DialectError{Message: "SkipPattern is an invalid regex", Err: err})
+	}
+	if (d.BlockComment[0] == "") != (d.BlockComment[1] == "") {
+		errs = append(errs, &DialectError{Message: "BlockComment needs both the text that starts a comment and the text that ends it"})
+	}
+	if _, ok := d.PartDefinitions[d.RootName]; !ok {
+		errs = append(errs, &DialectError{PartName: d.RootName, Message: "is the root part but is not defined"})
+	}
+	if _, ok := d.PartDefinitions[d.VersionPart]; d.VersionPart != "" && !ok {
+		errs = append(errs, &DialectError{PartName: d.VersionPart, Message: "is the VersionPart but is not defined"})
+	}
+	for _, name := range sortedPartNames(d) {
+		partDefinition := d.PartDefinitions[name]
+		if strings.Contains("."+name+".", "..") && strings.Contains(name, ".") {
+			errs = append(errs, &DialectError{PartName: name, Message: "has an empty namespace or name around a dot"})
+		}
+		// check the part is defined one way or another
+		switch count := definedBy(partDefinition); {
+		case count < 1:
+			errs = append(errs, &DialectError{PartName: name, Message: "defines none of Constituents, a Regex, a Literal, a Keyword, EOF, a Match, or an ExpressionPart"})
+		case count > 1:
+			errs = append(errs, &DialectError{PartName: name, Message: "defines more than one of Constituents, a Regex, a Literal, a Keyword, EOF, a Match, and an ExpressionPart"})
+		}
+		for _, problem := range partDefinition.templateProblems {
+			errs = append(errs, &DialectError{PartName: name, Message: problem})
+		}
+		if partDefinition.CaseInsensitive && partDefinition.CaseSensitive {
+			errs = append(errs, &DialectError{PartName: name, Message: "sets both CaseInsensitive and CaseSensitive"})
+		}
+		if partDefinition.Regex != "" {
+			if _, err := regexp.Compile(partDefinition.Regex); err != nil {
+				errs = append(errs, &DialectError{PartName: name, Message: "has an invalid regex", Err: err})
+			}
+		}
+		if expression := partDefinition.expression; expression != nil {
+			if _, ok := d.PartDefinitions[expression.operand]; !ok {
+				errs = append(errs, &DialectError{PartName: name, Message: "references undefined part (" + expression.operand + ") as the operand of its expression"})
+			}
+			for _, level := range expression.levels {
+				for _, operator := range level.Operators {
+					if operator == "" {
+						errs = append(errs, &DialectError{PartName: name, Message: "has an empty operator in its expression"})
+					}
+				}
+			}
+		}
+		for _, recoverAt := range partDefinition.RecoverAt {
+			if _, ok := d.PartDefinitions[recoverAt]; !ok {
+				errs = append(errs, &DialectError{PartName: name, Message: "references undefined part (" + recoverAt + ") in RecoverAt"})
+			}
+		}
+		// check every constituent sequence references defined parts
+		for i, constituentSeq := range partDefinition.Constituents {
+			if len(constituentSeq) < 1 {
+				errs = append(errs, &DialectError{PartName: name, Message: "has an empty constituent sequence (alternative " + strconv.Itoa(i+1) + ")"})
+			}
+			seen := make(map[string]bool)
+			for _, constituentID := range constituentSeq {
+				reference := parseConstituent(constituentID)
+				if reference.modifier == "=" && !seen[reference.name] {
+					errs = append(errs, &DialectError{PartName: name, Message: "references part (" + reference.name + ") in constituent " + strconv.Quote(constituentID) + " before it appears in the sequence"})
+				}
+				seen[reference.name] = true
+				if reference.modifier == "{}" && (reference.max == 0 || (reference.max > 0 && reference.max < reference.min)) {
+					errs = append(errs, &DialectError{PartName: name, Message: "has an invalid repetition bound in constituent " + strconv.Quote(constituentID)})
+				}
+				if _, ok := d.PartDefinitions[reference.name]; !ok && reference.modifier != "^" {
+					errs = append(errs, &DialectError{PartName: name, Message: "references undefined part (" + reference.name + ") in constituent " + strconv.Quote(constituentID)})
+				}
+				if _, ok := d.PartDefinitions[reference.separator]; reference.separator != "" && !ok {
+					errs = append(errs, &DialectError{PartName: name, Message: "references undefined part (" + reference.separator + ") in constituent " + strconv.Quote(constituentID)})
+				}
+			}
+		}
+	}
+	return append(errs, leftRecursionErrors(d)...)
+}
+
+// definedBy returns how many of Constituents, a Regex, a Literal, a Keyword, EOF, a Match, and an ExpressionPart the
+// part defines, which should be exactly one
+func definedBy(partDefinition PartDefinition) int {
+	count := 0
+	for _, defined := range []bool{len(partDefinition.Constituents) > 0, partDefinition.Regex != "", partDefinition.Literal != "", partDefinition.Keyword != "", partDefinition.EOF, partDefinition.Match != nil, partDefinition.expression != nil} {
+		if defined {
+			count++
+		}
+	}
+	return count
+}
+
+// nullableParts returns the set of parts that can match without consuming any input
+func nullableParts(d *Dialect) map[string]bool {
+	nullable := make(map[string]bool)
+	for name, partDefinition := range d.PartDefinitions {
+		if partDefinition.EOF {
+			nullable[name] = true
+		}
+		if partDefinition.Regex != "" {
+			if parsedRegex, err := syntax.Parse(partDefinition.Regex, syntax.Perl); err == nil && regexNullable(parsedRegex) {
+				nullable[name] = true
+			}
+		}
+	}
+	// keep marking composite parts until nothing changes
+	for changed := true; changed; {
+		changed = false
+		for name, partDefinition := range d.PartDefinitions {
+			if nullable[name] {
+				continue
+			}
+			// an expression can be a lone operand
+			if partDefinition.expression != nil && nullable[partDefinition.expression.operand] {
+				nullable[name] = true
+				changed = true
+			}
+			for _, constituentSeq := range partDefinition.Constituents {
+				if sequenceNullable(constituentSeq, nullable) {
+					nullable[name] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	return nullable
+}
+
+// regexNullable returns whether the regex can match without consuming any input at some position, which includes
+// zero-width assertions like \b or $ that don't match an empty input
+func regexNullable(parsedRegex *syntax.Regexp) bool {
+	switch parsedRegex.Op {
+	case syntax.OpEmptyMatch, syntax.OpBeginLine, syntax.OpEndLine, syntax.OpBeginText, syntax.OpEndText,
+		syntax.OpWordBoundary, syntax.OpNoWordBoundary, syntax.OpStar, syntax.OpQuest:
+		return true
+	case syntax.OpLiteral:
+		return len(parsedRegex.Rune) == 0
+	case syntax.OpCapture, syntax.OpPlus:
+		return regexNullable(parsedRegex.Sub[0])
+	case syntax.OpRepeat:
+		return parsedRegex.Min == 0 || regexNullable(parsedRegex.Sub[0])
+	case syntax.OpConcat:
+		for _, sub := range parsedRegex.Sub {
+			if !regexNullable(sub) {
+				return false
+			}
+		}
+		return true
+	case syntax.OpAlternate:
+		for _, sub := range parsedRegex.Sub {
+			if regexNullable(sub) {
+				return true
+			}
+		}
+	}
+	// character classes, any character and no match always consume input or fail
+	return false
+}
+
+// sequenceNullable returns whether every constituent of the sequence can match without consuming any input
+func sequenceNullable(constituentSeq []string, nullable map[string]bool) bool {
+	for _, constituentID := range constituentSeq {
+		if !constituentNullable(parseConstituent(constituentID), nullable) {
+			return false
+		}
+	}
+	return true
+}
+
+// constituentNullable returns whether the constituent can match without consuming any input, which lookaheads and cuts
+// never do
+func constituentNullable(reference constituent, nullable map[string]bool) bool {
+	switch reference.modifier {
+	case "?", "*", "!", "&", "^":
+		return true
+	case "{}":
+		return reference.min == 0 || nullable[reference.name]
+	}
+	return nullable[reference.name]
+}
+
+// leftRecursionErrors returns an error for each cycle of parts that can reach themselves without consuming input,
+// which would otherwise recurse until the stack overflows
+func leftRecursionErrors(d *Dialect) []error {
+	nullable := nullableParts(d)
+	// find the parts each part may attempt before consuming any input
+	leftEdges := make(map[string][]string)
+	for _, name := range sortedPartNames(d) {
+		// an expression starts with its operand
+		if expression := d.PartDefinitions[name].expression; expression != nil {
+			if _, ok := d.PartDefinitions[expression.operand]; ok {
+				leftEdges[name] = append(leftEdges[name], expression.operand)
+			}
+		}
+		for _, constituentSeq := range d.PartDefinitions[name].Constituents {
+			for _, constituentID := range constituentSeq {
+				reference := parseConstituent(constituentID)
+				if _, ok := d.PartDefinitions[reference.name]; ok {
+					leftEdges[name] = append(leftEdges[name], reference.name)
+				}
+				if !constituentNullable(reference, nullable) {
+					break
+				}
+			}
+		}
+	}
+	var errs []error
+	reported := make(map[string]bool)
+	visited := make(map[string]bool)
+	var stack []string
+	onStack := make(map[string]int)
+	var visit func(name string)
+	visit = func(name string) {
+		onStack[name] = len(stack)
+		stack = append(stack, name)
+		for _, next := range leftEdges[name] {
+			if start, ok := onStack[next]; ok {
+				// report each cycle once, starting from its alphabetically first part, unless one of its parts
+				// allows left recursion
+				cycle := rotateCycle(stack[start:])
+				key := strings.Join(cycle, " -> ")
+				if !reported[key] && !allowsLeftRecursion(d, cycle) {
+					reported[key] = true
+					errs = append(errs, &DialectError{PartName: cycle[0], Message: "has left recursion: " + key + " -> " + cycle[0]})
+				}
+				continue
+			}
+			if !visited[next] {
+				visit(next)
+			}
+		}
+		stack = stack[:len(stack)-1]
+		delete(onStack, name)
+		visited[name] = true
+	}
+	for _, name := range sortedPartNames(d) {
+		if !visited[name] {
+			visit(name)
+		}
+	}
+	return errs
+}
+
+// allowsLeftRecursion returns whether any part of the cycle allows left recursion, which ends the cycle by growing
+// the part from a seed
+func allowsLeftRecursion(d *Dialect, cycle []string) bool {
+	for _, name := range cycle {
+		if d.PartDefinitions[name].AllowLeftRecursion {
+			return true
+		}
+	}
+	return false
+}
+
+// rotateCycle returns a copy of the cycle of part names starting with its alphabetically first name
+func rotateCycle(cycle []string) []string {
+	first := 0
+	for i, name := range cycle {
+		if name < cycle[first] {
+			first = i
+		}
+	}
+	return append(append([]string{}, cycle[first:]...), cycle[:first]...)
+}
+
+// sortedPartNames returns the names of the dialect's parts in alphabetical order so results are deterministic
+func sortedPartNames(d *Dialect) []string {
+	names := make([]string, 0, len(d.PartDefinitions))
+	for name := range d.PartDefinitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,206 @@
+package dialects_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/AdamJonR/dialects"
+)
+
+// validationMessages returns the messages of the errors found validating the grammar
+func validationMessages(t *testing.T, g grammar) []string {
+	t.Helper()
+	var messages []string
+	for _, err := range dialects.ValidateDialect(g.NewDialect()) {
+		var dialectErr *dialects.DialectError
+		if !errors.As(err, &dialectErr) {
+			t.Fatalf("expected a DialectError, got %v", err)
+		}
+		messages = append(messages, err.Error())
+	}
+	return messages
+}
+
+func TestValidateDialect(t *testing.T) {
+	tests := []struct {
+		name     string
+		grammar  grammar
+		expected []string
+	}{
+		{"valid", wordList(), nil},
+		{"missing root", newGrammar("program", map[string]dialects.PartDefinition{
+			"word": {Regex: `[a-z]+`},
+		}), []string{"part (program) is the root part but is not defined"}},
+		{"undefined reference", newGrammar("root", map[string]dialects.PartDefinition{
+			"root": {Constituents: [][]string{{"wrod+"}}},
+			"word": {Regex: `[a-z]+`},
+		}), []string{`part (root) references undefined part (wrod) in constituent "wrod+"`}},
+		{"both constituents and regex", newGrammar("root", map[string]dialects.PartDefinition{
+			"root": {Constituents: [][]string{{"word"}}, Regex: `[a-z]+`},
+			"word": {Regex: `[a-z]+`},
+		}), []string{"part (root) defines more than one of Constituents, a Regex, a Literal, a Keyword, EOF, a Match, and an ExpressionPart"}},
+		{"both regex and literal", newGrammar("root", map[string]dialects.PartDefinition{
+			"root":  {Constituents: [][]string{{"arrow"}}},
+			"arrow": {Regex: `->`, Literal: "->"},
+		}), []string{"part (arrow) defines more than one of Constituents, a Regex, a Literal, a Keyword, EOF, a Match, and an ExpressionPart"}},
+		{"neither constituents nor regex", newGrammar("root", map[string]dialects.PartDefinition{
+			"root": {Constituents: [][]string{{"word?"}}},
+			"word": {},
+		}), []string{"part (word) defines none of Constituents, a Regex, a Literal, a Keyword, EOF, a Match, or an ExpressionPart"}},
+		{"undefined expression operand", newGrammar("expr", map[string]dialects.PartDefinition{
+			"expr": dialects.ExpressionPart("number", []dialects.OpLevel{{Operators: []string{"+", ""}}}),
+		}), []string{"part (expr) references undefined part (number) as the operand of its expression", "part (expr) has an empty operator in its expression"}},
+		{"undefined recovery part", newGrammar("root", map[string]dialects.PartDefinition{
+			"root": {Constituents: [][]string{{"word+"}}, RecoverAt: []string{"newline"}},
+			"word": {Regex: `[a-z]+`},
+		}), []string{"part (root) references undefined part (newline) in RecoverAt"}},
+		{"empty namespace", newGrammar("root", map[string]dialects.PartDefinition{
+			"root":        {Constituents: [][]string{{".word", "query..word?"}}},
+			".word":       {Regex: `[a-z]+`},
+			"query..word": {Regex: `[0-9]+`},
+		}), []string{"part (.word) has an empty namespace or name around a dot", "part (query..word) has an empty namespace or name around a dot"}},
+		{"both case insensitive and case sensitive", newGrammar("word", map[string]dialects.PartDefinition{
+			"word": {Regex: `[a-z]+`, CaseInsensitive: true, CaseSensitive: true},
+		}), []string{"part (word) sets both CaseInsensitive and CaseSensitive"}},
+		{"invalid repetition bound", newGrammar("root", map[string]dialects.PartDefinition{
+			"root": {Constituents: [][]string{{"word{3,1}", "word{0}"}}},
+			"word": {Regex: `[a-z]+`},
+		}), []string{`part (root) has an invalid repetition bound in constituent "word{3,1}"`, `part (root) has an invalid repetition bound in constituent "word{0}"`}},
+		{"malformed repetition bound", newGrammar("root", map[string]dialects.PartDefinition{
+			"root": {Constituents: [][]string{{"word{a}"}}},
+			"word": {Regex: `[a-z]+`},
+		}), []string{`part (root) references undefined part (word{a}) in constituent "word{a}"`}},
+		{"backreference before its part", newGrammar("root", map[string]dialects.PartDefinition{
+			"root": {Constituents: [][]string{{"=word", "word"}}},
+			"word": {Regex: `[a-z]+`},
+		}), []string{`part (root) references part (word) in constituent "=word" before it appears in the sequence`}},
+		{"empty sequence", newGrammar("root", map[string]dialects.PartDefinition{
+			"root": {Constituents: [][]string{{"word"}, {}}},
+			"word": {Regex: `[a-z]+`},
+		}), []string{"part (root) has an empty constituent sequence (alternative 2)"}},
+		{"invalid regex", newGrammar("word", map[string]dialects.PartDefinition{
+			"word": {Regex: `[a-z`},
+		}), []string{"part (word) has an invalid regex: error parsing regexp: missing closing ]: `[a-z`"}},
+		{"invalid skip pattern", func() grammar {
+			g := wordList()
+			g.dialect.SkipPattern = `[ `
+			return g
+		}(), []string{"SkipPattern is an invalid regex: error parsing regexp: missing closing ]: `[ `"}},
+		{"block comment without an end", func() grammar {
+			g := wordList()
+			g.dialect.BlockComment = [2]string{"/*", ""}
+			return g
+		}(), []string{"BlockComment needs both the text that starts a comment and the text that ends it"}},
+		{"invalid keyword continuation", func() grammar {
+			g := wordList()
+			g.dialect.KeywordContinuation = `[a-z`
+			return g
+		}(), []string{"KeywordContinuation is an invalid regex: error parsing regexp: missing closing ]: `[a-z`"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			messages := validationMessages(t, test.grammar)
+			if len(messages) != len(test.expected) {
+				t.Fatalf("expected %d errors, got %q", len(test.expected), messages)
+			}
+			for i, message := range messages {
+				if message != "dialects error: "+test.expected[i] {
+					t.Errorf("expected %q, got %q", test.expected[i], message)
+				}
+			}
+		})
+	}
+}
+
+func TestCompileReportsEveryProblem(t *testing.T) {
+	g := newGrammar("root", map[string]dialects.PartDefinition{
+		"root": {Constituents: [][]string{{"wrod", "numbr"}}},
+	})
+	_, err := dialects.Compile(g)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, name := range []string{"wrod", "numbr"} {
+		if !strings.Contains(err.Error(), "undefined part ("+name+")") {
+			t.Errorf("expected the error to mention %s, got %q", name, err)
+		}
+	}
+}
+
+func TestValidateLeftRecursion(t *testing.T) {
+	tests := []struct {
+		name     string
+		grammar  grammar
+		expected []string
+	}{
+		{"direct", newGrammar("expr", map[string]dialects.PartDefinition{
+			"expr": {Constituents: [][]string{{"expr", "plus", "term"}, {"term"}}},
+			"term": {Regex: `[0-9]+`},
+			"plus": {Regex: `\+`},
+		}), []string{"part (expr) has left recursion: expr -> expr"}},
+		{"indirect", newGrammar("expr", map[string]dialects.PartDefinition{
+			"expr":    {Constituents: [][]string{{"addExpr"}, {"term"}}},
+			"addExpr": {Constituents: [][]string{{"expr", "plus", "term"}}},
+			"term":    {Regex: `[0-9]+`},
+			"plus":    {Regex: `\+`},
+		}), []string{"part (addExpr) has left recursion: addExpr -> expr -> addExpr"}},
+		{"behind an optional part", newGrammar("list", map[string]dialects.PartDefinition{
+			"list": {Constituents: [][]string{{"ws?", "list", "item"}, {"item"}}},
+			"item": {Regex: `[a-z]+`},
+			"ws":   {Regex: `[ ]+`},
+		}), []string{"part (list) has left recursion: list -> list"}},
+		{"behind a zero-width regex", newGrammar("a", map[string]dialects.PartDefinition{
+			"a":  {Constituents: [][]string{{"wb", "a"}, {"x"}}},
+			"wb": {Regex: `\b`},
+			"x":  {Regex: `x`},
+		}), []string{"part (a) has left recursion: a -> a"}},
+		{"through an expression operand", newGrammar("expr", map[string]dialects.PartDefinition{
+			"expr":    dialects.ExpressionPart("operand", []dialects.OpLevel{{Operators: []string{"+"}}}),
+			"operand": {Constituents: [][]string{{"expr"}, {"number"}}},
+			"number":  {Regex: `[0-9]+`},
+		}), []string{"part (expr) has left recursion: expr -> operand -> expr"}},
+		{"allowed", newGrammar("expr", map[string]dialects.PartDefinition{
+			"expr": {Constituents: [][]string{{"expr", "plus", "term"}, {"term"}}, AllowLeftRecursion: true},
+			"term": {Regex: `[0-9]+`},
+			"plus": {Regex: `\+`},
+		}), nil},
+		{"allowed for part of the cycle", newGrammar("expr", map[string]dialects.PartDefinition{
+			"expr":    {Constituents: [][]string{{"addExpr"}, {"term"}}, AllowLeftRecursion: true},
+			"addExpr": {Constituents: [][]string{{"expr", "plus", "term"}}},
+			"term":    {Regex: `[0-9]+`},
+			"plus":    {Regex: `\+`},
+		}), nil},
+		{"behind a consuming part", newGrammar("list", map[string]dialects.PartDefinition{
+			"list": {Constituents: [][]string{{"item", "comma", "list"}, {"item"}}},
+			"item": {Regex: `[a-z]+`},
+			// the word boundary is zero width but neither alternative matches without a comma
+			"comma": {Regex: `,|\b,`},
+		}), nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			messages := validationMessages(t, test.grammar)
+			if len(messages) != len(test.expected) {
+				t.Fatalf("expected %d errors, got %q", len(test.expected), messages)
+			}
+			for i, message := range messages {
+				if message != "dialects error: "+test.expected[i] {
+					t.Errorf("expected %q, got %q", test.expected[i], message)
+				}
+			}
+		})
+	}
+}
+
+func TestParseLeftRecursion(t *testing.T) {
+	g := newGrammar("expr", map[string]dialects.PartDefinition{
+		"expr": {Constituents: [][]string{{"expr", "plus", "term"}, {"term"}}},
+		"term": {Regex: `[0-9]+`},
+		"plus": {Regex: `\+`},
+	})
+	// the grammar is rejected before parsing rather than overflowing the stack
+	if _, err, _ := dialects.Parse(g, "1+2"); err == nil || !strings.Contains(err.Error(), "left recursion") {
+		t.Errorf("expected a left recursion error, got %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package dialects
+
+import (
+	"math"
+	"strconv"
+)
+
+// VersionError reports that the input declares a version of the dialect, Declared, that the dialect's Version can't
+// parse, so the caller can look up a compatible dialect, as with LookupVersion
+type VersionError struct {
+	Declared string
+	Version  float64
+}
+
+// Error returns the versions of the input and the dialect, as in "document requires dialect version 2.1, this parser
+// is 1.4"
+func (e *VersionError) Error() string {
+	if _, err := strconv.ParseFloat(e.Declared, 64); err != nil {
+		return "document declares dialect version " + strconv.Quote(e.Declared) + ", which isn't a version number"
+	}
+	return "document requires dialect version " + e.Declared + ", this parser is " + strconv.FormatFloat(e.Version, 'f', -1, 64)
+}
+
+// CompatibleVersion returns a VersionError unless the declared version is a number with the same major version (its
+// whole number part) as the dialect's version and no newer, treating versions like LookupVersion does
+func CompatibleVersion(declared string, version float64) error {
+	required, err := strconv.ParseFloat(declared, 64)
+	if err != nil || math.Floor(required) != math.Floor(version) || required > version {
+		return &VersionError{Declared: declared, Version: version}
+	}
+	return nil
+}
+
+// checkVersion returns the parts found for the part, unless it's the dialect's VersionPart and the version it holds
+// isn't compatible, in which case it aborts the parse with the error at the part, leaving lookaheads to the parse
+// that follows them
+func checkVersion(partName string, parts []*Part, parser Parser) []*Part {
+	if partName != parser.dialect.VersionPart || len(parts) == 0 || parser.lookahead {
+		return parts
+	}
+	check := parser.dialect.CheckVersion
+	if check == nil {
+		check = CompatibleVersion
+	}
+	// the part follows any text skipped before it
+	part := parts[len(parts)-1]
+	if err := check(part.Value, parser.dialect.Version); err != nil {
+		parseError := newParseError(parser, part.StartPos, part.StartLine, partName, err.Error())
+		parseError.Err = err
+		parseError.length = part.EndPos - part.StartPos
+		if parser.failure.abort == nil {
+			failSemantically(parser)
+		}
+		abortParse(parseError, parser)
+		return nil
+	}
+	return parts
+}
@@ -0,0 +1,77 @@
+package dialects_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/AdamJonR/dialects"
+)
+
+// declaringVersion returns a grammar of a version directive followed by words, whose handler counts the words it sees
+func declaringVersion(version float64, words *int) grammar {
+	return grammar{dialect: dialects.Dialect{Title: "versioned", RootName: "root", Version: version, VersionPart: "number", SkipPattern: `\s+`, PartDefinitions: map[string]dialects.PartDefinition{
+		"root":    {Constituents: [][]string{{"version", "word*"}}},
+		"version": {Constituents: [][]string{{"keyword", "number"}}},
+		"keyword": {Keyword: "version"},
+		"number":  {Regex: `[0-9]+(?:\.[0-9]+)?`},
+		"word": {Regex: `[a-z]+`, Handler: func(*dialects.Part, interface{}) bool {
+			*words++
+			return true
+		}},
+	}}}
+}
+
+func TestVersionPart(t *testing.T) {
+	tests := []struct {
+		version  float64
+		input    string
+		expected string
+	}{
+		{1.4, "version 1.2 a b", ""},
+		{1.4, "version 1.4 a b", ""},
+		{1.4, "version 2.1 a b", "dialects error: document requires dialect version 2.1, this parser is 1.4 at line 1, column 9 (offset 8)"},
+		{2.1, "version 1.9 a b", "dialects error: document requires dialect version 1.9, this parser is 2.1 at line 1, column 9 (offset 8)"},
+		{1.4, "version 1.5\na b", "dialects error: document requires dialect version 1.5, this parser is 1.4 at line 1, column 9 (offset 8)\nversion 1.5\n        ^"},
+	}
+	for _, test := range tests {
+		words := 0
+		_, err, _ := dialects.Parse(declaringVersion(test.version, &words), test.input)
+		if test.expected == "" {
+			if err != nil || words != 2 {
+				t.Errorf("%q with version %v: expected 2 words, got %d and %v", test.input, test.version, words, err)
+			}
+			continue
+		}
+		if err == nil || !strings.HasPrefix(err.Error(), test.expected) {
+			t.Errorf("%q with version %v: expected %q, got %v", test.input, test.version, test.expected, err)
+		}
+		// the parse stops before the rest of the input reaches the model
+		if words != 0 {
+			t.Errorf("%q with version %v: expected no words, got %d", test.input, test.version, words)
+		}
+		var versionError *dialects.VersionError
+		if !errors.As(err, &versionError) || versionError.Version != test.version {
+			t.Errorf("%q with version %v: expected a VersionError, got %#v", test.input, test.version, err)
+		}
+	}
+	// the check can be replaced, here with one accepting only the exact version
+	words := 0
+	g := declaringVersion(1.4, &words)
+	g.dialect.CheckVersion = func(declared string, version float64) error {
+		if declared != "1.4" {
+			return errors.New("only version 1.4 is supported")
+		}
+		return nil
+	}
+	if _, err, _ := dialects.Parse(g, "version 1.2 a"); err == nil || !strings.Contains(err.Error(), "only version 1.4 is supported") {
+		t.Errorf("expected the custom check's error, got %v", err)
+	}
+	if _, err, _ := dialects.Parse(g, "version 1.4 a"); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	g.dialect.VersionPart = "release"
+	if _, err, _ := dialects.Parse(g, "version 1.4 a"); err == nil || !strings.Contains(err.Error(), "part (release) is the VersionPart but is not defined") {
+		t.Errorf("expected an undefined VersionPart, got %v", err)
+	}
+}